@@ -0,0 +1,76 @@
+package goduino
+
+import (
+	"time"
+)
+
+// WindowSummary summarizes the reports of a pin over one window. Windows
+// without reports carry the value held since the last report, with Count
+// zero.
+type WindowSummary struct {
+	Kind       EventKind
+	Pin        int
+	Start, End time.Time
+	Count      int
+	Min, Max   int
+	Mean       float64
+	Last       int
+}
+
+// Aggregate summarizes the reports of the subscription every window, so
+// consumers such as dashboards and databases can sample at a low rate
+// without missing the extremes in between. It takes over C, which must
+// not be read as well. Summaries are delivered once a report has been
+// seen; the returned channel is closed when the subscription is, and the
+// window in progress is discarded.
+func (s *Subscription) Aggregate(window time.Duration) <-chan WindowSummary {
+	out := make(chan WindowSummary, 16)
+	go func() {
+		defer close(out)
+		t := time.NewTicker(window)
+		defer t.Stop()
+		var (
+			w     WindowSummary
+			sum   int
+			valid bool
+		)
+		w.Kind, w.Start = s.kind, time.Now()
+		for {
+			select {
+			case e, ok := <-s.C:
+				if !ok {
+					return
+				}
+				if w.Count == 0 || e.Value < w.Min {
+					w.Min = e.Value
+				}
+				if w.Count == 0 || e.Value > w.Max {
+					w.Max = e.Value
+				}
+				w.Pin, w.Last = e.Pin, e.Value
+				w.Count++
+				sum += e.Value
+				valid = true
+			case now := <-t.C:
+				if !valid {
+					w.Start = now
+					continue
+				}
+				w.End = now
+				if w.Count > 0 {
+					w.Mean = float64(sum) / float64(w.Count)
+				} else {
+					w.Min, w.Max, w.Mean = w.Last, w.Last, float64(w.Last)
+				}
+				select {
+				case out <- w:
+				case <-s.closed:
+					return
+				}
+				w = WindowSummary{Kind: w.Kind, Pin: w.Pin, Start: now, Last: w.Last}
+				sum = 0
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,45 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregate(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	s, err := ino.Subscribe(AnalogEvent, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries := s.Aggregate(100 * time.Millisecond)
+	for _, v := range []int{100, 300, 200} {
+		board.InjectAnalog(0, v)
+	}
+
+	next := func() WindowSummary {
+		t.Helper()
+		select {
+		case w := <-summaries:
+			return w
+		case <-time.After(time.Second):
+			t.Fatal("no summary")
+		}
+		return WindowSummary{}
+	}
+	w := next()
+	if w.Kind != AnalogEvent || w.Pin != 0 || w.Count != 3 || w.Min != 100 || w.Max != 300 || w.Mean != 200 || w.Last != 200 {
+		t.Errorf("first window = %+v", w)
+	}
+	if !w.End.After(w.Start) {
+		t.Errorf("window ends at %v, before its start %v", w.End, w.Start)
+	}
+	// A quiet window holds the last value
+	w = next()
+	if w.Count != 0 || w.Min != 200 || w.Max != 200 || w.Mean != 200 {
+		t.Errorf("quiet window = %+v", w)
+	}
+
+	s.Close()
+	for range summaries {
+	}
+}
@@ -1,6 +1,17 @@
 package goduino
 
+import (
+	"math"
+	"time"
+)
+
+// snapshotTimeout bounds how long AnalogReadAll waits for a full cycle.
+const snapshotTimeout = time.Second
+
 func (ino *Goduino) AnalogWrite(pin, value int) error {
+	if err := ino.checkFence(); err != nil {
+		return err
+	}
 	// XXX Below PinMode checking is not enabled because PWM mode also can use AnalogWrite
 	// Check if pin is configured as analog
 	//if ino.board.Pins()[p].Mode != Analog {
@@ -9,20 +20,161 @@ func (ino *Goduino) AnalogWrite(pin, value int) error {
 	//	}
 	//}
 	ino.logger.Printf("analogWrite(%d) -> %d\r\n", pin, value)
-	return ino.board.AnalogWrite(pin, value)
+	if err := ino.board.AnalogWrite(pin, value); err != nil {
+		return err
+	}
+	ino.rearmOutputs(pin)
+	return nil
 }
 
 // AnalogRead retrieves value from analog pin.
 // Returns -1 if the response from the board has timed out
 func (ino *Goduino) AnalogRead(pin int) (value int, err error) {
-	p := ino.digitalPin(pin)
 	// Check if pin is configured as analog
-	if ino.board.Pins()[p].Mode != Analog {
-		if err = ino.PinMode(pin, Analog); err != nil {
-			return
-		}
+	if err = ino.ensureAnalog(pin); err != nil {
+		return
 	}
-	value = ino.board.Pins()[p].Value
+	value = int(math.Round(ino.analogValue(pin)))
 	ino.logger.Printf("analogRead(%d) -> %d\r\n", pin, value)
 	return
 }
+
+// AnalogReadAll retrieves the values of several analog pins taken from the
+// same reporting cycle, so correlated measurements are not skewed across
+// cycles. Values are returned in the order the pins were given.
+func (ino *Goduino) AnalogReadAll(pins ...int) (values []int, err error) {
+	for _, pin := range pins {
+		if err = ino.ensureAnalog(pin); err != nil {
+			return
+		}
+	}
+	// Only accept cycles that started after every pin was reporting
+	start, _ := ino.board.AnalogSnapshot()
+	deadline := time.Now().Add(snapshotTimeout)
+	for time.Now().Before(deadline) {
+		cycle, snapshot := ino.board.AnalogSnapshot()
+		if cycle > start+1 {
+			values = make([]int, 0, len(pins))
+			for _, pin := range pins {
+				value, ok := snapshot[pin]
+				if !ok {
+					break
+				}
+				values = append(values, value)
+			}
+			if len(values) == len(pins) {
+				ino.logger.Printf("analogReadAll(%v) -> %v\r\n", pins, values)
+				return
+			}
+		}
+		<-time.After(5 * time.Millisecond)
+	}
+	return nil, ErrTimeout
+}
+
+// Firmware sampling interval bounds
+const (
+	defaultSamplingInterval = 19 * time.Millisecond
+	minSamplingInterval     = time.Millisecond
+	maxSamplingInterval     = 0x3FFF * time.Millisecond
+)
+
+// AnalogSubscription delivers the value of an analog pin at its own rate.
+type AnalogSubscription struct {
+	// C receives the latest value of the pin every interval. Values are
+	// dropped while the receiver is not ready. It is closed by Close.
+	C <-chan int
+
+	ino      *Goduino
+	pin      int
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// SubscribeAnalog reports analog pin every interval on the returned
+// subscription. The firmware sampling interval is global, so it is set to
+// the shortest interval requested by the open subscriptions.
+func (ino *Goduino) SubscribeAnalog(pin int, interval time.Duration) (*AnalogSubscription, error) {
+	if interval < minSamplingInterval {
+		interval = minSamplingInterval
+	}
+	if err := ino.ensureAnalog(pin); err != nil {
+		return nil, err
+	}
+	c := make(chan int, 1)
+	s := &AnalogSubscription{C: c, ino: ino, pin: pin, interval: interval, stop: make(chan struct{})}
+	ino.mu.Lock()
+	if ino.analogSubs == nil {
+		ino.analogSubs = make(map[*AnalogSubscription]struct{})
+	}
+	ino.analogSubs[s] = struct{}{}
+	ino.mu.Unlock()
+	if err := ino.updateSamplingInterval(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	go s.run(c)
+	return s, nil
+}
+
+// Close stops the subscription and relaxes the sampling interval if it was
+// the fastest one.
+func (s *AnalogSubscription) Close() error {
+	s.ino.mu.Lock()
+	if _, ok := s.ino.analogSubs[s]; !ok {
+		s.ino.mu.Unlock()
+		return nil
+	}
+	delete(s.ino.analogSubs, s)
+	close(s.stop)
+	s.ino.mu.Unlock()
+	return s.ino.updateSamplingInterval()
+}
+
+func (s *AnalogSubscription) run(c chan<- int) {
+	defer close(c)
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			select {
+			case c <- int(math.Round(s.ino.analogValue(s.pin))):
+			default:
+			}
+		}
+	}
+}
+
+// updateSamplingInterval sets the firmware sampling interval to the shortest
+// subscription interval, or back to the firmware default without any.
+func (ino *Goduino) updateSamplingInterval() error {
+	ino.mu.Lock()
+	interval := time.Duration(0)
+	for s := range ino.analogSubs {
+		if interval == 0 || s.interval < interval {
+			interval = s.interval
+		}
+	}
+	switch {
+	case interval == 0:
+		interval = defaultSamplingInterval
+	case interval > maxSamplingInterval:
+		interval = maxSamplingInterval
+	}
+	if interval == ino.samplingInterval {
+		ino.mu.Unlock()
+		return nil
+	}
+	ino.mu.Unlock()
+	ino.logger.Printf("samplingInterval(%v)\r\n", interval)
+	if err := ino.board.SamplingInterval(int(interval / time.Millisecond)); err != nil {
+		return err
+	}
+	ino.mu.Lock()
+	ino.samplingInterval = interval
+	ino.mu.Unlock()
+	return nil
+}
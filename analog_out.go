@@ -0,0 +1,41 @@
+package goduino
+
+import "math"
+
+// AnalogWriter is an analog output: a native PWM pin, a PCA9685 channel or
+// an MCP4725 DAC. Drivers written against it work with any of them.
+type AnalogWriter interface {
+	// WriteLevel outputs level, a fraction of full scale from 0 to 1.
+	// Levels outside that range are clamped.
+	WriteLevel(level float64) error
+	// Resolution returns the number of bits of the output.
+	Resolution() int
+}
+
+// scaleLevel maps level (0-1) to an integer output of bits resolution.
+func scaleLevel(level float64, bits int) int {
+	max := float64(int(1)<<uint(bits) - 1)
+	return int(math.Round(math.Max(0, math.Min(1, level)) * max))
+}
+
+// PwmOutput is a native PWM pin as an AnalogWriter.
+type PwmOutput struct {
+	ino *Goduino
+	pin int
+}
+
+// PwmOutput returns PWM pin as an AnalogWriter. The pin is switched to PWM
+// mode on the first write.
+func (ino *Goduino) PwmOutput(pin int) *PwmOutput {
+	return &PwmOutput{ino: ino, pin: pin}
+}
+
+// WriteLevel sets the duty cycle of the pin.
+func (p *PwmOutput) WriteLevel(level float64) error {
+	return p.ino.PwmWrite(p.pin, byte(scaleLevel(level, 8)))
+}
+
+// Resolution returns 8, the resolution of PwmWrite.
+func (p *PwmOutput) Resolution() int { return 8 }
+
+var _ AnalogWriter = (*PwmOutput)(nil)
@@ -0,0 +1,128 @@
+package goduino
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Easing maps the linear progress t (0-1) of an animation to the eased
+// progress.
+type Easing func(t float64) float64
+
+// Common easings.
+var (
+	EaseLinear    Easing = func(t float64) float64 { return t }
+	EaseInQuad    Easing = func(t float64) float64 { return t * t }
+	EaseOutQuad   Easing = func(t float64) float64 { return t * (2 - t) }
+	EaseInOutQuad Easing = func(t float64) float64 {
+		if t < 0.5 {
+			return 2 * t * t
+		}
+		return -1 + (4-2*t)*t
+	}
+	EaseInOutSine Easing = func(t float64) float64 { return (1 - math.Cos(math.Pi*t)) / 2 }
+)
+
+// Animation is a PWM transition started by AnimateTo.
+type Animation struct {
+	// Done is closed when the animation completes or is cancelled.
+	Done <-chan struct{}
+
+	done   chan struct{}
+	cancel chan struct{}
+	once   sync.Once
+	err    error
+}
+
+// Cancel stops the animation, leaving the pin at its current value.
+func (a *Animation) Cancel() {
+	a.once.Do(func() { close(a.cancel) })
+	<-a.done
+}
+
+// Wait blocks until the animation ends and returns the error that ended
+// it, if any.
+func (a *Animation) Wait() error {
+	<-a.done
+	return a.err
+}
+
+// AnimateTo moves PWM pin from its current level to target over d, with
+// the progress shaped by easing (EaseLinear if nil). Starting another
+// animation on the pin cancels this one.
+func (ino *Goduino) AnimateTo(pin int, target byte, d time.Duration, easing Easing) (*Animation, error) {
+	if err := ino.checkMode(pin, Pwm); err != nil {
+		return nil, err
+	}
+	if easing == nil {
+		easing = EaseLinear
+	}
+	done := make(chan struct{})
+	a := &Animation{Done: done, done: done, cancel: make(chan struct{})}
+	ino.mu.Lock()
+	prev := ino.animations[pin]
+	if ino.animations == nil {
+		ino.animations = map[int]*Animation{}
+	}
+	ino.animations[pin] = a
+	ino.mu.Unlock()
+	if prev != nil {
+		prev.Cancel()
+	}
+	from := 0
+	if p := ino.board.Pins()[pin]; p.Mode == Pwm {
+		from = p.Value
+	}
+	go ino.animate(pin, a, from, int(target), d, easing)
+	return a, nil
+}
+
+func (ino *Goduino) animate(pin int, a *Animation, from, to int, d time.Duration, easing Easing) {
+	defer func() {
+		ino.mu.Lock()
+		if ino.animations[pin] == a {
+			delete(ino.animations, pin)
+		}
+		ino.mu.Unlock()
+		close(a.done)
+	}()
+	start := time.Now()
+	ticker := time.NewTicker(fadeStep)
+	defer ticker.Stop()
+	last := -1
+	for {
+		t := 1.0
+		if d > 0 {
+			t = math.Min(float64(time.Since(start))/float64(d), 1)
+		}
+		level := from + int(math.Round(float64(to-from)*easing(t)))
+		if level != last {
+			if a.err = ino.PwmWrite(pin, byte(level)); a.err != nil {
+				return
+			}
+			last = level
+		}
+		if t >= 1 {
+			return
+		}
+		select {
+		case <-a.cancel:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopAnimations cancels every running animation.
+func (ino *Goduino) stopAnimations() {
+	ino.mu.Lock()
+	animations := make([]*Animation, 0, len(ino.animations))
+	for _, a := range ino.animations {
+		animations = append(animations, a)
+	}
+	ino.mu.Unlock()
+	for _, a := range animations {
+		a.Cancel()
+	}
+}
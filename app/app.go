@@ -0,0 +1,171 @@
+// Package app runs a daemon controlling a board: it loads the
+// configuration, connects with outputs fenced, starts the drivers and the
+// logic of the program once the fence is lifted, and on SIGINT or SIGTERM stops everything and
+// leaves the outputs in their safe states:
+//
+//	config, err := app.LoadConfig("/etc/pump.json")
+//	a := app.New("pump", config)
+//	a.AddDriver(pump)
+//	a.Go(func(ctx context.Context, ino *goduino.Goduino) error {
+//		...
+//	})
+//	log.Fatal(a.Run())
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/argandas/goduino"
+)
+
+// connectTimeout bounds the handshake with the board in Run.
+const connectTimeout = 30 * time.Second
+
+// Config is the configuration of a daemon, usually loaded from a JSON
+// file with LoadConfig.
+type Config struct {
+	// Port is the serial port of the board, empty to discover it
+	Port string
+	// SafeStates maps pins to the value they are set to on shutdown and
+	// whenever the program panics
+	SafeStates map[int]int
+	// Reconnect enables the default reconnect policy
+	Reconnect bool
+}
+
+// LoadConfig reads a Config from the JSON file at path.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// App is a daemon built around one board.
+type App struct {
+	config  Config
+	ino     *goduino.Goduino
+	drivers []appDriver
+	logic   []func(ctx context.Context, ino *goduino.Goduino) error
+}
+
+// appDriver is a driver waiting for Run to add it to the board.
+type appDriver struct {
+	driver goduino.Driver
+	after  []goduino.Driver
+}
+
+// New creates the app name for config. Args are passed on to goduino.New
+// after the port, so a goduinotest board can stand in for the real one.
+func New(name string, config Config, args ...interface{}) *App {
+	if config.Port != "" {
+		args = append([]interface{}{config.Port}, args...)
+	}
+	return &App{config: config, ino: goduino.New(name, args...)}
+}
+
+// Board returns the board of the app.
+func (a *App) Board() *goduino.Goduino {
+	return a.ino
+}
+
+// AddDriver adds d to the drivers started once the board is connected
+// and configured, and halted on shutdown, see goduino.AddDriver.
+func (a *App) AddDriver(d goduino.Driver, after ...goduino.Driver) error {
+	for _, dep := range after {
+		if a.driverIndex(dep) < 0 {
+			return fmt.Errorf("driver %s depends on %s, which was not added", d.Name(), dep.Name())
+		}
+	}
+	if a.driverIndex(d) >= 0 {
+		return fmt.Errorf("driver %s was already added", d.Name())
+	}
+	a.drivers = append(a.drivers, appDriver{driver: d, after: after})
+	return nil
+}
+
+// driverIndex returns the position of d in the drivers, -1 if it was not
+// added.
+func (a *App) driverIndex(d goduino.Driver) int {
+	for i, ad := range a.drivers {
+		if ad.driver == d {
+			return i
+		}
+	}
+	return -1
+}
+
+// Go adds logic to run once the board is connected and the drivers are
+// started. Logic must return when ctx is done; an error returned earlier
+// shuts the app down.
+func (a *App) Go(logic func(ctx context.Context, ino *goduino.Goduino) error) {
+	a.logic = append(a.logic, logic)
+}
+
+// Run connects to the board, lifts the fence, starts the drivers and runs
+// the logic until SIGINT or SIGTERM is received or some logic fails. It
+// then waits for the remaining logic, halts the drivers, applies the safe
+// states and disconnects, returning the first error. The handshake is
+// bounded by a timeout, and the board is disconnected again when a driver
+// fails to start.
+func (a *App) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ino := a.ino
+	defer ino.SafeOnPanic()
+	for pin, value := range a.config.SafeStates {
+		ino.SetSafeState(pin, value)
+	}
+	if a.config.Reconnect {
+		ino.SetReconnectPolicy(goduino.DefaultReconnectPolicy)
+	}
+	ino.FenceUntilConfigured()
+	connectCtx, cancelConnect := context.WithTimeout(ctx, connectTimeout)
+	err := ino.ConnectContext(connectCtx)
+	cancelConnect()
+	if err != nil {
+		return err
+	}
+	ino.Configure()
+	for _, ad := range a.drivers {
+		if err := ino.AddDriver(ad.driver, ad.after...); err != nil {
+			ino.Disconnect()
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	for _, logic := range a.logic {
+		wg.Add(1)
+		go func(logic func(context.Context, *goduino.Goduino) error) {
+			defer wg.Done()
+			defer ino.SafeOnPanic()
+			if err := logic(ctx, ino); err != nil && err != context.Canceled {
+				once.Do(func() { first = err })
+				cancel()
+			}
+		}(logic)
+	}
+	<-ctx.Done()
+	cancel()
+	wg.Wait()
+	if err := ino.Disconnect(); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
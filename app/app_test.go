@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/argandas/goduino"
+	"github.com/argandas/goduino/goduinotest"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pump.json")
+	data := `{"Port": "/dev/ttyACM0", "SafeStates": {"13": 0, "7": 1}, "Reconnect": true}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Port != "/dev/ttyACM0" || !config.Reconnect || len(config.SafeStates) != 2 || config.SafeStates[7] != 1 {
+		t.Errorf("LoadConfig = %+v", config)
+	}
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("LoadConfig of a missing file succeeded")
+	}
+}
+
+func TestRun(t *testing.T) {
+	board := goduinotest.NewBoard()
+	a := New("pump", Config{SafeStates: map[int]int{13: 0}}, board)
+	failed := errors.New("sensor failed")
+	a.Go(func(ctx context.Context, ino *goduino.Goduino) error {
+		if err := ino.DigitalWrite(13, 1); err != nil {
+			return err
+		}
+		board.AssertPin(t, 13, 1)
+		return failed
+	})
+	stopped := false
+	a.Go(func(ctx context.Context, ino *goduino.Goduino) error {
+		<-ctx.Done()
+		stopped = true
+		return ctx.Err()
+	})
+	if err := a.Run(); err != failed {
+		t.Errorf("Run = %v, want %v", err, failed)
+	}
+	if !stopped {
+		t.Errorf("Run returned before the logic did")
+	}
+	// Shutting down applied the safe state
+	board.AssertPin(t, 13, 0)
+	select {
+	case <-board.Done():
+	default:
+		t.Errorf("board still connected after Run")
+	}
+}
+
+// pump is a driver switching pin 7 on when started.
+type pump struct {
+	ino *goduino.Goduino
+	err error
+}
+
+func (p *pump) Name() string { return "pump" }
+func (p *pump) Init() error  { return p.ino.PinMode(7, goduino.Output) }
+func (p *pump) Start() error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.ino.DigitalWrite(7, 1)
+}
+func (p *pump) Halt() error { return p.ino.DigitalWrite(7, 0) }
+
+func TestRunStartsDriversConfigured(t *testing.T) {
+	board := goduinotest.NewBoard()
+	a := New("pump", Config{}, board)
+	if err := a.AddDriver(&pump{ino: a.Board()}); err != nil {
+		t.Fatal(err)
+	}
+	a.Go(func(ctx context.Context, ino *goduino.Goduino) error {
+		board.AssertPin(t, 7, 1)
+		return errors.New("done")
+	})
+	if err := a.Run(); err == nil || err.Error() != "done" {
+		t.Errorf("Run = %v, want done", err)
+	}
+	board.AssertPin(t, 7, 0)
+}
+
+func TestRunDriverFails(t *testing.T) {
+	board := goduinotest.NewBoard()
+	a := New("pump", Config{}, board)
+	failed := errors.New("dry run")
+	if err := a.AddDriver(&pump{ino: a.Board(), err: failed}); err != nil {
+		t.Fatal(err)
+	}
+	ran := false
+	a.Go(func(ctx context.Context, ino *goduino.Goduino) error {
+		ran = true
+		return nil
+	})
+	if err := a.Run(); err == nil {
+		t.Errorf("Run succeeded with a failing driver")
+	}
+	if ran {
+		t.Errorf("Run started the logic although a driver failed")
+	}
+	select {
+	case <-board.Done():
+	default:
+		t.Errorf("board still connected after Run")
+	}
+}
@@ -0,0 +1,219 @@
+package goduino
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one state-changing command sent to the board.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Board   string    `json:"board"`
+	Command string    `json:"command"`
+	Pin     int       `json:"pin"` // pin, port, I2C address or stepper device
+	Value   int       `json:"value"`
+	Args    []int     `json:"args,omitempty"`
+	Err     string    `json:"error,omitempty"`
+}
+
+// AuditSink receives the audit records of a Goduino. Errors are logged
+// and do not fail the audited command.
+type AuditSink interface {
+	Audit(AuditRecord) error
+}
+
+// AuditSinkFunc adapts a function to an AuditSink.
+type AuditSinkFunc func(AuditRecord) error
+
+func (f AuditSinkFunc) Audit(r AuditRecord) error { return f(r) }
+
+// AuditLog is an AuditSink writing one JSON record per line.
+type AuditLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewAuditLog writes audit records to w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w, enc: json.NewEncoder(w)}
+}
+
+// OpenAuditLog appends audit records to the file at path, creating it if
+// needed.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewAuditLog(f), nil
+}
+
+func (l *AuditLog) Audit(r AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(r)
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (l *AuditLog) Close() error {
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// SetAuditSink records every state-changing command sent to the board,
+// pin modes and writes, reporting and sampling settings, servo, I2C,
+// NeoPixel, stepper and tone commands, strings and custom sysex commands,
+// in sink. A nil sink disables auditing. Set it before Connect or while no
+// other goroutine uses the Goduino.
+func (ino *Goduino) SetAuditSink(sink AuditSink) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	board := unwrapBoard(ino.board)
+	if sink == nil {
+		ino.board = board
+		return
+	}
+	if ino.auditActor.Load() == nil {
+		ino.auditActor.Store(defaultActor())
+	}
+	ino.board = &auditBoard{firmataBoard: board, ino: ino, sink: sink}
+}
+
+// SetAuditActor sets who the audited commands are attributed to, the
+// current OS user by default.
+func (ino *Goduino) SetAuditActor(actor string) {
+	ino.auditActor.Store(actor)
+}
+
+func defaultActor() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// auditBoard passes every call to the wrapped board and audits the state
+// changing ones.
+type auditBoard struct {
+	firmataBoard
+	ino  *Goduino
+	sink AuditSink
+}
+
+func (a *auditBoard) audit(command string, pin, value int, err error, args ...int) error {
+	actor, _ := a.ino.auditActor.Load().(string)
+	r := AuditRecord{Time: time.Now(), Actor: actor, Board: a.ino.name, Command: command, Pin: pin, Value: value, Args: args}
+	if err != nil {
+		r.Err = err.Error()
+	}
+	if serr := a.sink.Audit(r); serr != nil {
+		a.ino.logger.Printf("audit %s failed: %v\r\n", command, serr)
+	}
+	return err
+}
+
+func (a *auditBoard) SetPinMode(pin int, mode int) error {
+	return a.audit("SetPinMode", pin, mode, a.firmataBoard.SetPinMode(pin, mode))
+}
+
+func (a *auditBoard) ReportDigital(pin int, state int) error {
+	return a.audit("ReportDigital", pin, state, a.firmataBoard.ReportDigital(pin, state))
+}
+
+func (a *auditBoard) ReportAnalog(pin int, state int) error {
+	return a.audit("ReportAnalog", pin, state, a.firmataBoard.ReportAnalog(pin, state))
+}
+
+func (a *auditBoard) SamplingInterval(ms int) error {
+	return a.audit("SamplingInterval", 0, ms, a.firmataBoard.SamplingInterval(ms))
+}
+
+func (a *auditBoard) DigitalWrite(pin int, value int) error {
+	return a.audit("DigitalWrite", pin, value, a.firmataBoard.DigitalWrite(pin, value))
+}
+
+func (a *auditBoard) DigitalWritePort(port int, mask byte, value byte) error {
+	return a.audit("DigitalWritePort", port, int(value), a.firmataBoard.DigitalWritePort(port, mask, value), int(mask))
+}
+
+func (a *auditBoard) AnalogWrite(pin int, value int) error {
+	return a.audit("AnalogWrite", pin, value, a.firmataBoard.AnalogWrite(pin, value))
+}
+
+func (a *auditBoard) ServoConfig(pin int, max int, min int) error {
+	return a.audit("ServoConfig", pin, min, a.firmataBoard.ServoConfig(pin, max, min), max)
+}
+
+func (a *auditBoard) I2cConfig(delay int) error {
+	return a.audit("I2cConfig", 0, delay, a.firmataBoard.I2cConfig(delay))
+}
+
+func (a *auditBoard) I2cWrite(address int, data []byte) error {
+	args := make([]int, len(data))
+	for i, b := range data {
+		args[i] = int(b)
+	}
+	return a.audit("I2cWrite", address, 0, a.firmataBoard.I2cWrite(address, data), args...)
+}
+
+func (a *auditBoard) NeopixelControl(pin int, numpixels int, color int, state int) error {
+	return a.audit("NeopixelControl", pin, color, a.firmataBoard.NeopixelControl(pin, numpixels, color, state), numpixels, state)
+}
+
+func (a *auditBoard) StepperConfig(device int, iface int, pins []int) error {
+	return a.audit("StepperConfig", device, iface, a.firmataBoard.StepperConfig(device, iface, pins), pins...)
+}
+
+// StepperSpeed audits the speed rounded to whole steps per second.
+func (a *auditBoard) StepperSpeed(device int, speed float64) error {
+	return a.audit("StepperSpeed", device, int(math.Round(speed)), a.firmataBoard.StepperSpeed(device, speed))
+}
+
+func (a *auditBoard) StepperStep(device int, steps int) error {
+	return a.audit("StepperStep", device, steps, a.firmataBoard.StepperStep(device, steps))
+}
+
+func (a *auditBoard) StepperStop(device int) error {
+	return a.audit("StepperStop", device, 0, a.firmataBoard.StepperStop(device))
+}
+
+func (a *auditBoard) Tone(pin int, frequency int, duration int) error {
+	return a.audit("Tone", pin, frequency, a.firmataBoard.Tone(pin, frequency, duration), duration)
+}
+
+func (a *auditBoard) SendSysex(cmd byte, data []byte) error {
+	args := make([]int, len(data))
+	for i, b := range data {
+		args[i] = int(b)
+	}
+	return a.audit("SendSysex", int(cmd), 0, a.firmataBoard.SendSysex(cmd, data), args...)
+}
+
+func (a *auditBoard) SendString(s string) error {
+	args := make([]int, len(s))
+	for i := 0; i < len(s); i++ {
+		args[i] = int(s[i])
+	}
+	return a.audit("SendString", 0, 0, a.firmataBoard.SendString(s), args...)
+}
+
+func (a *auditBoard) NoTone(pin int) error {
+	return a.audit("NoTone", pin, 0, a.firmataBoard.NoTone(pin))
+}
+
+// unwrapBoard returns the board below an audit wrapper.
+func unwrapBoard(board firmataBoard) firmataBoard {
+	if a, ok := board.(*auditBoard); ok {
+		return a.firmataBoard
+	}
+	return board
+}
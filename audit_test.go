@@ -0,0 +1,85 @@
+package goduino
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestAuditSink(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	if err := ino.PinMode(13, Input); err != nil {
+		t.Fatal(err)
+	}
+	var records []AuditRecord
+	ino.SetAuditSink(AuditSinkFunc(func(r AuditRecord) error {
+		records = append(records, r)
+		return nil
+	}))
+	ino.SetAuditActor("tester")
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertPin(t, 13, 1)
+	want := []AuditRecord{
+		{Actor: "tester", Board: "test", Command: "SetPinMode", Pin: 13, Value: Output},
+		{Actor: "tester", Board: "test", Command: "DigitalWrite", Pin: 13, Value: 1},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("audited %+v, want %d records", records, len(want))
+	}
+	for i, r := range records {
+		if r.Time.IsZero() {
+			t.Errorf("record %d has no time", i)
+		}
+		if r.Actor != want[i].Actor || r.Board != want[i].Board || r.Command != want[i].Command ||
+			r.Pin != want[i].Pin || r.Value != want[i].Value || r.Err != "" {
+			t.Errorf("record %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+
+	// Strings and stepper settings are state changes too
+	records = nil
+	if err := ino.SendString("on"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ino.StepperConfig(0, StepperDriver, 200, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	var commands []string
+	for _, r := range records {
+		commands = append(commands, r.Command)
+	}
+	if len(records) < 2 || records[0].Command != "SendString" || len(records[0].Args) != 2 || records[0].Args[0] != 'o' ||
+		records[1].Command != "StepperConfig" {
+		t.Errorf("audited %v", commands)
+	}
+
+	// A nil sink stops auditing
+	records = nil
+	ino.SetAuditSink(nil)
+	if err := ino.DigitalWrite(13, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("audited %+v", records)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewAuditLog(&buf)
+	if err := log.Audit(AuditRecord{Command: "DigitalWrite", Pin: 13, Value: 1, Err: "fenced"}); err != nil {
+		t.Fatal(err)
+	}
+	var r AuditRecord
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("%q is not a JSON record: %v", buf.String(), err)
+	}
+	if r.Command != "DigitalWrite" || r.Pin != 13 || r.Value != 1 || r.Err != "fenced" {
+		t.Errorf("decoded %+v", r)
+	}
+	if err := log.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package goduino
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// batteryQueue is the number of low battery events a BatteryMonitor buffers.
+const batteryQueue = 4
+
+// Chemistry is a battery discharge curve: cell voltages at decreasing
+// states of charge, from 100% to 0%.
+type Chemistry struct {
+	Name   string
+	Points []ChargePoint
+}
+
+// ChargePoint is the resting cell voltage at a state of charge in percent.
+type ChargePoint struct {
+	Voltage float64
+	Percent float64
+}
+
+// Common battery chemistries, per cell.
+var (
+	LiPo = Chemistry{Name: "LiPo", Points: []ChargePoint{
+		{4.20, 100}, {4.10, 90}, {3.97, 80}, {3.92, 70}, {3.87, 60},
+		{3.82, 50}, {3.79, 40}, {3.77, 30}, {3.74, 20}, {3.68, 10},
+		{3.45, 5}, {3.00, 0},
+	}}
+	LeadAcid = Chemistry{Name: "lead-acid", Points: []ChargePoint{
+		{2.12, 100}, {2.10, 90}, {2.08, 80}, {2.06, 70}, {2.04, 60},
+		{2.02, 50}, {2.00, 40}, {1.98, 30}, {1.96, 20}, {1.93, 10},
+		{1.75, 0},
+	}}
+)
+
+// Percent estimates the state of charge of a cell at voltage by linear
+// interpolation of the curve.
+func (c Chemistry) Percent(voltage float64) float64 {
+	points := c.Points
+	if len(points) == 0 {
+		return 0
+	}
+	if voltage >= points[0].Voltage {
+		return points[0].Percent
+	}
+	for i := 1; i < len(points); i++ {
+		hi, lo := points[i-1], points[i]
+		if voltage >= lo.Voltage {
+			return lo.Percent + (voltage-lo.Voltage)/(hi.Voltage-lo.Voltage)*(hi.Percent-lo.Percent)
+		}
+	}
+	return points[len(points)-1].Percent
+}
+
+// BatteryConfig configures a BatteryMonitor.
+type BatteryConfig struct {
+	Pin       int     // analog pin
+	R1, R2    float64 // divider resistors, battery to pin and pin to ground; R1 of 0 means no divider
+	Reference float64 // ADC reference voltage, 5V if zero
+	Chemistry Chemistry
+	Cells     int           // cells in series, 1 if zero
+	LowPct    float64       // state of charge triggering a low battery event
+	Smoothing float64       // exponential smoothing factor in (0, 1], 1 (none) if zero
+	Interval  time.Duration // sampling period, one second if zero
+	// RecoverPct is how far above LowPct the state of charge must rise
+	// again before a recovery event, so a battery hovering around the
+	// threshold does not flap; 2 percentage points if zero
+	RecoverPct float64
+}
+
+// BatteryEvent is sent when the battery crosses the low threshold.
+type BatteryEvent struct {
+	Time    time.Time
+	Voltage float64
+	Percent float64
+	Low     bool // false when the battery recovered
+}
+
+// BatteryMonitor estimates the voltage and state of charge of a battery
+// measured through a voltage divider on an analog pin.
+type BatteryMonitor struct {
+	// Events receives low battery and recovery events. Events are dropped
+	// while it is full.
+	Events <-chan BatteryEvent
+
+	ino    *Goduino
+	config BatteryConfig
+	events chan BatteryEvent
+
+	mu      sync.Mutex
+	voltage float64
+	valid   bool
+	low     bool
+	task    int
+}
+
+// NewBatteryMonitor starts monitoring a battery.
+func (ino *Goduino) NewBatteryMonitor(config BatteryConfig) (*BatteryMonitor, error) {
+	if config.Reference == 0 {
+		config.Reference = 5
+	}
+	if config.Cells == 0 {
+		config.Cells = 1
+	}
+	if config.Smoothing <= 0 || config.Smoothing > 1 {
+		config.Smoothing = 1
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+	if config.RecoverPct <= 0 {
+		config.RecoverPct = 2
+	}
+	if err := ino.ensureAnalog(config.Pin); err != nil {
+		return nil, err
+	}
+	events := make(chan BatteryEvent, batteryQueue)
+	b := &BatteryMonitor{Events: events, ino: ino, config: config, events: events}
+	b.Start()
+	return b, nil
+}
+
+// Voltage returns the smoothed battery voltage.
+func (b *BatteryMonitor) Voltage() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.voltage
+}
+
+// Percent returns the estimated state of charge.
+func (b *BatteryMonitor) Percent() float64 {
+	return b.config.Chemistry.Percent(b.Voltage() / float64(b.config.Cells))
+}
+
+// Close stops monitoring.
+func (b *BatteryMonitor) Close() {
+	b.mu.Lock()
+	task := b.task
+	b.task = 0
+	b.mu.Unlock()
+	if task != 0 {
+		b.ino.cancelTask(task)
+	}
+}
+
+// Name returns the name of the monitor as a Driver.
+func (b *BatteryMonitor) Name() string { return fmt.Sprintf("battery(pin %d)", b.config.Pin) }
+
+// Init switches the pin to analog mode.
+func (b *BatteryMonitor) Init() error {
+	return b.ino.ensureAnalog(b.config.Pin)
+}
+
+// Start resumes monitoring after Halt.
+func (b *BatteryMonitor) Start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.task == 0 {
+		b.task = b.ino.every(b.config.Interval, priorityLow, b.sample)
+	}
+	return nil
+}
+
+// Halt stops monitoring.
+func (b *BatteryMonitor) Halt() error {
+	b.Close()
+	return nil
+}
+
+func (b *BatteryMonitor) sample() {
+	c := b.config
+	if _, err := b.ino.analogPin(c.Pin); err != nil {
+		b.ino.ReportDriver(b, err)
+		return
+	}
+	b.ino.ReportDriver(b, nil)
+	pinVoltage := b.ino.analogValue(c.Pin) / 1023 * c.Reference
+	voltage := pinVoltage
+	if c.R1 > 0 {
+		voltage = pinVoltage * (c.R1 + c.R2) / c.R2
+	}
+	b.mu.Lock()
+	if b.valid {
+		voltage = b.voltage + c.Smoothing*(voltage-b.voltage)
+	}
+	b.voltage, b.valid = voltage, true
+	percent := c.Chemistry.Percent(voltage / float64(c.Cells))
+	low := percent <= c.LowPct
+	if b.low {
+		low = percent < c.LowPct+c.RecoverPct
+	}
+	changed := low != b.low
+	b.low = low
+	b.mu.Unlock()
+	if changed {
+		select {
+		case b.events <- BatteryEvent{Time: time.Now(), Voltage: voltage, Percent: percent, Low: low}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatteryMonitor(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	// 0 to 5V maps linearly to 0 to 100%
+	linear := Chemistry{Name: "linear", Points: []ChargePoint{{5, 100}, {0, 0}}}
+	b, err := ino.NewBatteryMonitor(BatteryConfig{Pin: 0, Chemistry: linear, LowPct: 20, RecoverPct: 5, Interval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	expect := func(value int, low bool, event bool) {
+		t.Helper()
+		board.InjectAnalog(0, value)
+		select {
+		case e := <-b.Events:
+			if !event || e.Low != low {
+				t.Errorf("at %d: event %+v", value, e)
+			}
+		case <-time.After(50 * time.Millisecond):
+			if event {
+				t.Errorf("at %d: no event, want low %v", value, low)
+			}
+		}
+	}
+	expect(200, true, true)   // 19.6%
+	expect(215, true, false)  // 21%, within the recovery margin
+	expect(260, false, true)  // 25.4%, recovered
+	expect(210, false, false) // 20.5%, above the threshold
+}
@@ -0,0 +1,131 @@
+package goduino
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// channelQueue is the number of messages a VirtualChannel buffers.
+const channelQueue = 8
+
+// VirtualChannel is a named pub/sub channel shared with a matching sketch
+// over STRING_DATA. A message is sent as the channel name followed by
+// its key=value pairs, all separated by ';', e.g. "door;state=open;t=12".
+// Names, keys and values must not contain ';' or '='. StandardFirmata
+// buffers 64 sysex bytes, so keep messages under 30 characters.
+type VirtualChannel struct {
+	// C receives the messages the sketch publishes on the channel. They
+	// are dropped while it is full.
+	C <-chan map[string]string
+
+	ino  *Goduino
+	name string
+	c    chan map[string]string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Channel subscribes to the virtual channel name. Strings that are not
+// channel messages still go to the OnString handler.
+func (ino *Goduino) Channel(name string) (*VirtualChannel, error) {
+	if name == "" || strings.ContainsAny(name, ";=") {
+		return nil, fmt.Errorf("invalid channel name %q", name)
+	}
+	c := make(chan map[string]string, channelQueue)
+	vc := &VirtualChannel{C: c, ino: ino, name: name, c: c}
+	ino.mu.Lock()
+	if ino.channels == nil {
+		ino.channels = map[string][]*VirtualChannel{}
+	}
+	ino.channels[name] = append(ino.channels[name], vc)
+	ino.mu.Unlock()
+	ino.routeStrings()
+	return vc, nil
+}
+
+// Name returns the name of the channel.
+func (vc *VirtualChannel) Name() string { return vc.name }
+
+// Publish sends values on the channel to the sketch.
+func (vc *VirtualChannel) Publish(values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for key, value := range values {
+		if key == "" || strings.ContainsAny(key, ";=") || strings.ContainsAny(value, ";=") {
+			return fmt.Errorf("invalid channel pair %q=%q", key, value)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	msg := vc.name
+	for _, key := range keys {
+		msg += ";" + key + "=" + values[key]
+	}
+	return vc.ino.SendString(msg)
+}
+
+// Close unsubscribes from the channel and closes C.
+func (vc *VirtualChannel) Close() {
+	vc.ino.mu.Lock()
+	subs := vc.ino.channels[vc.name]
+	for i, s := range subs {
+		if s == vc {
+			vc.ino.channels[vc.name] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(vc.ino.channels[vc.name]) == 0 {
+		delete(vc.ino.channels, vc.name)
+	}
+	vc.ino.mu.Unlock()
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	if !vc.closed {
+		vc.closed = true
+		close(vc.c)
+	}
+}
+
+func (vc *VirtualChannel) deliver(msg map[string]string) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	if vc.closed {
+		return
+	}
+	select {
+	case vc.c <- msg:
+	default:
+		vc.ino.logger.Printf("channel %s full, message dropped\r\n", vc.name)
+	}
+}
+
+// routeStrings installs the STRING_DATA handler that dispatches channel
+// messages and passes other strings to the OnString handler.
+func (ino *Goduino) routeStrings() {
+	ino.board.OnString(ino.dispatchString)
+}
+
+func (ino *Goduino) dispatchString(s string) {
+	parts := strings.Split(s, ";")
+	ino.mu.Lock()
+	subs := append([]*VirtualChannel{}, ino.channels[parts[0]]...)
+	handler := ino.stringHandler
+	ino.mu.Unlock()
+	if len(subs) == 0 {
+		if handler != nil {
+			handler(s)
+		}
+		return
+	}
+	for _, vc := range subs {
+		msg := map[string]string{}
+		for _, pair := range parts[1:] {
+			if i := strings.Index(pair, "="); i > 0 {
+				msg[pair[:i]] = pair[i+1:]
+			}
+		}
+		vc.deliver(msg)
+	}
+}
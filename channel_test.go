@@ -0,0 +1,49 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChannel(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	var other string
+	ino.OnString(func(s string) { other = s })
+	vc, err := ino.Channel("door")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vc.Close()
+
+	board.InjectString("door;state=open;t=12")
+	select {
+	case msg := <-vc.C:
+		if len(msg) != 2 || msg["state"] != "open" || msg["t"] != "12" {
+			t.Errorf("received %v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+	}
+	board.InjectString("hello")
+	if other != "hello" {
+		t.Errorf("OnString handler got %q, want hello", other)
+	}
+
+	if err := vc.Publish(map[string]string{"t": "5", "cmd": "lock"}); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := hasCall(board, "SendString", 0); !ok || string(c.Data) != "door;cmd=lock;t=5" {
+		t.Errorf("Publish sent %q", c.Data)
+	}
+	if err := vc.Publish(map[string]string{"a": "b;c"}); err == nil {
+		t.Errorf("Publish accepted a value with ';'")
+	}
+	if _, err := ino.Channel("a=b"); err == nil {
+		t.Errorf("Channel accepted a name with '='")
+	}
+
+	vc.Close()
+	if _, ok := <-vc.C; ok {
+		t.Errorf("C is open after Close")
+	}
+}
@@ -0,0 +1,201 @@
+package goduino
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// charlieplex pin drive states
+const (
+	driveFloat = iota
+	driveLow
+	driveHigh
+)
+
+// Charlieplex drives a charlieplexed LED matrix where n pins control
+// n*(n-1) LEDs. LEDs are addressed by a logical index; the pin pair of each
+// index is anode-major, so index i lights pins[i/(n-1)] as anode.
+// When more than one LED is lit they are multiplexed, one LED per slot.
+type Charlieplex struct {
+	ino   *Goduino
+	pins  []int
+	slot  time.Duration
+	drive []int
+
+	mu   sync.Mutex
+	lit  map[int]bool
+	task int // scheduler task id, 0 when stopped
+	next int // index into the lit leds of the next slot
+}
+
+// NewCharlieplex creates a charlieplexed matrix over pins, refreshing each
+// lit LED for slot.
+func (ino *Goduino) NewCharlieplex(pins []int, slot time.Duration) *Charlieplex {
+	drive := make([]int, len(pins))
+	for i := range drive {
+		drive[i] = -1 // unknown, forces the first write
+	}
+	return &Charlieplex{ino: ino, pins: pins, slot: slot, drive: drive, lit: map[int]bool{}}
+}
+
+// Len returns the number of addressable LEDs.
+func (c *Charlieplex) Len() int {
+	return len(c.pins) * (len(c.pins) - 1)
+}
+
+// Pins returns the anode and cathode pins of led.
+func (c *Charlieplex) Pins(led int) (anode, cathode int, err error) {
+	a, k, err := c.pair(led)
+	if err != nil {
+		return 0, 0, err
+	}
+	return c.pins[a], c.pins[k], nil
+}
+
+// pair returns the indexes in pins of the anode and cathode of led.
+func (c *Charlieplex) pair(led int) (anode, cathode int, err error) {
+	if led < 0 || led >= c.Len() {
+		return 0, 0, fmt.Errorf("Invalid LED index %v\n", led)
+	}
+	n := len(c.pins) - 1
+	anode = led / n
+	cathode = led % n
+	if cathode >= anode {
+		cathode++
+	}
+	return
+}
+
+// Set turns led on or off. The change shows on the next refresh.
+func (c *Charlieplex) Set(led int, on bool) error {
+	if _, _, err := c.pair(led); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if on {
+		c.lit[led] = true
+	} else {
+		delete(c.lit, led)
+	}
+	return nil
+}
+
+// Clear turns every LED off.
+func (c *Charlieplex) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lit = map[int]bool{}
+}
+
+// Name returns the name of the matrix as a Driver.
+func (c *Charlieplex) Name() string { return fmt.Sprintf("charlieplex%v", c.pins) }
+
+// Init leaves every pin of the matrix floating.
+func (c *Charlieplex) Init() error {
+	for i := range c.pins {
+		c.set(i, driveFloat)
+	}
+	return nil
+}
+
+// Start begins refreshing the matrix.
+func (c *Charlieplex) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.task != 0 {
+		return nil
+	}
+	c.task = c.ino.every(c.slot, priorityHigh, c.refresh)
+	return nil
+}
+
+// Halt stops refreshing the matrix.
+func (c *Charlieplex) Halt() error {
+	c.Stop()
+	return nil
+}
+
+// Stop ends refreshing and leaves every pin floating.
+func (c *Charlieplex) Stop() {
+	c.mu.Lock()
+	task := c.task
+	c.task = 0
+	c.mu.Unlock()
+	if task == 0 {
+		return
+	}
+	c.ino.cancelTask(task)
+	for i := range c.pins {
+		c.set(i, driveFloat)
+	}
+}
+
+// refresh lights the next lit led for one slot.
+func (c *Charlieplex) refresh() {
+	c.mu.Lock()
+	leds := make([]int, 0, len(c.lit))
+	for led := 0; led < c.Len(); led++ {
+		if c.lit[led] {
+			leds = append(leds, led)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(leds) == 0 {
+		for i := range c.pins {
+			c.set(i, driveFloat)
+		}
+		return
+	}
+	c.next %= len(leds)
+	c.light(leds[c.next])
+	c.next++
+}
+
+// light floats every pin except the pair of led, then drives the pair.
+func (c *Charlieplex) light(led int) {
+	anode, cathode, _ := c.pair(led)
+	for i := range c.pins {
+		if i != anode && i != cathode {
+			c.set(i, driveFloat)
+		}
+	}
+	c.set(cathode, driveLow)
+	c.set(anode, driveHigh)
+}
+
+// set changes the drive state of pins[i], skipping redundant commands.
+func (c *Charlieplex) set(i int, state int) {
+	if c.drive[i] == state {
+		return
+	}
+	pin := c.pins[i]
+	board := c.ino.board
+	c.ino.forgetWrites(pin)
+	err := c.ino.checkFence()
+	switch {
+	case err != nil:
+		// Fenced, retried on the next refresh
+	case state == driveFloat:
+		// Clear the latch after switching so the input is left without pull-up
+		if err = board.SetPinMode(pin, Input); err == nil {
+			err = board.DigitalWrite(pin, 0)
+		}
+	default:
+		if board.Pins()[pin].Mode != Output {
+			err = board.SetPinMode(pin, Output)
+		}
+		if err == nil {
+			err = board.DigitalWrite(pin, state-driveLow)
+		}
+	}
+	c.ino.ReportDriver(c, err)
+	if err != nil {
+		c.ino.logger.Printf("charlieplex pin %d failed: %v\r\n", pin, err)
+		c.drive[i] = -1
+		return
+	}
+	c.drive[i] = state
+}
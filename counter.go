@@ -0,0 +1,100 @@
+package goduino
+
+import (
+	"sync"
+	"time"
+)
+
+// counterRateWindow is the period over which PulseCounter.Rate is measured.
+const counterRateWindow = time.Second
+
+// PulseCounter totals the rising edges reported on a digital pin, for flow
+// meters, odometers and energy meters. Like QuadratureDecoder it relies on
+// the digital reports of the board, so pulses shorter than the report
+// latency (a few milliseconds) can be missed.
+type PulseCounter struct {
+	ino      *Goduino
+	pin      int
+	dirPin   int
+	debounce time.Duration
+	listener int
+
+	mu    sync.Mutex
+	total int64
+	last  time.Time
+	times []time.Time
+}
+
+// NewPulseCounter counts rising edges on pin, ignoring edges closer than
+// debounce to the previous counted one. If dirPin is not negative it is
+// read on every pulse and a low level counts down instead of up.
+func (ino *Goduino) NewPulseCounter(pin int, debounce time.Duration, dirPin int) (*PulseCounter, error) {
+	if err := ino.PinMode(pin, Input); err != nil {
+		return nil, err
+	}
+	if dirPin >= 0 {
+		if err := ino.PinMode(dirPin, Input); err != nil {
+			return nil, err
+		}
+	}
+	c := &PulseCounter{ino: ino, pin: pin, dirPin: dirPin, debounce: debounce}
+	c.listener = ino.board.AddDigitalListener(c.update)
+	return c, nil
+}
+
+// Total returns the accumulated count.
+func (c *PulseCounter) Total() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// Rate returns the pulses per second over the last second.
+func (c *PulseCounter) Rate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trim(time.Now())
+	return float64(len(c.times)) / counterRateWindow.Seconds()
+}
+
+// Reset sets the total back to zero.
+func (c *PulseCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total = 0
+	c.times = nil
+}
+
+// Close stops counting.
+func (c *PulseCounter) Close() {
+	c.ino.board.RemoveDigitalListener(c.listener)
+}
+
+// trim drops the pulse times older than the rate window.
+func (c *PulseCounter) trim(now time.Time) {
+	i := 0
+	for i < len(c.times) && now.Sub(c.times[i]) > counterRateWindow {
+		i++
+	}
+	c.times = c.times[i:]
+}
+
+func (c *PulseCounter) update(pin int, value int) {
+	if pin != c.pin || value == 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.last.IsZero() && now.Sub(c.last) < c.debounce {
+		return
+	}
+	c.last = now
+	if c.dirPin >= 0 && c.ino.board.Pins()[c.dirPin].Value == 0 {
+		c.total--
+	} else {
+		c.total++
+	}
+	c.times = append(c.times, now)
+	c.trim(now)
+}
@@ -0,0 +1,64 @@
+package goduino
+
+import (
+	"sync"
+)
+
+// DifferentialPair reads two analog pins as a differential input, such as
+// the two outputs of a bridge sensor, without an external ADC. Both pins
+// are taken from the same reporting cycle so the common-mode signal
+// cancels out.
+type DifferentialPair struct {
+	ino   *Goduino
+	pos   int
+	neg   int
+	scale float64
+
+	mu     sync.Mutex
+	offset float64
+}
+
+// NewDifferentialPair creates a pair from analog pins pos and neg whose
+// Value is the difference in ADC steps multiplied by scale.
+func (ino *Goduino) NewDifferentialPair(pos, neg int, scale float64) (*DifferentialPair, error) {
+	for _, pin := range []int{pos, neg} {
+		if err := ino.checkMode(pin, Analog); err != nil {
+			return nil, err
+		}
+	}
+	return &DifferentialPair{ino: ino, pos: pos, neg: neg, scale: scale}, nil
+}
+
+// Read returns the raw difference and common-mode level of the pair, in
+// ADC steps.
+func (d *DifferentialPair) Read() (diff, common float64, err error) {
+	values, err := d.ino.AnalogReadAll(d.pos, d.neg)
+	if err != nil {
+		return 0, 0, err
+	}
+	p, n := float64(values[0]), float64(values[1])
+	return p - n, (p + n) / 2, nil
+}
+
+// Value returns the scaled difference of the pair minus the zero offset.
+func (d *DifferentialPair) Value() (float64, error) {
+	diff, _, err := d.Read()
+	if err != nil {
+		return 0, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return diff*d.scale - d.offset, nil
+}
+
+// Zero makes the current reading the zero point, like taring a scale.
+func (d *DifferentialPair) Zero() error {
+	diff, _, err := d.Read()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.offset = diff * d.scale
+	return nil
+}
@@ -1,23 +1,45 @@
 package goduino
 
+import (
+	"fmt"
+)
+
 // DigitalWrite write a HIGH or a LOW value to a digital pin.
 //
 // If the pin has been configured as an OUTPUT with pinMode(),
 // its voltage will be set to the corresponding value:
 // 5V (or 3.3V on 3.3V boards) for HIGH, 0V (ground) for LOW.
 func (ino *Goduino) DigitalWrite(pin, value int) error {
-	// Check if pin is configured as analog
+	if err := ino.checkFence(); err != nil {
+		return err
+	}
+	if err := ino.checkMode(pin, Output); err != nil {
+		return err
+	}
+	if ino.cachedWrite(pin, Output, value) {
+		ino.rearmOutputs(pin)
+		return nil
+	}
+	// Check if pin is configured as output
 	if ino.board.Pins()[pin].Mode != Output {
 		if err := ino.PinMode(pin, Output); err != nil {
 			return err
 		}
 	}
 	ino.logger.Printf("digitalWrite(%d, %d)\r\n", pin, value)
-	return ino.board.DigitalWrite(pin, value)
+	if err := ino.board.DigitalWrite(pin, value); err != nil {
+		return err
+	}
+	ino.recordWrite(pin, Output, value)
+	ino.rearmOutputs(pin)
+	return nil
 }
 
 // DigitalRead reads the value from a specified digital pin, either HIGH or LOW.
 func (ino *Goduino) DigitalRead(pin int) (value int, err error) {
+	if err = ino.checkMode(pin, Input); err != nil {
+		return
+	}
 	// Check if pin is configured as input
 	if ino.board.Pins()[pin].Mode != Input && ino.board.Pins()[pin].Mode != Pullup {
 		ino.logger.Printf("Set PinMode force to Input!!! current mode : %d\r\n", ino.board.Pins()[pin].Mode)
@@ -29,3 +51,49 @@ func (ino *Goduino) DigitalRead(pin int) (value int, err error) {
 	ino.logger.Printf("digitalRead(%d) -> %d\r\n", pin, value)
 	return
 }
+
+// SetBits drives high the pins of digital port (pins 8*port to 8*port+7)
+// selected by mask. The other pins of the port keep their last written
+// value and the whole port is updated in a single message.
+func (ino *Goduino) SetBits(port int, mask byte) error {
+	return ino.writePort(port, mask, 0xFF)
+}
+
+// ClearBits drives low the pins of digital port selected by mask, leaving
+// the other pins of the port unchanged.
+func (ino *Goduino) ClearBits(port int, mask byte) error {
+	return ino.writePort(port, mask, 0x00)
+}
+
+func (ino *Goduino) writePort(port int, mask, value byte) error {
+	if err := ino.checkFence(); err != nil {
+		return err
+	}
+	if port < 0 {
+		return fmt.Errorf("Invalid pin number %v\n", 8*port)
+	}
+	pins := ino.board.Pins()
+	written := []int{}
+	for i := 0; i < 8; i++ {
+		pin := 8*port + i
+		if mask&(1<<uint(i)) == 0 {
+			continue
+		}
+		written = append(written, pin)
+		if pin >= len(pins) {
+			return fmt.Errorf("Invalid pin number %v\n", pin)
+		}
+		if pins[pin].Mode != Output {
+			if err := ino.PinMode(pin, Output); err != nil {
+				return err
+			}
+		}
+		ino.forgetWrites(pin)
+	}
+	ino.logger.Printf("writePort(%d, 0x%02X, 0x%02X)\r\n", port, mask, value)
+	if err := ino.board.DigitalWritePort(port, mask, value); err != nil {
+		return err
+	}
+	ino.rearmOutputs(written...)
+	return nil
+}
@@ -0,0 +1,46 @@
+package goduino
+
+// DigitalWriter is a digital output: a native pin, an MCP23017 pin or a
+// 74HC595 output. Drivers written against it work with any of them.
+type DigitalWriter interface {
+	// WriteDigital drives the output low for 0 and high otherwise,
+	// switching the pin to output first if needed.
+	WriteDigital(value int) error
+}
+
+// DigitalReader is a digital input.
+type DigitalReader interface {
+	// ReadDigital returns the level of the input, 0 or 1, switching the
+	// pin to input first if needed.
+	ReadDigital() (int, error)
+}
+
+// DigitalPin is a pin that can be used as input and output.
+type DigitalPin interface {
+	DigitalWriter
+	DigitalReader
+}
+
+// NativePin is a digital pin of the board as a DigitalPin.
+type NativePin struct {
+	ino *Goduino
+	pin int
+}
+
+// DigitalPin returns pin as a DigitalPin. For a pulled up input, set the
+// pin to Pullup with PinMode before reading.
+func (ino *Goduino) DigitalPin(pin int) *NativePin {
+	return &NativePin{ino: ino, pin: pin}
+}
+
+// WriteDigital writes value with DigitalWrite.
+func (p *NativePin) WriteDigital(value int) error {
+	return p.ino.DigitalWrite(p.pin, value)
+}
+
+// ReadDigital reads the pin with DigitalRead.
+func (p *NativePin) ReadDigital() (int, error) {
+	return p.ino.DigitalRead(p.pin)
+}
+
+var _ DigitalPin = (*NativePin)(nil)
@@ -0,0 +1,89 @@
+package goduino
+
+import (
+	"math"
+	"time"
+)
+
+// fadeStep is the interval between PWM updates during a Fade.
+const fadeStep = 20 * time.Millisecond
+
+// DimmingCurve maps perceived brightness to PWM duty cycle.
+type DimmingCurve int
+
+const (
+	DimmingCIE    DimmingCurve = iota // CIE 1931 lightness, the default
+	DimmingGamma                      // gamma 2.2
+	DimmingLinear                     // duty cycle equals brightness
+)
+
+// Duty returns the 0-255 PWM level giving percent (0-100) perceived
+// brightness.
+func (c DimmingCurve) Duty(percent float64) byte {
+	if percent <= 0 {
+		return 0
+	}
+	if percent >= 100 {
+		return 255
+	}
+	var y float64
+	switch c {
+	case DimmingGamma:
+		y = math.Pow(percent/100, 2.2)
+	case DimmingLinear:
+		y = percent / 100
+	default:
+		if percent > 8 {
+			y = math.Pow((percent+16)/116, 3)
+		} else {
+			y = percent / 903.3
+		}
+	}
+	return byte(math.Round(y * 255))
+}
+
+// SetDimmingCurve selects the curve used by Brightness and Fade on pin.
+func (ino *Goduino) SetDimmingCurve(pin int, c DimmingCurve) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.dimming == nil {
+		ino.dimming = make(map[int]DimmingCurve)
+	}
+	ino.dimming[pin] = c
+}
+
+func (ino *Goduino) dimmingCurve(pin int) DimmingCurve {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	return ino.dimming[pin]
+}
+
+// Brightness sets the LED on PWM pin to percent (0-100) perceived
+// brightness through the dimming curve of the pin.
+func (ino *Goduino) Brightness(pin int, percent float64) error {
+	return ino.PwmWrite(pin, ino.dimmingCurve(pin).Duty(percent))
+}
+
+// Fade changes the LED on PWM pin from one perceived brightness to another
+// over d, returning when done. Steps are even in perceived brightness.
+func (ino *Goduino) Fade(pin int, from, to float64, d time.Duration) error {
+	curve := ino.dimmingCurve(pin)
+	steps := int(d / fadeStep)
+	if steps < 1 {
+		steps = 1
+	}
+	last := -1
+	for i := 0; i <= steps; i++ {
+		duty := int(curve.Duty(from + (to-from)*float64(i)/float64(steps)))
+		if duty != last {
+			if err := ino.PwmWrite(pin, byte(duty)); err != nil {
+				return err
+			}
+			last = duty
+		}
+		if i < steps {
+			time.Sleep(d / time.Duration(steps))
+		}
+	}
+	return nil
+}
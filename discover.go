@@ -0,0 +1,96 @@
+package goduino
+
+import (
+	"errors"
+	"fmt"
+	"github.com/argandas/goduino/firmata"
+	"github.com/tarm/serial"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// probeTimeout allows for the bootloader delay of boards that reset when
+// the port is opened.
+const probeTimeout = 4 * time.Second
+
+// ErrNoBoard is returned when no serial port answers Firmata queries.
+var ErrNoBoard = errors.New("no firmata board found")
+
+// DiscoveredBoard is a serial port with a board answering Firmata queries.
+type DiscoveredBoard struct {
+	Port     string
+	Firmware string
+	Protocol string
+}
+
+// SerialPorts lists the serial ports boards are usually attached to.
+func SerialPorts() ([]string, error) {
+	if runtime.GOOS == "windows" {
+		ports := []string{}
+		for i := 1; i <= 32; i++ {
+			ports = append(ports, fmt.Sprintf("COM%d", i))
+		}
+		return ports, nil
+	}
+	ports := []string{}
+	for _, pattern := range []string{"/dev/ttyACM*", "/dev/ttyUSB*", "/dev/cu.usbmodem*", "/dev/cu.usbserial*"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, matches...)
+	}
+	return ports, nil
+}
+
+// Discover probes every serial port for a Firmata board and returns the
+// ones that answered with their firmware name and protocol version.
+func Discover() ([]DiscoveredBoard, error) {
+	return DiscoverConfig(serial.Config{})
+}
+
+// DiscoverConfig is Discover for boards running at other serial
+// parameters. The name of config is ignored, a zero baud probes at the
+// StandardFirmata rate.
+func DiscoverConfig(config serial.Config) ([]DiscoveredBoard, error) {
+	ports, err := SerialPorts()
+	if err != nil {
+		return nil, err
+	}
+	if config.Baud == 0 {
+		config.Baud = defaultBaud
+	}
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = 100 * time.Millisecond
+	}
+	boards := []DiscoveredBoard{}
+	for _, port := range ports {
+		config.Name = port
+		sp, err := serial.OpenPort(&config)
+		if err != nil {
+			continue
+		}
+		firmware, protocol, err := firmata.Probe(sp, probeTimeout)
+		sp.Close()
+		if err != nil {
+			continue
+		}
+		boards = append(boards, DiscoveredBoard{Port: port, Firmware: firmware, Protocol: protocol})
+	}
+	return boards, nil
+}
+
+// FirmwareName returns the firmware name reported by the connected board,
+// e.g. "StandardFirmata.ino".
+func (ino *Goduino) FirmwareName() string {
+	firmware, _ := ino.board.Version()
+	return firmware
+}
+
+// ProtocolVersion returns the Firmata protocol version reported by the
+// connected board, e.g. "2.5".
+func (ino *Goduino) ProtocolVersion() string {
+	_, protocol := ino.board.Version()
+	return protocol
+}
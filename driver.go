@@ -0,0 +1,132 @@
+package goduino
+
+import (
+	"fmt"
+)
+
+// Driver is a device with a lifecycle managed by a Goduino. Init prepares
+// the pins of the device once the board is connected, Start begins its
+// operation and Halt stops it, leaving its outputs in a safe state.
+type Driver interface {
+	Name() string
+	Init() error
+	Start() error
+	Halt() error
+}
+
+// managedDriver is a Driver added to a Goduino.
+type managedDriver struct {
+	driver  Driver
+	running bool
+	status  DriverStatus
+}
+
+// AddDriver adds d to the drivers brought up by Connect and halted by
+// Disconnect. The drivers d depends on must have been added before, so
+// drivers start in the order they were added and halt in reverse. When
+// the board is already connected d is started right away.
+func (ino *Goduino) AddDriver(d Driver, after ...Driver) error {
+	ino.mu.Lock()
+	for _, dep := range after {
+		if ino.driverIndex(dep) < 0 {
+			ino.mu.Unlock()
+			return fmt.Errorf("driver %s depends on %s, which was not added", d.Name(), dep.Name())
+		}
+	}
+	if ino.driverIndex(d) >= 0 {
+		ino.mu.Unlock()
+		return fmt.Errorf("driver %s was already added", d.Name())
+	}
+	m := &managedDriver{driver: d}
+	ino.drivers = append(ino.drivers, m)
+	connected := ino.profile != nil
+	ino.mu.Unlock()
+	if connected {
+		return ino.startDriver(m)
+	}
+	return nil
+}
+
+// Drivers returns the added drivers in start order.
+func (ino *Goduino) Drivers() []Driver {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	drivers := make([]Driver, len(ino.drivers))
+	for i, m := range ino.drivers {
+		drivers[i] = m.driver
+	}
+	return drivers
+}
+
+// driverIndex returns the position of d in the drivers, -1 if it was not
+// added. Callers hold mu.
+func (ino *Goduino) driverIndex(d Driver) int {
+	for i, m := range ino.drivers {
+		if m.driver == d {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ino *Goduino) startDriver(m *managedDriver) error {
+	err := m.driver.Init()
+	if err != nil {
+		err = fmt.Errorf("driver %s: init: %v", m.driver.Name(), err)
+	} else if err = m.driver.Start(); err != nil {
+		err = fmt.Errorf("driver %s: start: %v", m.driver.Name(), err)
+	}
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	m.report(err)
+	m.running = err == nil
+	return err
+}
+
+// startDrivers brings up every driver in order. When one fails the ones
+// already started are halted again.
+func (ino *Goduino) startDrivers() error {
+	ino.mu.Lock()
+	drivers := append([]*managedDriver{}, ino.drivers...)
+	ino.mu.Unlock()
+	for _, m := range drivers {
+		if err := ino.startDriver(m); err != nil {
+			ino.haltDrivers()
+			return err
+		}
+	}
+	return nil
+}
+
+// haltDrivers halts the running drivers in reverse order, logging errors
+// so every driver gets halted.
+func (ino *Goduino) haltDrivers() {
+	ino.mu.Lock()
+	drivers := append([]*managedDriver{}, ino.drivers...)
+	ino.mu.Unlock()
+	for i := len(drivers) - 1; i >= 0; i-- {
+		m := drivers[i]
+		ino.mu.Lock()
+		running := m.running
+		m.running = false
+		ino.mu.Unlock()
+		if !running {
+			continue
+		}
+		err := m.driver.Halt()
+		if err != nil {
+			ino.logger.Printf("driver %s: halt: %v\r\n", m.driver.Name(), err)
+		}
+		ino.ReportDriver(m.driver, err)
+	}
+}
+
+var (
+	_ Driver = (*BatteryMonitor)(nil)
+	_ Driver = (*Charlieplex)(nil)
+	_ Driver = (*Irrigation)(nil)
+	_ Driver = (*Machine)(nil)
+	_ Driver = (*Pattern)(nil)
+	_ Driver = (*Scheduler)(nil)
+	_ Driver = (*Thermostat)(nil)
+)
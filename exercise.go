@@ -0,0 +1,175 @@
+package goduino
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// exerciseSettle bounds how long a fixture input may take to follow.
+const exerciseSettle = 200 * time.Millisecond
+
+// ExerciseFailure is an input that did not read what its output drove.
+type ExerciseFailure struct {
+	Pattern string
+	Out     int
+	In      int
+	Want    int
+	Got     int
+}
+
+// ExerciseReport is the result of ExercisePins.
+type ExerciseReport struct {
+	Steps    int
+	Failures []ExerciseFailure
+	Stuck    map[int]int // input pin -> the only value it ever read
+	Shorted  [][2]int    // output pins whose inputs follow each other
+}
+
+// Passed reports whether every input followed its output.
+func (r *ExerciseReport) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// ExercisePins cycles the output pins of fixture, which maps each output
+// to the input it is wired to on the test fixture, through all-high,
+// all-low, walking-one and walking-zero patterns and checks that every
+// input follows. With a nil fixture every output capable pin of the board
+// profile is cycled without checks, for burn-in. Pins end low.
+func (ino *Goduino) ExercisePins(fixture map[int]int, cycles int) (*ExerciseReport, error) {
+	outs := []int{}
+	if fixture == nil {
+		p := ino.Profile()
+		if p == nil {
+			return nil, fmt.Errorf("board profile not available, connect first")
+		}
+		for _, pin := range p.DigitalPins {
+			if supportsMode(ino.board.Pins()[pin], Output) {
+				outs = append(outs, pin)
+			}
+		}
+	} else {
+		for out := range fixture {
+			outs = append(outs, out)
+		}
+		sort.Ints(outs)
+	}
+	for _, out := range outs {
+		if err := ino.PinMode(out, Output); err != nil {
+			return nil, err
+		}
+		if in, ok := fixture[out]; ok {
+			if err := ino.PinMode(in, Input); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	r := &ExerciseReport{Stuck: map[int]int{}}
+	seen := map[int]map[int]bool{} // input -> values read
+	shorted := map[[2]int]bool{}
+	for c := 0; c < cycles; c++ {
+		for _, pattern := range exercisePatterns(len(outs)) {
+			r.Steps++
+			for i, out := range outs {
+				if err := ino.DigitalWrite(out, pattern.values[i]); err != nil {
+					return nil, err
+				}
+			}
+			if fixture == nil {
+				<-time.After(exerciseSettle)
+				continue
+			}
+			for i, out := range outs {
+				in := fixture[out]
+				want := pattern.values[i]
+				got := ino.waitPinValue(in, want, exerciseSettle)
+				if seen[in] == nil {
+					seen[in] = map[int]bool{}
+				}
+				seen[in][got] = true
+				if got == want {
+					continue
+				}
+				r.Failures = append(r.Failures, ExerciseFailure{Pattern: pattern.name, Out: out, In: in, Want: want, Got: got})
+				// In a walking pattern a wrong input matching the odd
+				// pin out means the two channels are connected
+				if pattern.walk >= 0 && pattern.walk != i && got == pattern.values[pattern.walk] {
+					pair := [2]int{outs[pattern.walk], out}
+					if pair[0] > pair[1] {
+						pair[0], pair[1] = pair[1], pair[0]
+					}
+					if !shorted[pair] {
+						shorted[pair] = true
+						r.Shorted = append(r.Shorted, pair)
+					}
+				}
+			}
+		}
+	}
+	for in, values := range seen {
+		if len(values) == 1 {
+			for v := range values {
+				r.Stuck[in] = v
+			}
+		}
+	}
+	// A stuck input mismatches every walking pattern, not a short
+	pairs := r.Shorted[:0]
+	for _, pair := range r.Shorted {
+		_, stuck0 := r.Stuck[fixture[pair[0]]]
+		_, stuck1 := r.Stuck[fixture[pair[1]]]
+		if !stuck0 && !stuck1 {
+			pairs = append(pairs, pair)
+		}
+	}
+	r.Shorted = pairs
+	for _, out := range outs {
+		if err := ino.DigitalWrite(out, 0); err != nil {
+			return nil, err
+		}
+	}
+	ino.logger.Printf("exercisePins: %d steps, %d failures, %d stuck, %d shorted\r\n",
+		r.Steps, len(r.Failures), len(r.Stuck), len(r.Shorted))
+	return r, nil
+}
+
+type exercisePattern struct {
+	name   string
+	values []int
+	walk   int // index of the odd pin in walking patterns, else -1
+}
+
+func exercisePatterns(n int) []exercisePattern {
+	high := make([]int, n)
+	for i := range high {
+		high[i] = 1
+	}
+	patterns := []exercisePattern{
+		{name: "all-high", values: high, walk: -1},
+		{name: "all-low", values: make([]int, n), walk: -1},
+	}
+	for i := 0; i < n; i++ {
+		one := make([]int, n)
+		one[i] = 1
+		zero := make([]int, n)
+		for j := range zero {
+			zero[j] = 1
+		}
+		zero[i] = 0
+		patterns = append(patterns,
+			exercisePattern{name: fmt.Sprintf("walking-one %d", i), values: one, walk: i},
+			exercisePattern{name: fmt.Sprintf("walking-zero %d", i), values: zero, walk: i})
+	}
+	return patterns
+}
+
+// waitPinValue waits up to timeout for the reported value of pin to become
+// value and returns the last reported value.
+func (ino *Goduino) waitPinValue(pin, value int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for ino.board.Pins()[pin].Value != value && time.Now().Before(deadline) {
+		<-time.After(5 * time.Millisecond)
+	}
+	return ino.board.Pins()[pin].Value
+}
@@ -0,0 +1,96 @@
+package goduino
+
+import "time"
+
+// outputExpiry is the dead-man's switch of an output.
+type outputExpiry struct {
+	timeout time.Duration
+	timer   *time.Timer
+	expired bool
+}
+
+// SetOutputExpiry makes pin revert to its safe state, set with
+// SetSafeState or low if none, unless it is written or refreshed with
+// RefreshOutput at least every timeout. It protects outputs driven on
+// behalf of a remote controller against the controller hanging. The
+// countdown starts now; a write after expiry re-arms it.
+func (ino *Goduino) SetOutputExpiry(pin int, timeout time.Duration) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if e, ok := ino.expiries[pin]; ok {
+		e.timer.Stop()
+	}
+	if ino.expiries == nil {
+		ino.expiries = make(map[int]*outputExpiry)
+	}
+	e := &outputExpiry{timeout: timeout}
+	e.timer = time.AfterFunc(timeout, func() { ino.expireOutput(pin, e) })
+	ino.expiries[pin] = e
+}
+
+// ClearOutputExpiry removes the expiry of pin.
+func (ino *Goduino) ClearOutputExpiry(pin int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if e, ok := ino.expiries[pin]; ok {
+		e.timer.Stop()
+		delete(ino.expiries, pin)
+	}
+}
+
+// RefreshOutput postpones the expiry of pin by its timeout without
+// writing it. It does not undo an expiry that already happened.
+func (ino *Goduino) RefreshOutput(pin int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if e, ok := ino.expiries[pin]; ok && !e.expired {
+		e.timer.Reset(e.timeout)
+	}
+}
+
+// OutputExpired reports whether pin reverted to its safe state since it
+// was last written.
+func (ino *Goduino) OutputExpired(pin int) bool {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	e, ok := ino.expiries[pin]
+	return ok && e.expired
+}
+
+// rearmOutputs restarts the expiry of pins after they were written.
+func (ino *Goduino) rearmOutputs(pins ...int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	for _, pin := range pins {
+		if e, ok := ino.expiries[pin]; ok {
+			e.expired = false
+			e.timer.Reset(e.timeout)
+		}
+	}
+}
+
+func (ino *Goduino) expireOutput(pin int, e *outputExpiry) {
+	ino.mu.Lock()
+	if ino.expiries[pin] != e {
+		// Replaced or cleared meanwhile
+		ino.mu.Unlock()
+		return
+	}
+	e.expired = true
+	value := ino.safeStates[pin]
+	ino.mu.Unlock()
+	ino.logger.Printf("output %d expired, reverting to %d\r\n", pin, value)
+	if err := ino.applySafeState(pin, value); err != nil {
+		ino.logger.Printf("safe state of pin %d failed: %v\r\n", pin, err)
+	}
+}
+
+// stopOutputExpiries disarms every expiry on Disconnect, where the safe
+// states are applied anyway. The next write to a pin re-arms it.
+func (ino *Goduino) stopOutputExpiries() {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	for _, e := range ino.expiries {
+		e.timer.Stop()
+	}
+}
@@ -0,0 +1,58 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+// waitPin waits for pin of board to reach value.
+func waitPin(t *testing.T, ino *Goduino, pin, value int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for ino.board.Pins()[pin].Value != value {
+		if time.Now().After(deadline) {
+			t.Fatalf("pin %d = %d, want %d", pin, ino.board.Pins()[pin].Value, value)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOutputExpiry(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	ino.SetSafeState(13, 0)
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	ino.SetOutputExpiry(13, 100*time.Millisecond)
+
+	// Refreshing keeps the output on past its timeout
+	for i := 0; i < 4; i++ {
+		time.Sleep(25 * time.Millisecond)
+		ino.RefreshOutput(13)
+	}
+	board.AssertPin(t, 13, 1)
+	if ino.OutputExpired(13) {
+		t.Errorf("refreshed output expired")
+	}
+
+	waitPin(t, ino, 13, 0)
+	if !ino.OutputExpired(13) {
+		t.Errorf("OutputExpired = false after the pin reverted")
+	}
+
+	// A write re-arms the expiry
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ino.OutputExpired(13) {
+		t.Errorf("OutputExpired = true after a write")
+	}
+	waitPin(t, ino, 13, 0)
+
+	ino.ClearOutputExpiry(13)
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	board.AssertPin(t, 13, 1)
+}
@@ -0,0 +1,252 @@
+package goduino
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expression is a compiled watch expression. Boolean results are 1 or 0.
+type expression func() (float64, error)
+
+// exprParser is a recursive descent parser for watch expressions:
+//
+//	or      = and { "||" and }
+//	and     = not { "&&" not }
+//	not     = "!" not | compare
+//	compare = sum [ ( ">" | "<" | ">=" | "<=" | "==" | "!=" ) sum ]
+//	sum     = product { ( "+" | "-" ) product }
+//	product = unary { ( "*" | "/" ) unary }
+//	unary   = "-" unary | number | name | "(" or ")"
+type exprParser struct {
+	tokens  []string
+	pos     int
+	resolve func(name string) (Variable, error)
+}
+
+// compileExpr parses src, resolving names with resolve.
+func compileExpr(src string, resolve func(string) (Variable, error)) (expression, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, resolve: resolve}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], src)
+	}
+	return e, nil
+}
+
+func tokenize(src string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		default:
+			if i+1 < len(src) {
+				if op := src[i : i+2]; op == "&&" || op == "||" || op == ">=" || op == "<=" || op == "==" || op == "!=" {
+					tokens = append(tokens, op)
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("<>!+-*/()", c) {
+				return nil, fmt.Errorf("unexpected character %q in %q", c, src)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// binary parses operands with operand joined by any of ops.
+func (p *exprParser) binary(operand func() (expression, error), ops ...string) (expression, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		found := false
+		for _, o := range ops {
+			if op == o {
+				found = true
+			}
+		}
+		if !found {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = combine(op, left, right)
+	}
+}
+
+func (p *exprParser) or() (expression, error)      { return p.binary(p.and, "||") }
+func (p *exprParser) and() (expression, error)     { return p.binary(p.not, "&&") }
+func (p *exprParser) sum() (expression, error)     { return p.binary(p.product, "+", "-") }
+func (p *exprParser) product() (expression, error) { return p.binary(p.unary, "*", "/") }
+
+func (p *exprParser) not() (expression, error) {
+	if p.peek() != "!" {
+		return p.compare()
+	}
+	p.next()
+	e, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	return func() (float64, error) {
+		v, err := e()
+		return truth(v == 0), err
+	}, nil
+}
+
+func (p *exprParser) compare() (expression, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek(); op {
+	case ">", "<", ">=", "<=", "==", "!=":
+		p.next()
+		right, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		return combine(op, left, right), nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) unary() (expression, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case t == "-":
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func() (float64, error) {
+			v, err := e()
+			return -v, err
+		}, nil
+	case t == "(":
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return e, nil
+	case unicode.IsDigit(rune(t[0])) || t[0] == '.':
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, err
+		}
+		return func() (float64, error) { return v, nil }, nil
+	case unicode.IsLetter(rune(t[0])) || t[0] == '_':
+		v, err := p.resolve(t)
+		if err != nil {
+			return nil, err
+		}
+		return expression(v), nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t)
+}
+
+func combine(op string, left, right expression) expression {
+	return func() (float64, error) {
+		l, err := left()
+		if err != nil {
+			return 0, err
+		}
+		// Short-circuit logical operators
+		switch {
+		case op == "&&" && l == 0:
+			return 0, nil
+		case op == "||" && l != 0:
+			return 1, nil
+		}
+		r, err := right()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "&&", "||":
+			return truth(r != 0), nil
+		case ">":
+			return truth(l > r), nil
+		case "<":
+			return truth(l < r), nil
+		case ">=":
+			return truth(l >= r), nil
+		case "<=":
+			return truth(l <= r), nil
+		case "==":
+			return truth(l == r), nil
+		case "!=":
+			return truth(l != r), nil
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		case "/":
+			if r == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return l / r, nil
+		}
+		return 0, fmt.Errorf("unknown operator %q", op)
+	}
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+/*
+  Lights the LED while analog input 0 is above a threshold the host can
+  read and change with goduino's Params:
+
+    value, err := arduino.Params().Set("threshold", 300)
+*/
+#include <Firmata.h>
+#include <GoduinoParams.h>
+
+long threshold = 512;
+long hysteresis = 20;
+GoduinoParams params;
+
+void sysexCallback(byte command, byte argc, byte *argv)
+{
+  params.handleSysex(command, argc, argv);
+}
+
+void setup()
+{
+  pinMode(LED_BUILTIN, OUTPUT);
+  params.add("threshold", &threshold, 0, 1023);
+  params.add("hysteresis", &hysteresis, 0, 100);
+  Firmata.setFirmwareVersion(FIRMATA_FIRMWARE_MAJOR_VERSION, FIRMATA_FIRMWARE_MINOR_VERSION);
+  Firmata.attach(START_SYSEX, sysexCallback);
+  Firmata.begin(57600);
+}
+
+void loop()
+{
+  while (Firmata.available()) {
+    Firmata.processInput();
+  }
+  int level = analogRead(A0);
+  if (level > threshold + hysteresis) {
+    digitalWrite(LED_BUILTIN, HIGH);
+  } else if (level < threshold - hysteresis) {
+    digitalWrite(LED_BUILTIN, LOW);
+  }
+}
@@ -0,0 +1,132 @@
+#include "GoduinoParams.h"
+
+#include <string.h>
+#include <Firmata.h>
+
+#define PARAM_LIST  0x00
+#define PARAM_GET   0x01
+#define PARAM_SET   0x02
+#define PARAM_VALUE 0x03
+#define PARAM_END   0x04
+
+// Values are two's complement, sent as five 7 bit bytes, least
+// significant first.
+static long decodeValue(const byte *b)
+{
+  unsigned long u = (unsigned long)(b[0] & 0x7F) |
+                    (unsigned long)(b[1] & 0x7F) << 7 |
+                    (unsigned long)(b[2] & 0x7F) << 14 |
+                    (unsigned long)(b[3] & 0x7F) << 21 |
+                    (unsigned long)(b[4] & 0x0F) << 28;
+  return (long)(int32_t)u;
+}
+
+static void writeValue(long value)
+{
+  unsigned long u = (unsigned long)value;
+  for (byte i = 0; i < 4; i++) {
+    Firmata.write(u & 0x7F);
+    u >>= 7;
+  }
+  Firmata.write(u & 0x0F);
+}
+
+GoduinoParams::GoduinoParams() : count(0)
+{
+}
+
+bool GoduinoParams::add(const char *name, long *value, long min, long max)
+{
+  if (count >= GODUINO_PARAMS_MAX) {
+    return false;
+  }
+  Param &p = params[count++];
+  p.name = name;
+  p.value = value;
+  p.min = min;
+  p.max = max;
+  store(&p, *value);
+  return true;
+}
+
+bool GoduinoParams::set(const char *name, long value)
+{
+  Param *p = find(name, strlen(name));
+  if (p == NULL) {
+    return false;
+  }
+  store(p, value);
+  report(p);
+  return true;
+}
+
+bool GoduinoParams::handleSysex(byte command, byte argc, byte *argv)
+{
+  if (command != GODUINO_PARAMS_SYSEX || argc < 1) {
+    return false;
+  }
+  Param *p;
+  switch (argv[0]) {
+    case PARAM_LIST:
+      for (byte i = 0; i < count; i++) {
+        report(&params[i]);
+      }
+      Firmata.write(START_SYSEX);
+      Firmata.write(GODUINO_PARAMS_SYSEX);
+      Firmata.write(PARAM_END);
+      Firmata.write(END_SYSEX);
+      break;
+    case PARAM_GET:
+      // unknown names are not answered, the host times out
+      p = find((const char *)argv + 1, argc - 1);
+      if (p != NULL) {
+        report(p);
+      }
+      break;
+    case PARAM_SET:
+      if (argc < 6) {
+        break;
+      }
+      p = find((const char *)argv + 6, argc - 6);
+      if (p != NULL) {
+        store(p, decodeValue(argv + 1));
+        report(p);
+      }
+      break;
+  }
+  return true;
+}
+
+GoduinoParams::Param *GoduinoParams::find(const char *name, byte len)
+{
+  for (byte i = 0; i < count; i++) {
+    if (strlen(params[i].name) == len && strncmp(params[i].name, name, len) == 0) {
+      return &params[i];
+    }
+  }
+  return NULL;
+}
+
+void GoduinoParams::store(Param *p, long value)
+{
+  if (value < p->min) {
+    value = p->min;
+  } else if (value > p->max) {
+    value = p->max;
+  }
+  *p->value = value;
+}
+
+// report sends the value message of p. Firmata.sendSysex would split
+// every byte in two, so the message is written raw.
+void GoduinoParams::report(const Param *p)
+{
+  Firmata.write(START_SYSEX);
+  Firmata.write(GODUINO_PARAMS_SYSEX);
+  Firmata.write(PARAM_VALUE);
+  writeValue(*p->value);
+  for (const char *c = p->name; *c; c++) {
+    Firmata.write(*c & 0x7F);
+  }
+  Firmata.write(END_SYSEX);
+}
@@ -0,0 +1,61 @@
+/*
+  GoduinoParams - named integer parameters for goduino over Firmata.
+
+  Implements the parameter extension on user sysex command 0x0C, see
+  params.go in goduino for the wire format. Register the parameters in
+  setup and pass the sysex messages the sketch does not handle itself to
+  handleSysex:
+
+    long threshold = 512;
+    GoduinoParams params;
+
+    void sysexCallback(byte command, byte argc, byte *argv) {
+      params.handleSysex(command, argc, argv);
+    }
+
+    void setup() {
+      params.add("threshold", &threshold, 0, 1023);
+      Firmata.attach(START_SYSEX, sysexCallback);
+      Firmata.begin(57600);
+    }
+*/
+#ifndef GoduinoParams_h
+#define GoduinoParams_h
+
+#include <Arduino.h>
+
+#ifndef GODUINO_PARAMS_MAX
+#define GODUINO_PARAMS_MAX 8
+#endif
+
+#define GODUINO_PARAMS_SYSEX 0x0C
+
+class GoduinoParams
+{
+  public:
+    GoduinoParams();
+    // add exposes *value under name, which must be ASCII and stay valid.
+    // Values set by the host are clamped to min and max. It returns false
+    // when GODUINO_PARAMS_MAX parameters were already added.
+    bool add(const char *name, long *value, long min, long max);
+    // set changes a parameter from the sketch and reports it to the host.
+    bool set(const char *name, long value);
+    // handleSysex answers a parameter message and returns true, or returns
+    // false for any other sysex command.
+    bool handleSysex(byte command, byte argc, byte *argv);
+
+  private:
+    struct Param {
+      const char *name;
+      long *value;
+      long min, max;
+    };
+    Param params[GODUINO_PARAMS_MAX];
+    byte count;
+
+    Param *find(const char *name, byte len);
+    void store(Param *p, long value);
+    void report(const Param *p);
+};
+
+#endif
@@ -0,0 +1,45 @@
+package goduino
+
+import (
+	"errors"
+)
+
+// ErrNotConfigured is returned by pin writes refused before Configure.
+var ErrNotConfigured = errors.New("pin writes are fenced until Configure is called")
+
+// FenceUntilConfigured makes every pin write (DigitalWrite, AnalogWrite,
+// PwmWrite, ServoWrite, SetBits, ClearBits, NeopixelControl and the helpers
+// built on them) and every sysex or string message sent to the sketch fail
+// with ErrNotConfigured until Configure is called, so no output can glitch
+// while connecting and querying capabilities. Explicit PinMode calls and
+// safe states are still applied. Call it before Connect.
+func (ino *Goduino) FenceUntilConfigured() {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	ino.fenced = true
+}
+
+// Configure ends the configuration phase and allows pin writes.
+func (ino *Goduino) Configure() {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.fenced {
+		ino.logger.Printf("configure: pin writes enabled\r\n")
+	}
+	ino.fenced = false
+}
+
+// Configured reports whether pin writes are allowed.
+func (ino *Goduino) Configured() bool {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	return !ino.fenced
+}
+
+// checkFence fails while pin writes are fenced.
+func (ino *Goduino) checkFence() error {
+	if !ino.Configured() {
+		return ErrNotConfigured
+	}
+	return nil
+}
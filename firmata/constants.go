@@ -40,12 +40,25 @@ const (
 	I2CModeContinuousRead byte = 0x02
 	I2CModeStopReading    byte = 0x03
 
+	// Stepper interfaces
+	StepperDriver    = 0x01 // step and direction driver
+	StepperTwoWire   = 0x02
+	StepperThreeWire = 0x03
+	StepperFourWire  = 0x04
+
 	// SerialConfig SerialSubCommand = 0x10
 	// SerialComm   SerialSubCommand = 0x20
 	// SerialFlush  SerialSubCommand = 0x30
 	// SerialClose  SerialSubCommand = 0x40
 )
 
+// Protocol limits. Pin numbers are sent as a single 7 bit byte, port
+// messages address 16 ports of 8 pins and analog messages 16 channels.
+const (
+	MaxPins           = 128
+	MaxAnalogChannels = 16
+)
+
 // Firmata commands
 const (
 	DigitalMessage           FirmataCommand = 0x90
@@ -67,13 +80,16 @@ const (
 const (
 	UltrasoundReport      SysExCommand = 0x08
 	NeopixelControl       SysExCommand = 0x18
+	ToneData              SysExCommand = 0x5F // tone and no tone on a digital pin
 	Serial                SysExCommand = 0x60
+	AccelStepper          SysExCommand = 0x62 // AccelStepperFirmata stepper control
 	AnalogMappingQuery    SysExCommand = 0x69
 	AnalogMappingResponse SysExCommand = 0x6A
 	CapabilityQuery       SysExCommand = 0x6B
 	CapabilityResponse    SysExCommand = 0x6C
 	PinStateQuery         SysExCommand = 0x6D
 	PinStateResponse      SysExCommand = 0x6E
+	ExtendedAnalog        SysExCommand = 0x6F // analog write to any pin, with more than 14 bits
 	ServoConfig           SysExCommand = 0x70
 	StringData            SysExCommand = 0x71
 	ShiftData             SysExCommand = 0x75 // a bitstream to/from a shift register
@@ -125,8 +141,8 @@ func (c SysExCommand) String() string {
 		return fmt.Sprintf("I2CReply (0x%x)", uint8(c))
 	case c == I2CConfig:
 		return fmt.Sprintf("I2CConfig (0x%x)", uint8(c))
-	// case c == ExtendedAnalog:
-	//	return fmt.Sprintf("ExtendedAnalog (0x%x)", uint8(c))
+	case c == ExtendedAnalog:
+		return fmt.Sprintf("ExtendedAnalog (0x%x)", uint8(c))
 	case c == PinStateQuery:
 		return fmt.Sprintf("PinStateQuery (0x%x)", uint8(c))
 	case c == PinStateResponse:
@@ -149,6 +165,10 @@ func (c SysExCommand) String() string {
 		return fmt.Sprintf("SysExRealtime (0x%x)", uint8(c))
 	case c == Serial:
 		return fmt.Sprintf("Serial (0x%x)", uint8(c))
+	case c == ToneData:
+		return fmt.Sprintf("ToneData (0x%x)", uint8(c))
+	case c == AccelStepper:
+		return fmt.Sprintf("AccelStepper (0x%x)", uint8(c))
 	case c == SysExSPI:
 		return fmt.Sprintf("SPI (0x%x)", uint8(c))
 	}
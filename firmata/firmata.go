@@ -2,6 +2,7 @@ package firmata
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -11,10 +12,16 @@ import (
 	"time"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Errors
 var ErrConnected = errors.New("client is already connected")
+var ErrPinRange = errors.New("pin number out of range")
+
+// PinListener is called from the read loop whenever a reported pin changes
+// value. It must return quickly as it blocks further processing.
+type PinListener func(pin int, value int)
 
 // Firmata represents a client connection to a firmata board
 type Firmata struct {
@@ -29,11 +36,54 @@ type Firmata struct {
 	capabilityDone    bool
 	ultrasoundDistance  string // interim definition, XXX need to change XXX
 	logger            *log.Logger
+
+	// serializes read-modify-write of digital ports
+	portMu sync.Mutex
+
+	// analog reporting cycle tracking, guarded by mu
+	mu          sync.Mutex
+	analogCycle uint64
+	lastChannel int
+	cycleValues map[int]int
+	snapshot    map[int]int
+
+	// pin and I2C listeners, guarded by mu
+	digitalListeners map[int]PinListener
+	analogListeners  map[int]PinListener
+	i2cListeners     map[int]func(I2cReply)
+	nextListener     int
+
+	// closed and replaced on every ultrasound reading or protocol version
+	// report, guarded by mu
+	ultrasoundUpdated chan struct{}
+	versionReceived   chan struct{}
+
+	// enabled reports, replayed by a reconnect, guarded by mu
+	digitalReports map[int]bool
+	analogReports  map[int]bool
+
+	// closed when the read loop of the current connection ends with err,
+	// guarded by mu
+	done chan struct{}
+	err  error
+
+	// AccelStepper devices, guarded by mu
+	steppers map[int]*stepperState
+
+	// last board value of pins and channels whose reports are held,
+	// guarded by mu
+	heldDigital map[int]int
+	heldAnalog  map[int]int
+
+	// user handlers of sysex commands and STRING_DATA, guarded by mu
+	sysexHandlers map[byte]func([]byte)
+	stringHandler func(string)
 }
 
 // Pin represents a pin on the firmata board
 type Pin struct {
 	SupportedModes []int
+	Resolutions    map[int]int // bits of resolution by supported mode
 	Mode           int
 	Value          int
 	State          int
@@ -58,6 +108,21 @@ func New() *Firmata {
 		connected:       false,
 		ultrasoundDistance: "", // interim definition, XXX need to change XXX
 		logger:          log.New(os.Stdout, "[firmata] ", log.Ltime),
+		lastChannel:     -1,
+		cycleValues:     map[int]int{},
+		snapshot:        map[int]int{},
+		digitalListeners: map[int]PinListener{},
+		analogListeners:  map[int]PinListener{},
+		i2cListeners:     map[int]func(I2cReply){},
+		ultrasoundUpdated: make(chan struct{}),
+		versionReceived:   make(chan struct{}),
+		digitalReports:    map[int]bool{},
+		analogReports:     map[int]bool{},
+		done:              make(chan struct{}),
+		steppers:          map[int]*stepperState{},
+		heldDigital:       map[int]int{},
+		heldAnalog:        map[int]int{},
+		sysexHandlers:     map[byte]func([]byte){},
 	}
 
 	return c
@@ -83,11 +148,29 @@ func (f *Firmata) Pins() []Pin {
 // then continuously polls the firmata board for new information when it's
 // available.
 func (f *Firmata) Connect(conn io.ReadWriteCloser) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err = f.ConnectContext(ctx, conn); err == context.DeadlineExceeded {
+		return errors.New("Unable to initialize connection")
+	}
+	return err
+}
+
+// ConnectContext is Connect with the handshake bounded by ctx; conn is
+// closed if ctx is done first. When the Firmata was connected before, the
+// pin modes and reports of the previous connection are restored.
+func (f *Firmata) ConnectContext(ctx context.Context, conn io.ReadWriteCloser) (err error) {
 	if f.connected {
 		return ErrConnected
 	}
+	prev := f.pins
 
 	f.connection = conn
+	f.mu.Lock()
+	f.done = make(chan struct{})
+	f.err = nil
+	done := f.done
+	f.mu.Unlock()
 
 	// Start threads
 	go f.process()
@@ -97,17 +180,27 @@ func (f *Firmata) Connect(conn io.ReadWriteCloser) (err error) {
 
 	// Wait for device to response
 	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	reset := time.After(time.Second * 15)
 	for !f.connected {
 		select {
 		case <-t.C:
 			// Do nothing
-		case <-time.After(time.Second * 15):
+		case <-reset:
 			f.logger.Print("No response in 15 seconds. Resetting device")
 			f.Reset()
-		case <-time.After(time.Second * 30):
+		case <-done:
+			return f.Err()
+		case <-ctx.Done():
 			// Close connections
 			f.connection.Close()
-			return errors.New("Unable to initialize connection")
+			return ctx.Err()
+		}
+	}
+
+	if len(prev) > 0 {
+		if err = f.restore(prev); err != nil {
+			return err
 		}
 	}
 
@@ -116,6 +209,109 @@ func (f *Firmata) Connect(conn io.ReadWriteCloser) (err error) {
 	return nil
 }
 
+// Restore pacing: the firmware buffers 64 received bytes, so the restore
+// sequence is flushed in chunks of at most that size with a pause for the
+// firmware to drain each one.
+const (
+	restoreChunk = 64
+	restorePace  = 2 * time.Millisecond
+)
+
+// restore brings the freshly reset board back to the state of prev: pin
+// modes, output values and reports are sent as one contiguous sequence.
+func (f *Firmata) restore(prev []Pin) error {
+	var msgs [][]byte
+	ports := map[int]bool{}
+	for pin := range prev {
+		if pin >= len(f.pins) {
+			break
+		}
+		mode := prev[pin].Mode
+		if mode != f.pins[pin].Mode {
+			f.pins[pin].Mode = mode
+			msgs = append(msgs, []byte{byte(PinMode), byte(pin), byte(mode)})
+		}
+		switch mode {
+		case Output:
+			f.pins[pin].Value = prev[pin].Value
+			ports[pin/8] = true
+		case Pwm, Servo:
+			value := prev[pin].Value
+			f.pins[pin].Value = value
+			if pin >= MaxAnalogChannels {
+				msgs = append(msgs, []byte{byte(StartSysex), byte(ExtendedAnalog), byte(pin), byte(value & 0x7F), byte((value >> 7) & 0x7F), byte(EndSysex)})
+			} else {
+				msgs = append(msgs, []byte{byte(AnalogMessage) | byte(pin), byte(value & 0x7F), byte((value >> 7) & 0x7F)})
+			}
+		}
+	}
+	for port := range ports {
+		value := byte(0)
+		for i := 0; i < 8 && 8*port+i < len(f.pins); i++ {
+			if f.pins[8*port+i].Value != 0 {
+				value |= 1 << byte(i)
+			}
+		}
+		msgs = append(msgs, []byte{byte(DigitalMessage) | byte(port), value & 0x7F, (value >> 7) & 0x7F})
+	}
+	f.mu.Lock()
+	for port := range f.digitalReports {
+		msgs = append(msgs, []byte{byte(ReportDigital) | byte(port), 1})
+	}
+	for channel := range f.analogReports {
+		msgs = append(msgs, []byte{byte(ReportAnalog) | byte(channel), 1})
+	}
+	f.mu.Unlock()
+
+	f.logger.Printf("restoring %d commands", len(msgs))
+	var buf []byte
+	for i, msg := range msgs {
+		buf = append(buf, msg...)
+		if i+1 < len(msgs) && len(buf)+len(msgs[i+1]) <= restoreChunk {
+			continue
+		}
+		if err := f.write(buf); err != nil {
+			return err
+		}
+		buf = buf[:0]
+		if i+1 < len(msgs) {
+			time.Sleep(restorePace)
+		}
+	}
+	return nil
+}
+
+// Done returns a channel that is closed when the connection to the board
+// is lost or closed.
+func (f *Firmata) Done() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.done
+}
+
+// Err returns the error that ended the last connection, nil while
+// connected.
+func (f *Firmata) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// fail ends the connection after the read loop failed with err.
+func (f *Firmata) fail(err error) {
+	f.logger.Printf("connection lost: %v", err)
+	f.connected = false
+	f.connection.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.done:
+	default:
+		f.err = err
+		close(f.done)
+	}
+}
+
 // Reset sends the SystemReset sysex code.
 func (f *Firmata) Reset() error {
 	return f.write([]byte{byte(SystemReset)})
@@ -123,17 +319,48 @@ func (f *Firmata) Reset() error {
 
 // SetPinMode sets the pin to mode.
 func (f *Firmata) SetPinMode(pin int, mode int) error {
-	f.pins[byte(pin)].Mode = mode
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
+	f.pins[pin].Mode = mode
 	return f.sendCommand([]byte{byte(PinMode), byte(pin), byte(mode)})
 }
 
 // DigitalWrite writes value to pin.
 func (f *Firmata) DigitalWrite(pin int, value int) error {
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
+	f.portMu.Lock()
+	defer f.portMu.Unlock()
 	//f.logger.Printf("DigitalWrite pin %d, value %d", pin, value)
 	port := byte(math.Floor(float64(pin) / 8))
 	//f.logger.Printf("DigitalWrite port %v", port)
-	portValue := byte(0)
 	f.pins[pin].Value = value
+	return f.writePort(port)
+}
+
+// DigitalWritePort sets the pins of port selected by mask to the matching
+// bits of value and sends the whole port at once, so the other pins keep
+// their last written state and no intermediate state is ever output.
+func (f *Firmata) DigitalWritePort(port int, mask byte, value byte) error {
+	if port < 0 || port >= MaxPins/8 {
+		return ErrPinRange
+	}
+	f.portMu.Lock()
+	defer f.portMu.Unlock()
+	for i := 0; i < 8; i++ {
+		pin := 8*port + i
+		if mask&(1<<byte(i)) != 0 && pin < len(f.pins) {
+			f.pins[pin].Value = int((value >> byte(i)) & 0x01)
+		}
+	}
+	return f.writePort(byte(port))
+}
+
+// writePort sends the values of the pins of port. Callers hold portMu.
+func (f *Firmata) writePort(port byte) error {
+	portValue := byte(0)
 	// Build command
 	for i := byte(0); i < 8; i++ {
 		//f.logger.Printf("DigitalWrite 8*port+i : port - %v, i - %d, sum - %d", port, i, 8*port+i)
@@ -159,12 +386,90 @@ func (f *Firmata) ServoConfig(pin int, max int, min int) error {
 	return f.writeSysex(ret)
 }
 
-// AnalogWrite writes value to pin.
+// AnalogWrite writes value to pin. Pins above 15 cannot be addressed by an
+// analog message and are written with the ExtendedAnalog sysex instead.
 func (f *Firmata) AnalogWrite(pin int, value int) error {
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
 	f.pins[pin].Value = value
+	if pin >= MaxAnalogChannels {
+		return f.writeSysex([]byte{byte(ExtendedAnalog), byte(pin), byte(value & 0x7F), byte((value >> 7) & 0x7F)})
+	}
 	return f.write([]byte{byte(AnalogMessage) | byte(pin), byte(value & 0x7F), byte((value >> 7) & 0x7F)})
 }
 
+// AnalogSnapshot returns the values of the last complete analog reporting
+// cycle keyed by analog channel, along with the sequence number of that
+// cycle. The firmware reports every enabled channel once per sampling
+// interval in ascending order, so a cycle ends when a channel repeats.
+func (f *Firmata) AnalogSnapshot() (uint64, map[int]int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	values := make(map[int]int, len(f.snapshot))
+	for channel, value := range f.snapshot {
+		values[channel] = value
+	}
+	return f.analogCycle, values
+}
+
+// AddDigitalListener registers l to be called when a reported digital pin
+// changes value and returns an id for RemoveDigitalListener.
+func (f *Firmata) AddDigitalListener(l PinListener) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextListener++
+	f.digitalListeners[f.nextListener] = l
+	return f.nextListener
+}
+
+// RemoveDigitalListener unregisters the listener with id.
+func (f *Firmata) RemoveDigitalListener(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.digitalListeners, id)
+}
+
+// AddAnalogListener registers l to be called with the analog channel and
+// value of every analog report and returns an id for RemoveAnalogListener.
+func (f *Firmata) AddAnalogListener(l PinListener) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextListener++
+	f.analogListeners[f.nextListener] = l
+	return f.nextListener
+}
+
+// RemoveAnalogListener unregisters the listener with id.
+func (f *Firmata) RemoveAnalogListener(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.analogListeners, id)
+}
+
+// AddI2cListener registers l to be called with every I2C reply and returns
+// an id for RemoveI2cListener.
+func (f *Firmata) AddI2cListener(l func(I2cReply)) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextListener++
+	f.i2cListeners[f.nextListener] = l
+	return f.nextListener
+}
+
+// RemoveI2cListener unregisters the listener with id.
+func (f *Firmata) RemoveI2cListener(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.i2cListeners, id)
+}
+
+// SamplingInterval sets how often, in milliseconds, the firmware reports
+// analog pins and I2C continuous reads.
+func (f *Firmata) SamplingInterval(ms int) error {
+	return f.writeSysex([]byte{byte(SamplingInterval), byte(ms & 0x7F), byte((ms >> 7) & 0x7F)})
+}
+
 // FirmwareQuery sends the FirmwareQuery sysex code.
 func (f *Firmata) FirmwareQuery() error {
 	return f.writeSysex([]byte{byte(FirmwareQuery)})
@@ -172,6 +477,9 @@ func (f *Firmata) FirmwareQuery() error {
 
 // PinStateQuery sends a PinStateQuery for pin.
 func (f *Firmata) PinStateQuery(pin int) error {
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
 	return f.writeSysex([]byte{byte(PinStateQuery), byte(pin)})
 }
 
@@ -185,6 +493,15 @@ func (f *Firmata) CapabilitiesQuery() error {
 	return f.writeSysex([]byte{byte(CapabilityQuery)})
 }
 
+// VersionReceived returns a channel that is closed when the next protocol
+// version report arrives, which makes ProtocolVersionQuery usable as a
+// round trip probe.
+func (f *Firmata) VersionReceived() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.versionReceived
+}
+
 // AnalogMappingQuery sends the AnalogMappingQuery sysex code.
 func (f *Firmata) AnalogMappingQuery() error {
 	return f.writeSysex([]byte{byte(AnalogMappingQuery)})
@@ -200,27 +517,58 @@ func (f *Firmata) UltrasoundDistance() string {
 	return f.ultrasoundDistance
 }
 
+// UltrasoundUpdated returns a channel that is closed when the next
+// ultrasound reading arrives.
+func (f *Firmata) UltrasoundUpdated() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ultrasoundUpdated
+}
+
 // NeopixelControl sends the NeopixelControl sysex code.
 func (f *Firmata) NeopixelControl(pin int, numpixels int, color int, state int) error {
 	return f.writeSysex([]byte{byte(NeopixelControl), byte(pin), byte(numpixels), byte(color), byte(state)})
 }
 
-// ReportDigital enables or disables digital reporting for pin, a non zero
-// state enables reporting
+// ReportDigital enables or disables digital reporting for the port of pin,
+// a non zero state enables reporting
 func (f *Firmata) ReportDigital(pin int, state int) error {
-	return f.togglePinReporting(pin, state, byte(ReportDigital))
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
+	return f.togglePinReporting(pin/8, state, byte(ReportDigital))
 }
 
-// ReportAnalog enables or disables analog reporting for pin, a non zero
+// ReportAnalog enables or disables analog reporting for channel, a non zero
 // state enables reporting
-func (f *Firmata) ReportAnalog(pin int, state int) error {
-	return f.togglePinReporting(pin, state, byte(ReportAnalog))
+func (f *Firmata) ReportAnalog(channel int, state int) error {
+	if channel < 0 || channel >= MaxAnalogChannels {
+		return ErrPinRange
+	}
+	return f.togglePinReporting(channel, state, byte(ReportAnalog))
+}
+
+// checkPin fails for pins the board does not have or the protocol cannot
+// address.
+func (f *Firmata) checkPin(pin int) error {
+	if pin < 0 || pin >= MaxPins || pin >= len(f.pins) {
+		return ErrPinRange
+	}
+	return nil
 }
 
 // I2cRead reads numBytes from address once.
 func (f *Firmata) I2cRead(address int, numBytes int) error {
 	return f.writeSysex([]byte{byte(I2CRequest), byte(address), (I2CModeRead << 3),
-		byte(numBytes) & 0x7F, (byte(numBytes) >> 7) & 0x7F})
+		byte(numBytes & 0x7F), byte((numBytes >> 7) & 0x7F)})
+}
+
+// I2cReadRegister reads numBytes from register of address once. The reply
+// carries the register so it can be matched to the request.
+func (f *Firmata) I2cReadRegister(address int, register int, numBytes int) error {
+	return f.writeSysex([]byte{byte(I2CRequest), byte(address), (I2CModeRead << 3),
+		byte(register & 0x7F), byte((register >> 7) & 0x7F),
+		byte(numBytes & 0x7F), byte((numBytes >> 7) & 0x7F)})
 }
 
 // I2cWrite writes data to address.
@@ -236,7 +584,7 @@ func (f *Firmata) I2cWrite(address int, data []byte) error {
 // I2cConfig configures the delay in which a register can be read from after it
 // has been written to.
 func (f *Firmata) I2cConfig(delay int) error {
-	return f.writeSysex([]byte{byte(I2CConfig), byte(delay & 0xFF), byte((delay >> 8) & 0xFF)})
+	return f.writeSysex([]byte{byte(I2CConfig), byte(delay & 0x7F), byte((delay >> 7) & 0x7F)})
 }
 
 func (f *Firmata) togglePinReporting(pin int, state int, mode byte) error {
@@ -251,6 +599,17 @@ func (f *Firmata) togglePinReporting(pin int, state int, mode byte) error {
 		return err
 	}
 
+	f.mu.Lock()
+	reports := f.digitalReports
+	if mode == byte(ReportAnalog) {
+		reports = f.analogReports
+	}
+	if state != 0 {
+		reports[pin] = true
+	} else {
+		delete(reports, pin)
+	}
+	f.mu.Unlock()
 	return nil
 
 }
@@ -272,22 +631,139 @@ func (f *Firmata) sendCommand(cmd []byte) error {
 	return err
 }
 
-func (f *Firmata) read(length int) (buf []byte, err error) {
-	i := 0
-	for length > 0 {
-		tmp := make([]byte, length)
-		if i, err = f.connection.Read(tmp); err != nil {
-			if err.Error() != "EOF" {
-				return
+// read reads exactly length bytes from r, which must be the buffered reader
+// of the read loop so no buffered bytes are skipped.
+func (f *Firmata) read(r io.Reader, length int) (buf []byte, err error) {
+	buf = make([]byte, length)
+	n := 0
+	for n < length {
+		i, err := r.Read(buf[n:])
+		n += i
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
 			}
 			<-time.After(5 * time.Millisecond)
 		}
-		if i > 0 {
-			buf = append(buf, tmp...)
-			length = length - i
+	}
+	return buf, nil
+}
+
+// listeners returns a copy of the listeners in m.
+func (f *Firmata) listeners(m map[int]PinListener) []PinListener {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	listeners := make([]PinListener, 0, len(m))
+	for _, l := range m {
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// analogReport records value for the analog channel and dispatches it.
+func (f *Firmata) analogReport(channel int, value int) {
+	f.trackAnalogCycle(channel, value)
+
+	if channel >= 0 && len(f.analogPins) > channel && f.analogPins[channel] >= 0 {
+		if len(f.pins) > f.analogPins[channel] {
+			f.pins[f.analogPins[channel]].Value = value
+			f.logger.Printf("AnalogRead%v", channel)
+		}
+	}
+	for _, l := range f.listeners(f.analogListeners) {
+		l(channel, value)
+	}
+}
+
+// InjectAnalog processes a synthetic analog report for channel exactly as
+// if it had been received from the board, for simulations and tests.
+func (f *Firmata) InjectAnalog(channel int, value int) {
+	f.analogReport(channel, value)
+}
+
+// InjectDigital processes a synthetic digital report for pin exactly as if
+// it had been received from the board, for simulations and tests. Unlike
+// board reports it applies whatever the mode of the pin.
+func (f *Firmata) InjectDigital(pin int, value int) error {
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
+	if f.pins[pin].Value != value {
+		f.pins[pin].Value = value
+		f.notifyDigital([]int{pin})
+	}
+	return nil
+}
+
+// HoldDigital makes the read loop ignore the reports of the board for pin
+// while hold is set, so injected values stay in place. Releasing the pin
+// applies the last value the board reported for it.
+func (f *Firmata) HoldDigital(pin int, hold bool) error {
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	last, held := f.heldDigital[pin]
+	if hold {
+		if !held {
+			f.heldDigital[pin] = f.pins[pin].Value
+		}
+		f.mu.Unlock()
+		return nil
+	}
+	delete(f.heldDigital, pin)
+	f.mu.Unlock()
+	if held {
+		return f.InjectDigital(pin, last)
+	}
+	return nil
+}
+
+// HoldAnalog is HoldDigital for analog channel.
+func (f *Firmata) HoldAnalog(channel int, hold bool) error {
+	if channel < 0 || channel >= MaxAnalogChannels {
+		return ErrPinRange
+	}
+	f.mu.Lock()
+	last, held := f.heldAnalog[channel]
+	if hold {
+		if !held {
+			f.heldAnalog[channel] = -1 // unknown until the next report
+		}
+		f.mu.Unlock()
+		return nil
+	}
+	delete(f.heldAnalog, channel)
+	f.mu.Unlock()
+	if held && last >= 0 {
+		f.InjectAnalog(channel, last)
+	}
+	return nil
+}
+
+// holdReport records value as the last board value of a held pin or
+// channel and reports whether it is held.
+func (f *Firmata) holdReport(held map[int]int, pin int, value int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := held[pin]; !ok {
+		return false
+	}
+	held[pin] = value
+	return true
+}
+
+// notifyDigital calls the digital listeners for every pin in changed.
+func (f *Firmata) notifyDigital(changed []int) {
+	if len(changed) == 0 {
+		return
+	}
+	listeners := f.listeners(f.digitalListeners)
+	for _, pin := range changed {
+		for _, l := range listeners {
+			l(pin, f.pins[pin].Value)
 		}
 	}
-	return
 }
 
 func (f *Firmata) process() {
@@ -296,7 +772,7 @@ func (f *Firmata) process() {
 	for {
 		b, err := r.ReadByte()
 		if err != nil {
-			f.logger.Panic(err)
+			f.fail(err)
 			return
 		}
 		cmd := FirmataCommand(b)
@@ -314,56 +790,68 @@ func (f *Firmata) process() {
 
 		switch {
 		case ProtocolVersion == cmd:
-			buf, err := f.read(2)
+			buf, err := f.read(r, 2)
 			if err != nil {
-				f.logger.Panic(err)
+				f.fail(err)
 				return
 			}
 			f.ProtocolVersion = fmt.Sprintf("%v.%v", buf[0], buf[1])
 			f.logger.Printf("Protocol version: %s", f.ProtocolVersion)
-			f.FirmwareQuery()
+			f.mu.Lock()
+			close(f.versionReceived)
+			f.versionReceived = make(chan struct{})
+			f.mu.Unlock()
+			// Later version reports are answers to queries, not a reset
+			if !f.connected {
+				f.FirmwareQuery()
+			}
 		case AnalogMessageRangeStart <= cmd && AnalogMessageRangeEnd >= cmd:
-			buf, err := f.read(2)
+			buf, err := f.read(r, 2)
 			if err != nil {
-				f.logger.Panic(err)
+				f.fail(err)
 				return
 			}
 
 			value := uint(buf[0]) | uint(buf[1])<<7
 			pin := int((cmd & 0x0F))
-
-			if len(f.analogPins) > pin {
-				if len(f.pins) > f.analogPins[pin] {
-					f.pins[f.analogPins[pin]].Value = int(value)
-					f.logger.Printf("AnalogRead%v", pin)
-				}
+			if !f.holdReport(f.heldAnalog, pin, int(value)) {
+				f.analogReport(pin, int(value))
 			}
 		case DigitalMessageRangeStart <= cmd && DigitalMessageRangeEnd >= cmd:
 			f.logger.Printf("DigitalMessage received!!!")
-			buf, err := f.read(2)
+			buf, err := f.read(r, 2)
 			if err != nil {
-				f.logger.Panic(err)
+				f.fail(err)
 				return
 			}
 			port := cmd & 0x0F
 			portValue := buf[0] | (buf[1] << 7)
+			changed := []int{}
 			for i := 0; i < 8; i++ {
 				pinNumber := int((8*byte(port) + byte(i)))
 				if len(f.pins) > pinNumber {
 					if f.pins[pinNumber].Mode == Input || f.pins[pinNumber].Mode == Pullup {
 						f.logger.Printf("portValue : %x", portValue)
 						f.logger.Printf("i : %v", i)
-						f.pins[pinNumber].Value = int((portValue >> (byte(i) & 0x07)) & 0x01)
+						value := int((portValue >> (byte(i) & 0x07)) & 0x01)
+						if f.holdReport(f.heldDigital, pinNumber, value) {
+							continue
+						}
+						if f.pins[pinNumber].Value != value {
+							changed = append(changed, pinNumber)
+						}
+						f.pins[pinNumber].Value = value
 						f.logger.Printf("DigitalRead : %v", pinNumber)
 						f.logger.Printf("f.pins[%v].Value : %v", pinNumber, f.pins[pinNumber].Value)
 					}
 				}
 			}
+			f.notifyDigital(changed)
 		case StartSysex == cmd:
 			sysExData, err := r.ReadSlice(byte(EndSysex))
 			if err != nil {
-				f.logger.Panic(err)
-				break
+				f.fail(err)
+				return
 			}
 			// Remove EndSysEx byte
 			f.parseSysEx(sysExData[:len(sysExData)-1])
@@ -371,6 +859,20 @@ func (f *Firmata) process() {
 	}
 }
 
+// trackAnalogCycle records value for channel in the current reporting cycle,
+// publishing the previous cycle as the snapshot when a new one starts.
+func (f *Firmata) trackAnalogCycle(channel int, value int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if channel <= f.lastChannel {
+		f.snapshot = f.cycleValues
+		f.cycleValues = map[int]int{}
+		f.analogCycle++
+	}
+	f.cycleValues[channel] = value
+	f.lastChannel = channel
+}
+
 func (f *Firmata) parseSysEx(data []byte) {
 
 	// ino.printSysExData("SysEx Rx", cmd, data)
@@ -383,42 +885,58 @@ func (f *Firmata) parseSysEx(data []byte) {
 	case CapabilityResponse:
 		f.pins = []Pin{}
 		supportedModes := 0
+		resolutions := map[int]int{}
+		mode := 0
 		n := 0
-		for _, val := range data[:(len(data) - 5)] {
+		for _, val := range data {
 			if val == 127 {
 				modes := []int{}
-				for _, mode := range []int{Input, Output, Analog, Pwm, Servo, Pullup} {
+				for _, mode := range []int{Input, Output, Analog, Pwm, Servo, I2C, Pullup} {
 					if (supportedModes & (1 << byte(mode))) != 0 {
 						modes = append(modes, mode)
 					}
 				}
 
-				f.pins = append(f.pins, Pin{SupportedModes: modes, Mode: Output})
+				f.pins = append(f.pins, Pin{SupportedModes: modes, Resolutions: resolutions, Mode: Output})
 				supportedModes = 0
+				resolutions = map[int]int{}
 				n = 0
 				continue
 			}
 
 			if n == 0 {
+				mode = int(val)
 				supportedModes = supportedModes | (1 << val)
+			} else {
+				resolutions[mode] = int(val)
 			}
 			n ^= 1
 		}
 		f.logger.Printf("Total pins: %v\n", len(f.pins))
 		f.AnalogMappingQuery()
 	case AnalogMappingResponse:
+		// analogPins is indexed by channel, which need not follow pin order
 		f.analogPins = []int{}
-		for index, val := range data[:len(f.pins)-1] {
+		for index, val := range data {
+			if index >= len(f.pins) {
+				break
+			}
 			f.pins[index].AnalogChannel = int(val)
 			if val != 127 {
-				f.analogPins = append(f.analogPins, index)
+				for len(f.analogPins) <= int(val) {
+					f.analogPins = append(f.analogPins, -1)
+				}
+				f.analogPins[val] = index
 			}
 			// fmt.Println(index, ":", f.pins[index].AnalogChannel, ":", val)
 		}
-		f.logger.Printf("pin -> channel: %v\n", f.analogPins)
+		f.logger.Printf("channel -> pin: %v\n", f.analogPins)
 		f.connected = true
 	case PinStateResponse:
 		pin := data[0]
+		if int(pin) >= len(f.pins) {
+			break
+		}
 		f.pins[pin].Mode = int(data[1])
 		f.pins[pin].State = int(data[2])
 
@@ -430,43 +948,134 @@ func (f *Firmata) parseSysEx(data []byte) {
 		}
 		f.logger.Printf("PinState%v", pin)
 	case I2CReply:
+		if len(data) < 4 {
+			break
+		}
 		reply := I2cReply{
 			Address:  int(byte(data[0]) | byte(data[1])<<7),
 			Register: int(byte(data[2]) | byte(data[3])<<7),
-			Data:     []byte{byte(data[4]) | byte(data[5])<<7},
+			Data:     []byte{},
 		}
-		for i := 8; i < len(data); i = i + 2 {
-			if data[i] == byte(0xF7) {
-				break
-			}
-			if i+2 > len(data) {
-				break
-			}
+		for i := 4; i+1 < len(data); i = i + 2 {
 			reply.Data = append(reply.Data,
 				byte(data[i])|byte(data[i+1])<<7,
 			)
 		}
 		f.logger.Printf("I2cReply%v", reply)
+		f.mu.Lock()
+		listeners := make([]func(I2cReply), 0, len(f.i2cListeners))
+		for _, l := range f.i2cListeners {
+			listeners = append(listeners, l)
+		}
+		f.mu.Unlock()
+		for _, l := range listeners {
+			l(reply)
+		}
+	case AccelStepper:
+		f.parseStepper(data)
 	case FirmwareQuery:
-		name := []byte{}
-		for _, val := range data[2:(len(data) - 1)] {
-			if val != 0 {
-				name = append(name, val)
-			}
+		if len(data) < 2 {
+			break
 		}
-		f.FirmwareName = string(name[:])
+		f.FirmwareName = firmwareName(data[2:])
 		f.logger.Printf("Firmware: %s", f.FirmwareName)
-		f.CapabilitiesQuery()
-	case StringData: // Currently it's used just for ultrasound distance!!!
-		str := data[:]
-		string_data := strings.Split(string(str[:len(str)-1]), "\r")
-		f.logger.Printf("StringData%v", string_data[0])
-		distance, err := strconv.Atoi(string_data[0])
+		if !f.connected {
+			f.CapabilitiesQuery()
+		}
+	case StringData:
+		str := decodeString(data)
+		f.logger.Printf("StringData %q", str)
+		f.mu.Lock()
+		handler := f.stringHandler
+		f.mu.Unlock()
+		if handler != nil {
+			handler(str)
+		}
+		// The ultrasound sketch reports the echo time as a number
+		string_data := strings.Split(str, "\r")
+		distance, err := strconv.Atoi(strings.TrimSpace(string_data[0]))
 		if err != nil {
-			f.logger.Printf("strconv.Atoi error %v", err)
+			break
 		}
 		f.ultrasoundDistance = fmt.Sprintf("%v", distance / 29.0 / 2.0) // convert to CM
+		f.mu.Lock()
+		close(f.ultrasoundUpdated)
+		f.ultrasoundUpdated = make(chan struct{})
+		f.mu.Unlock()
+	}
+
+	f.mu.Lock()
+	handler := f.sysexHandlers[byte(cmd)]
+	f.mu.Unlock()
+	if handler != nil {
+		handler(append([]byte{}, data...))
+	}
+}
+
+// decodeString decodes STRING_DATA, sent as 7 bit pairs by Firmata
+// sketches. Sketches writing plain ASCII are accepted as well.
+func decodeString(data []byte) string {
+	if len(data)%2 != 0 {
+		return string(data)
 	}
+	for i := 1; i < len(data); i += 2 {
+		if data[i] > 1 {
+			return string(data)
+		}
+	}
+	str := make([]byte, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if c := data[i] | data[i+1]<<7; c != 0 {
+			str = append(str, c)
+		}
+	}
+	return string(str)
+}
+
+// SendSysex sends a sysex message of cmd with data, which must be 7 bit
+// bytes.
+func (f *Firmata) SendSysex(cmd byte, data []byte) error {
+	if cmd > 0x7F {
+		return fmt.Errorf("sysex command 0x%02X is not 7 bit", cmd)
+	}
+	for _, b := range data {
+		if b > 0x7F {
+			return fmt.Errorf("sysex data byte 0x%02X is not 7 bit", b)
+		}
+	}
+	return f.writeSysex(append([]byte{cmd}, data...))
+}
+
+// OnSysex registers handler for the sysex messages of cmd, replacing any
+// previous handler; a nil handler removes it. Handlers are called from the
+// read loop after the built-in processing, with the data after cmd, and
+// must return quickly.
+func (f *Firmata) OnSysex(cmd byte, handler func(data []byte)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if handler == nil {
+		delete(f.sysexHandlers, cmd)
+		return
+	}
+	f.sysexHandlers[cmd] = handler
+}
+
+// SendString sends s as STRING_DATA.
+func (f *Firmata) SendString(s string) error {
+	data := []byte{byte(StringData)}
+	for _, c := range []byte(s) {
+		data = append(data, c&0x7F, c>>7)
+	}
+	return f.writeSysex(data)
+}
+
+// OnString registers handler for STRING_DATA messages, replacing any
+// previous handler; a nil handler removes it. It is called from the read
+// loop and must return quickly.
+func (f *Firmata) OnString(handler func(string)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stringHandler = handler
 }
 
 func (f *Firmata) printByteArray(title string, data []uint8) {
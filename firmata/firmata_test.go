@@ -0,0 +1,108 @@
+package firmata
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"testing"
+)
+
+// testConn records the bytes written to the board.
+type testConn struct {
+	bytes.Buffer
+}
+
+func (c *testConn) Close() error { return nil }
+
+// newTestFirmata returns a Firmata with n pins writing to the returned
+// connection, without a handshake.
+func newTestFirmata(n int) (*Firmata, *testConn) {
+	conn := &testConn{}
+	f := New()
+	f.logger = log.New(io.Discard, "", 0)
+	f.connection = conn
+	f.pins = make([]Pin, n)
+	return f, conn
+}
+
+func TestAnalogWrite(t *testing.T) {
+	tests := []struct {
+		pin, value int
+		want       []byte
+	}{
+		{3, 0, []byte{0xE3, 0x00, 0x00}},
+		{9, 200, []byte{0xE9, 0x48, 0x01}},
+		{15, 1023, []byte{0xEF, 0x7F, 0x07}},
+		{16, 128, []byte{0xF0, 0x6F, 16, 0x00, 0x01, 0xF7}},
+		{44, 255, []byte{0xF0, 0x6F, 44, 0x7F, 0x01, 0xF7}},
+	}
+	for _, tt := range tests {
+		f, conn := newTestFirmata(70)
+		if err := f.AnalogWrite(tt.pin, tt.value); err != nil {
+			t.Fatalf("AnalogWrite(%d, %d): %v", tt.pin, tt.value, err)
+		}
+		if got := conn.Bytes(); !bytes.Equal(got, tt.want) {
+			t.Errorf("AnalogWrite(%d, %d) sent % X, want % X", tt.pin, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestReportDigital(t *testing.T) {
+	tests := []struct {
+		pin, state int
+		want       []byte
+	}{
+		{0, 1, []byte{0xD0, 0x01}},
+		{7, 1, []byte{0xD0, 0x01}},
+		{8, 1, []byte{0xD1, 0x01}},
+		{13, 0, []byte{0xD1, 0x00}},
+		{19, 5, []byte{0xD2, 0x01}},
+		{69, 1, []byte{0xD8, 0x01}},
+	}
+	for _, tt := range tests {
+		f, conn := newTestFirmata(70)
+		if err := f.ReportDigital(tt.pin, tt.state); err != nil {
+			t.Fatalf("ReportDigital(%d, %d): %v", tt.pin, tt.state, err)
+		}
+		if got := conn.Bytes(); !bytes.Equal(got, tt.want) {
+			t.Errorf("ReportDigital(%d, %d) sent % X, want % X", tt.pin, tt.state, got, tt.want)
+		}
+	}
+	f, _ := newTestFirmata(20)
+	for _, pin := range []int{-1, 20} {
+		if err := f.ReportDigital(pin, 1); err != ErrPinRange {
+			t.Errorf("ReportDigital(%d, 1) = %v, want ErrPinRange", pin, err)
+		}
+	}
+}
+
+func TestDigitalWritePort(t *testing.T) {
+	f, conn := newTestFirmata(20)
+	if err := f.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.DigitalWritePort(1, 0x03, 0xFF); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x91, 0x20, 0x00, 0x91, 0x23, 0x00}
+	if got := conn.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("sent % X, want % X", got, want)
+	}
+	if err := f.DigitalWritePort(-1, 0x01, 0x01); err != ErrPinRange {
+		t.Errorf("DigitalWritePort(-1) = %v, want ErrPinRange", err)
+	}
+}
+
+func TestSendString(t *testing.T) {
+	f, conn := newTestFirmata(20)
+	if err := f.SendString("Hi"); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xF0, 0x71, 'H', 0x00, 'i', 0x00, 0xF7}
+	if got := conn.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("SendString sent % X, want % X", got, want)
+	}
+	if err := f.SendSysex(0x01, []byte{0x80}); err == nil {
+		t.Errorf("SendSysex accepted an 8 bit data byte")
+	}
+}
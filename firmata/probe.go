@@ -0,0 +1,79 @@
+package firmata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNoFirmata is returned by Probe when no Firmata reply was received.
+var ErrNoFirmata = errors.New("no firmata reply")
+
+// Probe asks the device on conn for its protocol version and firmware and
+// waits up to timeout for both replies, ignoring any other traffic. It does
+// not require a connected Firmata client, so it can be used to identify
+// the board on an unknown port.
+func Probe(conn io.ReadWriter, timeout time.Duration) (firmware string, protocol string, err error) {
+	query := []byte{byte(ProtocolVersion), byte(StartSysex), byte(FirmwareQuery), byte(EndSysex)}
+	if _, err = conn.Write(query); err != nil {
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	var sysex []byte
+	var version []byte
+	inSysex := false
+	buf := make([]byte, 64)
+	for time.Now().Before(deadline) {
+		n, rerr := conn.Read(buf)
+		if rerr != nil && rerr != io.EOF {
+			return "", "", rerr
+		}
+		if n == 0 {
+			<-time.After(10 * time.Millisecond)
+			continue
+		}
+		for _, b := range buf[:n] {
+			switch {
+			case version != nil && len(version) < 2:
+				version = append(version, b)
+				if len(version) == 2 {
+					protocol = fmt.Sprintf("%v.%v", version[0], version[1])
+				}
+			case b == byte(ProtocolVersion):
+				version = []byte{}
+			case b == byte(StartSysex):
+				sysex, inSysex = []byte{}, true
+			case b == byte(EndSysex) && inSysex:
+				inSysex = false
+				if len(sysex) > 3 && sysex[0] == byte(FirmwareQuery) {
+					firmware = firmwareName(sysex[3:])
+				}
+			case inSysex:
+				sysex = append(sysex, b)
+			}
+		}
+		if firmware != "" && protocol != "" {
+			return firmware, protocol, nil
+		}
+	}
+	if firmware == "" && protocol == "" {
+		return "", "", ErrNoFirmata
+	}
+	return firmware, protocol, nil
+}
+
+// firmwareName decodes the 7 bit pairs of a firmware name.
+func firmwareName(data []byte) string {
+	name := []byte{}
+	for i := 0; i+1 < len(data); i += 2 {
+		name = append(name, data[i]|data[i+1]<<7)
+	}
+	return string(name)
+}
+
+// Version returns the firmware name and protocol version reported by the
+// board during Connect.
+func (f *Firmata) Version() (firmware string, protocol string) {
+	return f.FirmwareName, f.ProtocolVersion
+}
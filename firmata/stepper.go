@@ -0,0 +1,212 @@
+package firmata
+
+import (
+	"errors"
+	"math"
+)
+
+// AccelStepper subcommands
+const (
+	stepperConfig       byte = 0x00
+	stepperZero         byte = 0x01
+	stepperStep         byte = 0x02
+	stepperStop         byte = 0x05
+	stepperReport       byte = 0x06
+	stepperSpeed        byte = 0x09
+	stepperMoveComplete byte = 0x0A
+)
+
+// Tone subcommands
+const (
+	toneTone   byte = 0x00
+	toneNoTone byte = 0x01
+)
+
+// MaxSteppers is the number of stepper devices AccelStepperFirmata drives.
+const MaxSteppers = 10
+
+var ErrStepperDevice = errors.New("stepper device out of range")
+
+// stepperState tracks a stepper device, guarded by mu.
+type stepperState struct {
+	position int
+	complete chan struct{} // closed and replaced on every move complete
+}
+
+// stepper returns the state of device, creating it if needed. Callers
+// hold mu.
+func (f *Firmata) stepper(device int) *stepperState {
+	s, ok := f.steppers[device]
+	if !ok {
+		s = &stepperState{complete: make(chan struct{})}
+		f.steppers[device] = s
+	}
+	return s
+}
+
+// StepperConfig configures device to drive a motor with iface
+// (StepperDriver, StepperTwoWire, ...) on pins, in whole steps.
+func (f *Firmata) StepperConfig(device int, iface int, pins []int) error {
+	if device < 0 || device >= MaxSteppers {
+		return ErrStepperDevice
+	}
+	data := []byte{byte(AccelStepper), stepperConfig, byte(device), byte(iface&0x07) << 4}
+	for _, pin := range pins {
+		if err := f.checkPin(pin); err != nil {
+			return err
+		}
+		data = append(data, byte(pin))
+	}
+	return f.writeSysex(data)
+}
+
+// StepperSpeed sets the maximum speed of device in steps per second.
+func (f *Firmata) StepperSpeed(device int, speed float64) error {
+	if device < 0 || device >= MaxSteppers {
+		return ErrStepperDevice
+	}
+	data := append([]byte{byte(AccelStepper), stepperSpeed, byte(device)}, encodeCustomFloat(speed)...)
+	return f.writeSysex(data)
+}
+
+// StepperStep moves device by steps relative to its position, negative
+// steps turn backwards. StepperMoveComplete signals the end of the move.
+func (f *Firmata) StepperStep(device int, steps int) error {
+	if device < 0 || device >= MaxSteppers {
+		return ErrStepperDevice
+	}
+	data := append([]byte{byte(AccelStepper), stepperStep, byte(device)}, encodeInt32(steps)...)
+	return f.writeSysex(data)
+}
+
+// StepperStop stops device as fast as possible.
+func (f *Firmata) StepperStop(device int) error {
+	if device < 0 || device >= MaxSteppers {
+		return ErrStepperDevice
+	}
+	return f.writeSysex([]byte{byte(AccelStepper), stepperStop, byte(device)})
+}
+
+// StepperZero makes the current position of device its zero position.
+func (f *Firmata) StepperZero(device int) error {
+	if device < 0 || device >= MaxSteppers {
+		return ErrStepperDevice
+	}
+	return f.writeSysex([]byte{byte(AccelStepper), stepperZero, byte(device)})
+}
+
+// StepperMoveComplete returns a channel that is closed when the next move
+// of device completes.
+func (f *Firmata) StepperMoveComplete(device int) <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stepper(device).complete
+}
+
+// StepperPosition returns the position of device reported by its last
+// completed move or stop.
+func (f *Firmata) StepperPosition(device int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stepper(device).position
+}
+
+// Tone plays a square wave of frequency Hz on pin for duration
+// milliseconds, zero plays until NoTone.
+func (f *Firmata) Tone(pin int, frequency int, duration int) error {
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
+	return f.writeSysex([]byte{byte(ToneData), toneTone, byte(pin),
+		byte(frequency & 0x7F), byte((frequency >> 7) & 0x7F),
+		byte(duration & 0x7F), byte((duration >> 7) & 0x7F),
+	})
+}
+
+// NoTone stops the tone on pin.
+func (f *Firmata) NoTone(pin int) error {
+	if err := f.checkPin(pin); err != nil {
+		return err
+	}
+	return f.writeSysex([]byte{byte(ToneData), toneNoTone, byte(pin)})
+}
+
+// parseStepper handles the position reports and move complete replies of
+// AccelStepper, data starts at the subcommand.
+func (f *Firmata) parseStepper(data []byte) {
+	if len(data) < 7 || (data[0] != stepperReport && data[0] != stepperMoveComplete) {
+		return
+	}
+	device := int(data[1])
+	position := decodeInt32(data[2:7])
+	f.logger.Printf("Stepper %d at %d", device, position)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.stepper(device)
+	s.position = position
+	if data[0] == stepperMoveComplete {
+		close(s.complete)
+		s.complete = make(chan struct{})
+	}
+}
+
+// encodeInt32 encodes a signed 32 bit value as five 7 bit bytes, the sign
+// in bit 3 of the last byte.
+func encodeInt32(v int) []byte {
+	u := uint32(v)
+	if v < 0 {
+		u = uint32(-v)
+	}
+	data := []byte{
+		byte(u & 0x7F), byte((u >> 7) & 0x7F), byte((u >> 14) & 0x7F),
+		byte((u >> 21) & 0x7F), byte((u >> 28) & 0x07),
+	}
+	if v < 0 {
+		data[4] |= 0x08
+	}
+	return data
+}
+
+func decodeInt32(data []byte) int {
+	v := int(data[0]) | int(data[1])<<7 | int(data[2])<<14 | int(data[3])<<21 | int(data[4]&0x07)<<28
+	if data[4]&0x08 != 0 {
+		v = -v
+	}
+	return v
+}
+
+// encodeCustomFloat encodes v in the AccelStepperFirmata float format: a
+// 23 bit significand, a 4 bit decimal exponent biased by 11 and a sign
+// bit, as four 7 bit bytes.
+func encodeCustomFloat(v float64) []byte {
+	const maxSignificand = 1 << 23
+	if v == 0 {
+		return []byte{0, 0, 0, 0}
+	}
+	sign := byte(0)
+	if v < 0 {
+		sign, v = 1, -v
+	}
+	exponent := int(math.Floor(math.Log10(v)))
+	v /= math.Pow(10, float64(exponent))
+	for v != math.Trunc(v) && v*10 < maxSignificand && exponent > -11 {
+		exponent--
+		v *= 10
+	}
+	for v >= maxSignificand {
+		exponent++
+		v /= 10
+	}
+	exponent += 11
+	if exponent < 0 {
+		return []byte{0, 0, 0, 0}
+	}
+	if exponent > 0x0F {
+		exponent = 0x0F
+	}
+	significand := int(v)
+	return []byte{
+		byte(significand & 0x7F), byte((significand >> 7) & 0x7F), byte((significand >> 14) & 0x7F),
+		byte((significand>>21)&0x03) | byte(exponent)<<2 | sign<<6,
+	}
+}
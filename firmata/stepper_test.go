@@ -0,0 +1,68 @@
+package firmata
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestEncodeInt32(t *testing.T) {
+	tests := []struct {
+		value int
+		want  []byte
+	}{
+		{0, []byte{0x00, 0x00, 0x00, 0x00, 0x00}},
+		{1, []byte{0x01, 0x00, 0x00, 0x00, 0x00}},
+		{-1, []byte{0x01, 0x00, 0x00, 0x00, 0x08}},
+		{127, []byte{0x7F, 0x00, 0x00, 0x00, 0x00}},
+		{128, []byte{0x00, 0x01, 0x00, 0x00, 0x00}},
+		{-200, []byte{0x48, 0x01, 0x00, 0x00, 0x08}},
+		{1 << 28, []byte{0x00, 0x00, 0x00, 0x00, 0x01}},
+		{math.MaxInt32, []byte{0x7F, 0x7F, 0x7F, 0x7F, 0x07}},
+		{-math.MaxInt32, []byte{0x7F, 0x7F, 0x7F, 0x7F, 0x0F}},
+	}
+	for _, tt := range tests {
+		got := encodeInt32(tt.value)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeInt32(%d) = % X, want % X", tt.value, got, tt.want)
+		}
+		if v := decodeInt32(got); v != tt.value {
+			t.Errorf("decodeInt32(encodeInt32(%d)) = %d", tt.value, v)
+		}
+	}
+}
+
+// decodeCustomFloat is the decoding done by AccelStepperFirmata.
+func decodeCustomFloat(b []byte) float64 {
+	significand := int(b[0]) | int(b[1])<<7 | int(b[2])<<14 | int(b[3]&0x03)<<21
+	exponent := int(b[3]>>2&0x0F) - 11
+	v := float64(significand) * math.Pow(10, float64(exponent))
+	if b[3]&0x40 != 0 {
+		v = -v
+	}
+	return v
+}
+
+func TestEncodeCustomFloat(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  []byte
+	}{
+		{0, []byte{0x00, 0x00, 0x00, 0x00}},
+		{1, []byte{0x01, 0x00, 0x00, 11 << 2}},
+		{100, []byte{0x01, 0x00, 0x00, 13 << 2}},
+		{-2.5, []byte{25, 0x00, 0x00, 10<<2 | 0x40}},
+		{0.25, []byte{25, 0x00, 0x00, 9 << 2}},
+	}
+	for _, tt := range tests {
+		if got := encodeCustomFloat(tt.value); !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeCustomFloat(%v) = % X, want % X", tt.value, got, tt.want)
+		}
+	}
+	for _, v := range []float64{1, 3.14159, -42.5, 1000, 12345.678, 0.001, 8388607} {
+		got := decodeCustomFloat(encodeCustomFloat(v))
+		if math.Abs(got-v) > math.Abs(v)*1e-6 {
+			t.Errorf("encodeCustomFloat(%v) decodes to %v", v, got)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package goduino
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// machineQueue is the number of pending events a Machine buffers.
+const machineQueue = 32
+
+// PinValue is a value to write to a digital pin.
+type PinValue struct {
+	Pin   int
+	Value int
+}
+
+// WriteAction returns an action writing each value in order, for use as a
+// state entry or exit action.
+func (ino *Goduino) WriteAction(writes ...PinValue) func() error {
+	return func() error {
+		for _, w := range writes {
+			if err := ino.DigitalWrite(w.Pin, w.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// State is a state of a Machine. OnEnter and OnExit are optional.
+type State struct {
+	Name    string
+	OnEnter func() error
+	OnExit  func() error
+}
+
+type pinTrigger struct {
+	pin, value int
+	to         string
+}
+
+type timeout struct {
+	after time.Duration
+	to    string
+}
+
+// machineState is the state of a Machine saved in a StateStore.
+type machineState struct {
+	State   string    `json:"state"`
+	Entered time.Time `json:"entered"`
+}
+
+type machineEvent struct {
+	name  string
+	pin   int
+	value int
+}
+
+// Machine is a finite state machine whose transitions are triggered by
+// named events, digital pin changes or timers. Transitions and actions run
+// on a single goroutine in the order their triggers arrive.
+type Machine struct {
+	ino    *Goduino
+	name   string
+	states map[string]State
+	events map[string]map[string]string // state -> event -> next state
+	pins   map[string][]pinTrigger
+	timers map[string]timeout
+
+	mu       sync.Mutex
+	initial  string
+	current  string
+	queue    chan machineEvent
+	stop     chan struct{}
+	done     chan struct{}
+	listener int
+	store    *StateStore
+}
+
+// NewMachine creates an empty state machine.
+func (ino *Goduino) NewMachine(name string) *Machine {
+	return &Machine{
+		ino:    ino,
+		name:   name,
+		states: map[string]State{},
+		events: map[string]map[string]string{},
+		pins:   map[string][]pinTrigger{},
+		timers: map[string]timeout{},
+	}
+}
+
+// AddState adds s to the machine.
+func (m *Machine) AddState(s State) {
+	m.states[s.Name] = s
+}
+
+// OnEvent moves from state from to state to when event is fired.
+func (m *Machine) OnEvent(from, event, to string) {
+	if m.events[from] == nil {
+		m.events[from] = map[string]string{}
+	}
+	m.events[from][event] = to
+}
+
+// OnPin moves from state from to state to when digital pin reports value.
+// The pin must be configured as an input.
+func (m *Machine) OnPin(from string, pin, value int, to string) {
+	m.pins[from] = append(m.pins[from], pinTrigger{pin: pin, value: value, to: to})
+}
+
+// After moves from state from to state to once the machine has stayed in
+// from for d.
+func (m *Machine) After(from string, d time.Duration, to string) {
+	m.timers[from] = timeout{after: d, to: to}
+}
+
+// State returns the current state.
+func (m *Machine) State() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// SetInitial sets the state entered by Start.
+func (m *Machine) SetInitial(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initial = name
+}
+
+// Name returns the name of the machine as a Driver.
+func (m *Machine) Name() string { return "machine " + m.name }
+
+// Init does nothing, the actions configure their own pins.
+func (m *Machine) Init() error { return nil }
+
+// Halt stops the machine like Stop.
+func (m *Machine) Halt() error {
+	m.Stop()
+	return nil
+}
+
+// Start enters the initial state set with SetInitial and begins
+// processing triggers. With a StateStore set, a machine that was running
+// before the process restarted resumes in its saved state instead, without
+// running the entry action again; a pending After timer fires when it
+// would have without the restart, or right away if that time has passed.
+func (m *Machine) Start() error {
+	m.mu.Lock()
+	initial := m.initial
+	m.mu.Unlock()
+	for from, targets := range m.events {
+		for _, to := range targets {
+			if err := m.checkStates(from, to); err != nil {
+				return err
+			}
+		}
+	}
+	for from, triggers := range m.pins {
+		for _, t := range triggers {
+			if err := m.checkStates(from, t.to); err != nil {
+				return err
+			}
+		}
+	}
+	for from, t := range m.timers {
+		if err := m.checkStates(from, t.to); err != nil {
+			return err
+		}
+	}
+	if err := m.checkStates(initial); err != nil {
+		return err
+	}
+	store := m.ino.states()
+	var saved machineState
+	if !store.loadPrevious(m.stateKey(), &saved) || m.checkStates(saved.State) != nil {
+		saved = machineState{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return errors.New("machine is already running")
+	}
+	m.queue = make(chan machineEvent, machineQueue)
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	if len(m.pins) > 0 {
+		queue := m.queue
+		m.listener = m.ino.board.AddDigitalListener(func(pin int, value int) {
+			select {
+			case queue <- machineEvent{pin: pin, value: value}:
+			default:
+				m.ino.logger.Printf("machine %s queue full, dropping pin %d\r\n", m.name, pin)
+			}
+		})
+	}
+	m.store = store
+	go m.run(initial, saved, m.queue, m.stop, m.done)
+	return nil
+}
+
+func (m *Machine) stateKey() string { return "machine:" + m.name }
+
+func (m *Machine) checkStates(names ...string) error {
+	for _, name := range names {
+		if _, ok := m.states[name]; !ok {
+			return fmt.Errorf("machine %s has no state %q", m.name, name)
+		}
+	}
+	return nil
+}
+
+// Fire delivers the named event to the machine.
+func (m *Machine) Fire(event string) {
+	m.mu.Lock()
+	queue, done := m.queue, m.done
+	m.mu.Unlock()
+	if queue == nil {
+		return
+	}
+	select {
+	case queue <- machineEvent{name: event, pin: -1}:
+	case <-done:
+	}
+}
+
+// Stop ends processing triggers without running the exit action of the
+// current state.
+func (m *Machine) Stop() {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done, m.queue = nil, nil, nil
+	if len(m.pins) > 0 && stop != nil {
+		m.ino.board.RemoveDigitalListener(m.listener)
+	}
+	m.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (m *Machine) run(initial string, saved machineState, queue <-chan machineEvent, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	var timer <-chan time.Time
+	setState := func(name string, entered time.Time) {
+		m.mu.Lock()
+		m.current = name
+		store := m.store
+		m.mu.Unlock()
+		if err := store.save(m.stateKey(), machineState{State: name, Entered: entered}); err != nil {
+			m.ino.logger.Printf("machine %s state not saved: %v\r\n", m.name, err)
+		}
+		timer = nil
+		if t, ok := m.timers[name]; ok {
+			timer = time.After(t.after - time.Since(entered))
+		}
+	}
+	enter := func(name string) {
+		m.ino.logger.Printf("machine %s -> %s\r\n", m.name, name)
+		setState(name, time.Now())
+		m.action(m.states[name].OnEnter, name, "enter")
+	}
+	if saved.State != "" {
+		m.ino.logger.Printf("machine %s resumed in %s\r\n", m.name, saved.State)
+		setState(saved.State, saved.Entered)
+	} else {
+		enter(initial)
+	}
+	for {
+		var to string
+		select {
+		case <-stop:
+			return
+		case <-timer:
+			to = m.timers[m.State()].to
+		case e := <-queue:
+			state := m.State()
+			if e.pin < 0 {
+				to = m.events[state][e.name]
+				break
+			}
+			for _, t := range m.pins[state] {
+				if t.pin == e.pin && t.value == e.value {
+					to = t.to
+					break
+				}
+			}
+		}
+		if to == "" {
+			continue
+		}
+		m.action(m.states[m.State()].OnExit, m.State(), "exit")
+		enter(to)
+	}
+}
+
+func (m *Machine) action(fn func() error, state, kind string) {
+	if fn == nil {
+		return
+	}
+	err := fn()
+	if err != nil {
+		m.ino.logger.Printf("machine %s %s action of %s failed: %v\r\n", m.name, kind, state, err)
+	}
+	m.ino.ReportDriver(m, err)
+}
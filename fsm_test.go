@@ -0,0 +1,128 @@
+package goduino
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitState waits for m to enter state.
+func waitState(t *testing.T, m *Machine, state string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for m.State() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("machine in state %q, want %q", m.State(), state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMachine(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	if err := ino.PinMode(2, Input); err != nil {
+		t.Fatal(err)
+	}
+	m := ino.NewMachine("lamp")
+	m.AddState(State{Name: "off", OnEnter: ino.WriteAction(PinValue{13, 0})})
+	m.AddState(State{Name: "on", OnEnter: ino.WriteAction(PinValue{13, 1})})
+	m.AddState(State{Name: "idle"})
+	m.OnEvent("off", "toggle", "on")
+	m.OnEvent("on", "toggle", "off")
+	m.OnPin("off", 2, 1, "on")
+	m.After("on", 20*time.Millisecond, "idle")
+
+	m.SetInitial("off")
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer m.Stop()
+	waitState(t, m, "off")
+	board.AssertPin(t, 13, 0)
+
+	m.Fire("toggle")
+	waitState(t, m, "on")
+	board.AssertPin(t, 13, 1)
+	waitState(t, m, "idle")
+
+	// Unknown events are ignored
+	m.Fire("toggle")
+	m.Stop()
+	if got := m.State(); got != "idle" {
+		t.Errorf("state = %q after an unknown event, want idle", got)
+	}
+
+	m.SetInitial("off")
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, m, "off")
+	if err := board.InjectDigital(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, m, "on")
+}
+
+func TestMachineUnknownState(t *testing.T) {
+	ino, _ := newTestGoduino(t)
+	m := ino.NewMachine("broken")
+	m.AddState(State{Name: "a"})
+	m.OnEvent("a", "go", "b")
+	m.SetInitial("a")
+	if err := m.Start(); err == nil {
+		m.Stop()
+		t.Errorf("Start accepted a transition to an unknown state")
+	}
+}
+
+func TestMachineResume(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ino.SetStateStore(store)
+	newLamp := func() *Machine {
+		m := ino.NewMachine("lamp")
+		m.AddState(State{Name: "off", OnEnter: ino.WriteAction(PinValue{13, 0})})
+		m.AddState(State{Name: "on", OnEnter: ino.WriteAction(PinValue{13, 1})})
+		m.OnEvent("off", "toggle", "on")
+		m.SetInitial("off")
+		return m
+	}
+	m := newLamp()
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	m.Fire("toggle")
+	waitState(t, m, "on")
+	m.Stop()
+
+	// Restarting in the same process enters the initial state again
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, m, "off")
+	board.AssertPin(t, 13, 0)
+	m.Fire("toggle")
+	waitState(t, m, "on")
+	m.Stop()
+
+	// A restarted process resumes without running the entry action
+	store, err = OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ino.SetStateStore(store)
+	board.ClearCalls()
+	m = newLamp()
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+	waitState(t, m, "on")
+	if _, ok := hasCall(board, "DigitalWrite", 13); ok {
+		t.Errorf("resumed machine ran the entry action")
+	}
+}
@@ -1,12 +1,16 @@
 package goduino
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/argandas/goduino/firmata"
 	"github.com/tarm/serial"
 	"io"
 	"log"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 	//"strconv"
 )
@@ -17,26 +21,67 @@ const (
 	Analog = firmata.Analog
 	Pwm    = firmata.Pwm
 	Servo  = firmata.Servo
+	I2C    = firmata.I2C
 	Pullup = firmata.Pullup
 )
 
+// Errors
+var ErrTimeout = errors.New("timed out waiting for the board")
+var ErrNotConnected = errors.New("board is not connected")
+
+// connectTimeout bounds the handshake of Connect.
+const connectTimeout = 30 * time.Second
+
 type firmataBoard interface {
 	Connect(io.ReadWriteCloser) error
+	ConnectContext(context.Context, io.ReadWriteCloser) error
 	Disconnect() error
+	Done() <-chan struct{}
+	Err() error
 	Pins() []firmata.Pin
 	AnalogWrite(int, int) error
 	SetPinMode(int, int) error
 	ReportAnalog(int, int) error
 	ReportDigital(int, int) error
 	DigitalWrite(int, int) error
+	DigitalWritePort(int, byte, byte) error
 	I2cRead(int, int) error
+	I2cReadRegister(int, int, int) error
 	I2cWrite(int, []byte) error
 	I2cConfig(int) error
 	PinStateQuery(int) error
 	ServoConfig(int, int, int) error
 	UltrasoundReport(int) error
 	UltrasoundDistance() string
+	UltrasoundUpdated() <-chan struct{}
 	NeopixelControl(int, int, int, int) error
+	AnalogSnapshot() (uint64, map[int]int)
+	AddDigitalListener(firmata.PinListener) int
+	RemoveDigitalListener(int)
+	AddAnalogListener(firmata.PinListener) int
+	RemoveAnalogListener(int)
+	SamplingInterval(int) error
+	ProtocolVersionQuery() error
+	VersionReceived() <-chan struct{}
+	AddI2cListener(func(firmata.I2cReply)) int
+	RemoveI2cListener(int)
+	InjectAnalog(int, int)
+	InjectDigital(int, int) error
+	Version() (string, string)
+	StepperConfig(int, int, []int) error
+	StepperSpeed(int, float64) error
+	StepperStep(int, int) error
+	StepperStop(int) error
+	StepperMoveComplete(int) <-chan struct{}
+	StepperPosition(int) int
+	Tone(int, int, int) error
+	NoTone(int) error
+	HoldDigital(int, bool) error
+	HoldAnalog(int, bool) error
+	SendSysex(byte, []byte) error
+	OnSysex(byte, func([]byte))
+	SendString(string) error
+	OnString(func(string))
 }
 // Arduino Firmata client for golang
 type Goduino struct {
@@ -47,6 +92,69 @@ type Goduino struct {
 	openSP  func(port string) (io.ReadWriteCloser, error)
 	logger  *log.Logger
 	verbose bool
+	mu      sync.Mutex
+	servos  map[int]*servoState
+
+	serialConfig serial.Config
+
+	variables map[string]Variable
+	macros    map[string][]Step
+
+	safeStates   map[int]int
+	timedOutputs map[int]*timedOutput
+	analogSubs   map[*AnalogSubscription]struct{}
+
+	samplingInterval time.Duration
+	oversamplers     map[int]*oversampler
+	dimming          map[int]DimmingCurve
+	animations       map[int]*Animation
+	notifiers        map[<-chan int]*pinNotifier
+
+	profile *BoardProfile
+	fenced  bool
+
+	// I2C requests are serialized so replies match their request
+	i2cMu      sync.Mutex
+	i2cEnabled bool
+	i2cReplies chan firmata.I2cReply
+
+	reconnect  *ReconnectPolicy
+	supervisor *supervisor
+
+	drivers []*managedDriver
+
+	// steps per revolution of the configured stepper devices
+	steppers map[int]int
+
+	overrides map[overrideKey]int
+
+	// last outputs, nil unless SetWriteCache enabled caching
+	written map[int]writtenState
+
+	journal *journalAttachment
+
+	stateStore *StateStore
+
+	expiries map[int]*outputExpiry
+
+	auditActor atomic.Value // string
+
+	// STRING_DATA routing
+	stringHandler func(string)
+	channels      map[string][]*VirtualChannel
+
+	// sysex handlers of the extensions and of OnSysex, per command
+	sysexRoutes map[byte]*sysexRoute
+
+	// identity requests are serialized like I2C requests
+	identityMu sync.Mutex
+
+	params *ParamStore
+
+	// hardware serial ports opened through Serial Firmata
+	uarts map[firmata.SerialPort]*UART
+
+	tasks taskLoop
 }
 
 // Creates a new Goduino object and connects to the Arduino board
@@ -55,15 +163,14 @@ type Goduino struct {
 func New(name string, args ...interface{}) *Goduino {
 	// Create new Goduino client
 	goduino := &Goduino{
-		name:  name,
-		port:  "",
-		conn:  nil,
-		board: firmata.New(),
-		openSP: func(port string) (io.ReadWriteCloser, error) {
-			return serial.OpenPort(&serial.Config{Name: port, Baud: 57600})
-		},
-		logger:  log.New(os.Stdout, fmt.Sprintf("[%s] ", name), log.Ltime),
-		verbose: true,
+		name:         name,
+		port:         "",
+		conn:         nil,
+		board:        firmata.New(),
+		serialConfig: serial.Config{Baud: defaultBaud},
+		logger:       log.New(os.Stdout, fmt.Sprintf("[%s] ", name), log.Ltime),
+		verbose:      true,
+		servos:       make(map[int]*servoState),
 	}
 	// Parse variadic args
 	for _, arg := range args {
@@ -72,14 +179,52 @@ func New(name string, args ...interface{}) *Goduino {
 			goduino.port = arg.(string)
 		case io.ReadWriteCloser:
 			goduino.conn = arg.(io.ReadWriteCloser)
+		case firmataBoard:
+			goduino.board = arg.(firmataBoard)
+		case serial.Config:
+			goduino.serialConfig = arg.(serial.Config)
+		case *serial.Config:
+			goduino.serialConfig = *arg.(*serial.Config)
 		}
 	}
+	if goduino.port == "" {
+		goduino.port = goduino.serialConfig.Name
+	}
+	goduino.openSP = goduino.openSerial
 	return goduino
 }
 
-// Connect starts a connection to the firmata board.
+// Connect starts a connection to the firmata board. Without a port or
+// connection the first board found by Discover is used. Boards passed to
+// New, like the goduinotest simulator, are connected directly.
 func (ino *Goduino) Connect() error {
-	if ino.conn == nil {
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	err := ino.ConnectContext(ctx)
+	if err == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return err
+}
+
+// ConnectContext is Connect with the handshake bounded by ctx instead of a
+// fixed timeout. When a driver fails to start, the board is disconnected
+// again before the error is returned.
+func (ino *Goduino) ConnectContext(ctx context.Context) error {
+	_, serialBoard := unwrapBoard(ino.board).(*firmata.Firmata)
+	if serialBoard && ino.conn == nil && ino.port == "" {
+		boards, err := DiscoverConfig(ino.serialConfig)
+		if err != nil {
+			return err
+		}
+		if len(boards) == 0 {
+			return ErrNoBoard
+		}
+		ino.port = boards[0].Port
+		ino.logger.Printf("discovered %s (%s, protocol %s)\r\n", boards[0].Port, boards[0].Firmware, boards[0].Protocol)
+	}
+	opened := false
+	if serialBoard && ino.conn == nil {
 		// Try to connect to serial port
 		sp, err := ino.openSP(ino.Port())
 		if err != nil {
@@ -87,14 +232,48 @@ func (ino *Goduino) Connect() error {
 		}
 		// Serial connection was successful
 		ino.conn = sp
+		opened = true
 	}
 	// Firmata connection
-	return ino.board.Connect(ino.conn)
+	if err := ino.board.ConnectContext(ctx, ino.conn); err != nil {
+		if opened {
+			// the next attempt opens the port again
+			if ino.conn != nil {
+				ino.conn.Close()
+			}
+			ino.conn = nil
+		}
+		return err
+	}
+	ino.forgetWrites()
+	profile := newBoardProfile(ino.board.Pins())
+	ino.mu.Lock()
+	ino.profile = profile
+	ino.mu.Unlock()
+	ino.logger.Printf("board profile: %s, %d pins, %d analog\r\n", profile.Model, profile.Pins, len(profile.AnalogPins))
+	if err := ino.startDrivers(); err != nil {
+		ino.Disconnect()
+		if opened {
+			ino.conn = nil
+		}
+		return err
+	}
+	ino.startSupervisor()
+	return nil
 }
 
-// Disconnect closes the io connection to the firmata board
+// Disconnect halts the drivers, restores the pending timed writes, drives
+// the pins with a safe state to their value and closes the io connection
+// to the firmata board
 func (ino *Goduino) Disconnect() (err error) {
+	ino.stopSupervisor()
+	ino.haltDrivers()
+	ino.stopAnimations()
+	ino.stopServoTimers()
+	ino.stopOutputExpiries()
 	if ino.board != nil {
+		ino.stopTimedOutputs()
+		ino.ApplySafeStates()
 		// Disconnect firmata board
 		return ino.board.Disconnect()
 	}
@@ -114,34 +293,60 @@ func (ino *Goduino) ServoConfig(pin, min, max int) error {
 	//	return err
 	//}
 
+	s := ino.servo(pin)
+	ino.mu.Lock()
+	s.setRange(min, max)
+	ino.mu.Unlock()
 	return ino.board.ServoConfig(pin, max, min)
 }
 
-// ServoWrite writes the 0-180 degree angle to the specified pin.
+// ServoWrite writes the 0-180 degree angle to the specified pin, adjusted
+// by the trim and reverse settings of the servo.
 func (ino *Goduino) ServoWrite(pin int, angle byte) (err error) {
+	if err = ino.checkFence(); err != nil {
+		return err
+	}
 	//p, err := strconv.Atoi(pin)
 	//if err != nil {
 	//	return err
 	//}
 
+	if err = ino.checkMode(pin, Servo); err != nil {
+		return err
+	}
 	if ino.board.Pins()[pin].Mode != firmata.Servo {
-		err = ino.board.SetPinMode(pin, firmata.Servo)
-		if err != nil {
+		if err = ino.servoAttach(pin); err != nil {
 			return err
 		}
 	}
-	ino.logger.Printf("ServoWrite(%d, %d)\r\n", pin, int(angle))
-	err = ino.board.AnalogWrite(pin, int(angle))
+	value := ino.servoAngle(pin, angle)
+	ino.logger.Printf("ServoWrite(%d, %d)\r\n", pin, value)
+	if err = ino.board.AnalogWrite(pin, value); err != nil {
+		return err
+	}
+	ino.rearmOutputs(pin)
+	ino.servoMoved(pin, angle)
+	ino.servoTouch(pin)
 	return
 }
 
 // PwmWrite writes the 0-254 value to the specified pin
 func (ino *Goduino) PwmWrite(pin int, level byte) (err error) {
+	if err = ino.checkFence(); err != nil {
+		return err
+	}
 	//p, err := strconv.Atoi(pin)
 	//if err != nil {
 	//	return err
 	//}
 
+	if err = ino.checkMode(pin, Pwm); err != nil {
+		return err
+	}
+	if ino.cachedWrite(pin, Pwm, int(level)) {
+		ino.rearmOutputs(pin)
+		return nil
+	}
 	if ino.board.Pins()[pin].Mode != firmata.Pwm {
 		err = ino.board.SetPinMode(pin, firmata.Pwm)
 		if err != nil {
@@ -149,7 +354,11 @@ func (ino *Goduino) PwmWrite(pin int, level byte) (err error) {
 		}
 	}
 	ino.logger.Printf("PwmWrite(%d, %d)\r\n", pin, int(level))
-	err = ino.board.AnalogWrite(pin, int(level))
+	if err = ino.board.AnalogWrite(pin, int(level)); err != nil {
+		return err
+	}
+	ino.recordWrite(pin, Pwm, int(level))
+	ino.rearmOutputs(pin)
 	return
 }
 
@@ -165,6 +374,9 @@ func (ino *Goduino) UltrasoundDistance() string { return ino.board.UltrasoundDis
 
 // NeopixelControl set state of neopixel.
 func (ino *Goduino) NeopixelControl(pin int, numpixels int, color int, state int) (err error) {
+	if err = ino.checkFence(); err != nil {
+		return err
+	}
 	ino.logger.Printf("NeopixelControl(%d, %d, %d, %d)\r\n", pin, numpixels, color, state)
 	err = ino.board.NeopixelControl(pin, numpixels, color, state)
 	return
@@ -172,10 +384,11 @@ func (ino *Goduino) NeopixelControl(pin int, numpixels int, color int, state int
 
 // PinMode configures the specified pin to behave either as an input or an output.
 func (ino *Goduino) PinMode(pin, mode int) error {
-	// Check if pin is valid
-	if uint8(pin) < 0 || pin > len(ino.board.Pins()) {
-		return fmt.Errorf("Invalid pin number %v\n", pin)
+	// Check if pin is valid and supports mode
+	if err := ino.checkMode(pin, mode); err != nil {
+		return err
 	}
+	ino.forgetWrites(pin)
 	switch mode {
 	// If mode == Input
 	case Input:
@@ -189,12 +402,13 @@ func (ino *Goduino) PinMode(pin, mode int) error {
 		<-time.After(10 * time.Millisecond)
 	// If mode == Analog
 	case Analog:
-		pin = ino.digitalPin(pin)
+		channel := pin
+		pin, _ = ino.analogPin(channel)
 		// Set pin mode
 		if err := ino.board.SetPinMode(pin, mode); err != nil {
 			return err
 		}
-		if err := ino.board.ReportAnalog(pin, 1); err != nil {
+		if err := ino.board.ReportAnalog(channel, 1); err != nil {
 			return err
 		}
 		<-time.After(10 * time.Millisecond)
@@ -225,11 +439,6 @@ func (ino *Goduino) Delay(duration time.Duration) {
 	time.Sleep(duration)
 }
 
-// digitalPin converts pin number to digital mapping
-func (ino *Goduino) digitalPin(pin int) int {
-	return pin + 14
-}
-
 type PinMode uint8
 
 func (m PinMode) String() string {
@@ -244,6 +453,8 @@ func (m PinMode) String() string {
 		return "PWM"
 	case m == Servo:
 		return "SERVO"
+	case m == I2C:
+		return "I2C"
 	case m == Pullup:
 		return "PULLUP"
 	}
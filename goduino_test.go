@@ -0,0 +1,292 @@
+package goduino
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/argandas/goduino/goduinotest"
+)
+
+// newTestGoduino connects a quiet Goduino to a simulated Uno.
+func newTestGoduino(t *testing.T, args ...interface{}) (*Goduino, *goduinotest.Board) {
+	t.Helper()
+	board := goduinotest.NewBoard()
+	ino := New("test", append([]interface{}{board}, args...)...)
+	ino.logger = log.New(io.Discard, "", 0)
+	if err := ino.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { ino.Disconnect() })
+	return ino, board
+}
+
+// hasCall reports whether board received method for pin.
+func hasCall(board *goduinotest.Board, method string, pin int) (goduinotest.Call, bool) {
+	for _, c := range board.Calls() {
+		if c.Method == method && c.Pin == pin {
+			return c, true
+		}
+	}
+	return goduinotest.Call{}, false
+}
+
+// answerSysex waits for the first sysex message of cmd sent to board for
+// which reply returns a message and injects that message back.
+func answerSysex(board *goduinotest.Board, cmd byte, reply func(data []byte) []byte) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		for _, c := range board.Calls() {
+			if c.Method != "SendSysex" || c.Pin != int(cmd) || len(c.Data) == 0 {
+				continue
+			}
+			if data := reply(c.Data); data != nil {
+				board.InjectSysex(cmd, data)
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPinMode(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	if err := ino.PinMode(2, Input); err != nil {
+		t.Fatalf("PinMode(2, Input): %v", err)
+	}
+	board.AssertMode(t, 2, Input)
+	if _, ok := hasCall(board, "ReportDigital", 2); !ok {
+		t.Errorf("PinMode(2, Input) did not enable reporting")
+	}
+	if err := ino.PinMode(0, Analog); err != nil {
+		t.Fatalf("PinMode(0, Analog): %v", err)
+	}
+	board.AssertMode(t, 14, Analog)
+	if _, ok := hasCall(board, "ReportAnalog", 0); !ok {
+		t.Errorf("PinMode(0, Analog) did not enable reporting")
+	}
+
+	tests := []struct {
+		pin, mode int
+	}{
+		{2, Pwm},
+		{1, Servo},
+		{20, Output},
+		{-1, Output},
+		{6, Analog},
+	}
+	for _, tt := range tests {
+		if err := ino.PinMode(tt.pin, tt.mode); err == nil {
+			t.Errorf("PinMode(%d, %s) succeeded", tt.pin, PinMode(tt.mode))
+		}
+	}
+}
+
+func TestDigitalWrite(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	if err := ino.PinMode(13, Input); err != nil {
+		t.Fatal(err)
+	}
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatalf("DigitalWrite(13, 1): %v", err)
+	}
+	board.AssertMode(t, 13, Output)
+	board.AssertPin(t, 13, 1)
+	if err := ino.DigitalWrite(13, 0); err != nil {
+		t.Fatalf("DigitalWrite(13, 0): %v", err)
+	}
+	board.AssertPin(t, 13, 0)
+	if err := ino.DigitalWrite(20, 1); err == nil {
+		t.Errorf("DigitalWrite(20, 1) succeeded")
+	}
+}
+
+func TestDigitalWriteFenced(t *testing.T) {
+	board := goduinotest.NewBoard()
+	ino := New("test", board)
+	ino.logger = log.New(io.Discard, "", 0)
+	ino.FenceUntilConfigured()
+	if err := ino.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer ino.Disconnect()
+	if err := ino.DigitalWrite(13, 1); err != ErrNotConfigured {
+		t.Errorf("fenced DigitalWrite = %v, want ErrNotConfigured", err)
+	}
+	if err := ino.SendString("hello"); err != ErrNotConfigured {
+		t.Errorf("fenced SendString = %v, want ErrNotConfigured", err)
+	}
+	if _, err := ino.ReadIdentity(); err != ErrNotConfigured {
+		t.Errorf("fenced ReadIdentity = %v, want ErrNotConfigured", err)
+	}
+	if _, err := ino.Params().Get("threshold"); err != ErrNotConfigured {
+		t.Errorf("fenced parameter Get = %v, want ErrNotConfigured", err)
+	}
+	board.AssertPin(t, 13, 0)
+	ino.Configure()
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertPin(t, 13, 1)
+}
+
+func TestServoWrite(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	if err := ino.ServoConfig(9, 600, 2400); err != nil {
+		t.Fatal(err)
+	}
+	if err := ino.ServoWrite(9, 90); err != nil {
+		t.Fatalf("ServoWrite(9, 90): %v", err)
+	}
+	board.AssertMode(t, 9, Servo)
+	board.AssertPin(t, 9, 90)
+
+	ino.ServoReverse(9, true)
+	ino.ServoTrim(9, 5)
+	if err := ino.ServoWrite(9, 30); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertPin(t, 9, 155)
+	if err := ino.ServoWrite(9, 0); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertPin(t, 9, 180)
+
+	// Detaching forgets the pulse range, attaching again restores it
+	if err := ino.ServoDetach(9); err != nil {
+		t.Fatal(err)
+	}
+	board.ClearCalls()
+	if err := ino.ServoWrite(9, 90); err != nil {
+		t.Fatal(err)
+	}
+	c, ok := hasCall(board, "ServoConfig", 9)
+	if !ok || c.Value != 600 || len(c.Args) != 1 || c.Args[0] != 2400 {
+		t.Errorf("attach sent ServoConfig %+v, want range 600-2400", c)
+	}
+	if err := ino.ServoWrite(1, 90); err == nil {
+		t.Errorf("ServoWrite(1, 90) succeeded on a pin without servo support")
+	}
+}
+
+func TestWritePort(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	if err := ino.SetBits(1, 0x21); err != nil {
+		t.Fatalf("SetBits(1, 0x21): %v", err)
+	}
+	board.AssertMode(t, 8, Output)
+	board.AssertMode(t, 13, Output)
+	board.AssertPin(t, 8, 1)
+	board.AssertPin(t, 13, 1)
+	board.AssertPin(t, 9, 0)
+	if err := ino.ClearBits(1, 0x01); err != nil {
+		t.Fatalf("ClearBits(1, 0x01): %v", err)
+	}
+	board.AssertPin(t, 8, 0)
+	board.AssertPin(t, 13, 1)
+
+	tests := []struct {
+		port int
+		mask byte
+	}{
+		{-1, 0x01},
+		{2, 0x10},
+		{3, 0x01},
+	}
+	for _, tt := range tests {
+		if err := ino.SetBits(tt.port, tt.mask); err == nil {
+			t.Errorf("SetBits(%d, 0x%02X) succeeded", tt.port, tt.mask)
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	ino, board := newTestGoduino(t, "sim")
+	ino.openSP = func(string) (io.ReadWriteCloser, error) { return nil, nil }
+	ino.SetWriteCache(true)
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ino.ServoConfig(9, 600, 2400); err != nil {
+		t.Fatal(err)
+	}
+	if err := ino.ServoWrite(9, 45); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ino.SubscribeAnalog(0, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	board.ClearCalls()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ino.Reconnect(ctx); err != nil {
+		t.Fatalf("Reconnect: %v", err)
+	}
+	if _, ok := hasCall(board, "ServoConfig", 9); !ok {
+		t.Errorf("Reconnect did not restore the servo range")
+	}
+	c, ok := hasCall(board, "SamplingInterval", 0)
+	if !ok || c.Value != 5 {
+		t.Errorf("Reconnect sent sampling interval %+v, want 5 ms", c)
+	}
+
+	// Writes are not served from the cache of the previous connection
+	board.ClearCalls()
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := hasCall(board, "DigitalWrite", 13); !ok {
+		t.Errorf("DigitalWrite after Reconnect was served from the cache")
+	}
+}
+
+func TestReconnectWithoutPort(t *testing.T) {
+	ino, _ := newTestGoduino(t)
+	if err := ino.Reconnect(context.Background()); err != ErrNoReconnect {
+		t.Errorf("Reconnect = %v, want ErrNoReconnect", err)
+	}
+}
+
+// testDriver records its lifecycle and fails Start with err.
+type testDriver struct {
+	name    string
+	err     error
+	started bool
+	halted  bool
+}
+
+func (d *testDriver) Name() string { return d.name }
+func (d *testDriver) Init() error  { return nil }
+func (d *testDriver) Start() error { d.started = d.err == nil; return d.err }
+func (d *testDriver) Halt() error  { d.halted = true; return nil }
+
+func TestConnectDriverFails(t *testing.T) {
+	board := goduinotest.NewBoard()
+	ino := New("test", board)
+	ino.logger = log.New(io.Discard, "", 0)
+	ok := &testDriver{name: "ok"}
+	bad := &testDriver{name: "bad", err: errors.New("no sensor")}
+	if err := ino.AddDriver(ok); err != nil {
+		t.Fatal(err)
+	}
+	if err := ino.AddDriver(bad, ok); err != nil {
+		t.Fatal(err)
+	}
+	if err := ino.Connect(); err == nil {
+		t.Fatal("Connect succeeded with a failing driver")
+	}
+	if !ok.started || !ok.halted {
+		t.Errorf("driver ok started %v, halted %v, want both", ok.started, ok.halted)
+	}
+	select {
+	case <-board.Done():
+	default:
+		t.Errorf("board still connected after a driver failed to start")
+	}
+}
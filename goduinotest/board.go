@@ -0,0 +1,676 @@
+// Package goduinotest provides a simulated board for testing code written
+// against goduino without hardware:
+//
+//	board := goduinotest.NewBoard()
+//	ino := goduino.New("test", board)
+//	ino.Connect()
+//	ino.DigitalWrite(13, 1)
+//	board.AssertPin(t, 13, 1)
+//
+// The board records every command, answers queries right away and lets
+// tests inject analog, digital and I2C input.
+package goduinotest
+
+import (
+	"context"
+	"github.com/argandas/goduino/firmata"
+	"io"
+	"sync"
+	"testing"
+)
+
+// Call is a command received by the board. Pin holds the pin, port,
+// channel, device or I2C address the command was sent to, Args the
+// arguments after Value and Data the bytes of I2C writes.
+type Call struct {
+	Method string
+	Pin    int
+	Value  int
+	Args   []int
+	Data   []byte
+}
+
+// Board is a simulated Arduino Uno running StandardFirmata. Reported pins
+// report continuously: every analog snapshot is a new complete cycle.
+type Board struct {
+	mu        sync.Mutex
+	pins      []firmata.Pin
+	calls     []Call
+	connected bool
+	done      chan struct{}
+
+	digitalListeners map[int]firmata.PinListener
+	analogListeners  map[int]firmata.PinListener
+	i2cListeners     map[int]func(firmata.I2cReply)
+	nextListener     int
+
+	analogCycle uint64
+	analog      map[int]int // last value by channel
+	i2c         map[int]map[int]byte
+	steppers    map[int]*stepper
+
+	sysexHandlers map[byte]func([]byte)
+	stringHandler func(string)
+
+	ultrasound        string
+	ultrasoundUpdated chan struct{}
+	versionReceived   chan struct{}
+}
+
+type stepper struct {
+	position int
+	complete chan struct{}
+}
+
+// NewBoard returns a simulated Uno: digital pins 0-19, PWM on 3, 5, 6, 9,
+// 10 and 11, analog channels 0-5 on pins 14-19 and I2C on pins 18 and 19.
+func NewBoard() *Board {
+	b := &Board{
+		done:              make(chan struct{}),
+		digitalListeners:  map[int]firmata.PinListener{},
+		analogListeners:   map[int]firmata.PinListener{},
+		i2cListeners:      map[int]func(firmata.I2cReply){},
+		analog:            map[int]int{},
+		i2c:               map[int]map[int]byte{},
+		steppers:          map[int]*stepper{},
+		sysexHandlers:     map[byte]func([]byte){},
+		ultrasoundUpdated: make(chan struct{}),
+		versionReceived:   make(chan struct{}),
+	}
+	for i := 0; i < 20; i++ {
+		pin := firmata.Pin{Mode: firmata.Output, AnalogChannel: 127}
+		pin.SupportedModes = []int{firmata.Input, firmata.Output, firmata.Pullup}
+		pin.Resolutions = map[int]int{firmata.Input: 1, firmata.Output: 1, firmata.Pullup: 1}
+		if i >= 2 {
+			pin.SupportedModes = append(pin.SupportedModes, firmata.Servo)
+			pin.Resolutions[firmata.Servo] = 14
+		}
+		switch i {
+		case 3, 5, 6, 9, 10, 11:
+			pin.SupportedModes = append(pin.SupportedModes, firmata.Pwm)
+			pin.Resolutions[firmata.Pwm] = 8
+		}
+		if i >= 14 {
+			pin.SupportedModes = append(pin.SupportedModes, firmata.Analog)
+			pin.Resolutions[firmata.Analog] = 10
+			pin.AnalogChannel = i - 14
+		}
+		if i == 18 || i == 19 {
+			pin.SupportedModes = append(pin.SupportedModes, firmata.I2C)
+			pin.Resolutions[firmata.I2C] = 1
+		}
+		b.pins = append(b.pins, pin)
+	}
+	return b
+}
+
+// record adds a call, callers hold mu.
+func (b *Board) record(method string, pin, value int, args ...int) {
+	b.calls = append(b.calls, Call{Method: method, Pin: pin, Value: value, Args: args})
+}
+
+func (b *Board) checkPin(pin int) error {
+	if pin < 0 || pin >= len(b.pins) {
+		return firmata.ErrPinRange
+	}
+	return nil
+}
+
+// Calls returns the commands received so far, oldest first.
+func (b *Board) Calls() []Call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Call{}, b.calls...)
+}
+
+// ClearCalls forgets the recorded commands.
+func (b *Board) ClearCalls() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = nil
+}
+
+// PinValue returns the last value written to or injected on pin.
+func (b *Board) PinValue(pin int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pins[pin].Value
+}
+
+// PinMode returns the mode of pin.
+func (b *Board) PinMode(pin int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pins[pin].Mode
+}
+
+// AssertPin fails t unless pin holds value.
+func (b *Board) AssertPin(t testing.TB, pin, value int) {
+	t.Helper()
+	if got := b.PinValue(pin); got != value {
+		t.Errorf("pin %d: got value %d, want %d", pin, got, value)
+	}
+}
+
+// AssertMode fails t unless pin is in mode.
+func (b *Board) AssertMode(t testing.TB, pin, mode int) {
+	t.Helper()
+	if got := b.PinMode(pin); got != mode {
+		t.Errorf("pin %d: got mode %d, want %d", pin, got, mode)
+	}
+}
+
+// SetI2cRegisters stores data in the registers of the I2C device at
+// address starting at register. Register reads are answered from the
+// stored bytes, writes update them.
+func (b *Board) SetI2cRegisters(address, register int, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.setI2c(address, register, data)
+}
+
+func (b *Board) setI2c(address, register int, data []byte) {
+	regs, ok := b.i2c[address]
+	if !ok {
+		regs = map[int]byte{}
+		b.i2c[address] = regs
+	}
+	for i, v := range data {
+		regs[register+i] = v
+	}
+}
+
+// InjectI2c delivers reply to the I2C listeners as if the board sent it.
+func (b *Board) InjectI2c(reply firmata.I2cReply) {
+	b.mu.Lock()
+	listeners := make([]func(firmata.I2cReply), 0, len(b.i2cListeners))
+	for _, l := range b.i2cListeners {
+		listeners = append(listeners, l)
+	}
+	b.mu.Unlock()
+	for _, l := range listeners {
+		l(reply)
+	}
+}
+
+// SetUltrasoundDistance sets the distance in cm returned by ultrasound
+// reports.
+func (b *Board) SetUltrasoundDistance(cm string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ultrasound = cm
+}
+
+// Connect simulates the handshake, conn is ignored and may be nil.
+func (b *Board) Connect(conn io.ReadWriteCloser) error {
+	return b.ConnectContext(context.Background(), conn)
+}
+
+// ConnectContext simulates the handshake, conn is ignored and may be nil.
+func (b *Board) ConnectContext(ctx context.Context, conn io.ReadWriteCloser) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.connected {
+		return firmata.ErrConnected
+	}
+	b.connected = true
+	b.done = make(chan struct{})
+	return nil
+}
+
+// Disconnect ends the simulated connection.
+func (b *Board) Disconnect() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.connected {
+		b.connected = false
+		close(b.done)
+	}
+	return nil
+}
+
+// Done returns a channel closed by Disconnect.
+func (b *Board) Done() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done
+}
+
+// Err always returns nil, the simulated link never fails.
+func (b *Board) Err() error { return nil }
+
+// Pins returns a copy of the pins.
+func (b *Board) Pins() []firmata.Pin {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]firmata.Pin{}, b.pins...)
+}
+
+// Version returns the simulated firmware and protocol version.
+func (b *Board) Version() (string, string) { return "goduinotest", "2.5" }
+
+func (b *Board) AnalogWrite(pin int, value int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPin(pin); err != nil {
+		return err
+	}
+	b.record("AnalogWrite", pin, value)
+	b.pins[pin].Value = value
+	return nil
+}
+
+func (b *Board) SetPinMode(pin int, mode int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPin(pin); err != nil {
+		return err
+	}
+	b.record("SetPinMode", pin, mode)
+	b.pins[pin].Mode = mode
+	return nil
+}
+
+func (b *Board) ReportAnalog(channel int, state int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("ReportAnalog", channel, state)
+	return nil
+}
+
+func (b *Board) ReportDigital(pin int, state int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("ReportDigital", pin, state)
+	return nil
+}
+
+func (b *Board) DigitalWrite(pin int, value int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPin(pin); err != nil {
+		return err
+	}
+	b.record("DigitalWrite", pin, value)
+	b.pins[pin].Value = value
+	return nil
+}
+
+func (b *Board) DigitalWritePort(port int, mask byte, value byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("DigitalWritePort", port, int(value), int(mask))
+	for i := 0; i < 8; i++ {
+		pin := 8*port + i
+		if mask&(1<<byte(i)) != 0 && pin < len(b.pins) {
+			b.pins[pin].Value = int((value >> byte(i)) & 0x01)
+		}
+	}
+	return nil
+}
+
+func (b *Board) I2cRead(address int, numBytes int) error {
+	return b.I2cReadRegister(address, 0, numBytes)
+}
+
+// I2cReadRegister answers with the stored registers of the device,
+// unknown registers read as zero.
+func (b *Board) I2cReadRegister(address int, register int, numBytes int) error {
+	b.mu.Lock()
+	b.record("I2cRead", address, register, numBytes)
+	reply := firmata.I2cReply{Address: address, Register: register, Data: make([]byte, numBytes)}
+	for i := range reply.Data {
+		reply.Data[i] = b.i2c[address][register+i]
+	}
+	b.mu.Unlock()
+	b.InjectI2c(reply)
+	return nil
+}
+
+// I2cWrite stores data after the register byte in the device registers.
+func (b *Board) I2cWrite(address int, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("I2cWrite", address, 0)
+	b.calls[len(b.calls)-1].Data = append([]byte{}, data...)
+	if len(data) > 1 {
+		b.setI2c(address, int(data[0]), data[1:])
+	}
+	return nil
+}
+
+func (b *Board) I2cConfig(delay int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("I2cConfig", 0, delay)
+	return nil
+}
+
+func (b *Board) PinStateQuery(pin int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("PinStateQuery", pin, 0)
+	return nil
+}
+
+func (b *Board) ServoConfig(pin int, max int, min int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPin(pin); err != nil {
+		return err
+	}
+	b.record("ServoConfig", pin, min, max)
+	return nil
+}
+
+// UltrasoundReport answers right away with the distance set by
+// SetUltrasoundDistance, if any.
+func (b *Board) UltrasoundReport(pin int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("UltrasoundReport", pin, 0)
+	if b.ultrasound != "" {
+		close(b.ultrasoundUpdated)
+		b.ultrasoundUpdated = make(chan struct{})
+	}
+	return nil
+}
+
+func (b *Board) UltrasoundDistance() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ultrasound
+}
+
+func (b *Board) UltrasoundUpdated() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ultrasoundUpdated
+}
+
+func (b *Board) NeopixelControl(pin int, numpixels int, color int, state int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("NeopixelControl", pin, color, numpixels, state)
+	return nil
+}
+
+// AnalogSnapshot returns a new cycle holding the last value of every
+// analog channel.
+func (b *Board) AnalogSnapshot() (uint64, map[int]int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.analogCycle++
+	values := make(map[int]int, len(b.analog))
+	for channel, value := range b.analog {
+		values[channel] = value
+	}
+	return b.analogCycle, values
+}
+
+func (b *Board) AddDigitalListener(l firmata.PinListener) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextListener++
+	b.digitalListeners[b.nextListener] = l
+	return b.nextListener
+}
+
+func (b *Board) RemoveDigitalListener(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.digitalListeners, id)
+}
+
+func (b *Board) AddAnalogListener(l firmata.PinListener) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextListener++
+	b.analogListeners[b.nextListener] = l
+	return b.nextListener
+}
+
+func (b *Board) RemoveAnalogListener(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.analogListeners, id)
+}
+
+func (b *Board) AddI2cListener(l func(firmata.I2cReply)) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextListener++
+	b.i2cListeners[b.nextListener] = l
+	return b.nextListener
+}
+
+func (b *Board) RemoveI2cListener(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.i2cListeners, id)
+}
+
+func (b *Board) SamplingInterval(ms int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("SamplingInterval", 0, ms)
+	return nil
+}
+
+// ProtocolVersionQuery is answered right away.
+func (b *Board) ProtocolVersionQuery() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	close(b.versionReceived)
+	b.versionReceived = make(chan struct{})
+	return nil
+}
+
+func (b *Board) VersionReceived() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.versionReceived
+}
+
+// InjectAnalog sets analog channel to value and calls the analog
+// listeners as if the board reported it.
+func (b *Board) InjectAnalog(channel int, value int) {
+	b.mu.Lock()
+	b.analog[channel] = value
+	for i := range b.pins {
+		if b.pins[i].AnalogChannel == channel {
+			b.pins[i].Value = value
+		}
+	}
+	listeners := make([]firmata.PinListener, 0, len(b.analogListeners))
+	for _, l := range b.analogListeners {
+		listeners = append(listeners, l)
+	}
+	b.mu.Unlock()
+	for _, l := range listeners {
+		l(channel, value)
+	}
+}
+
+// InjectDigital sets pin to value and calls the digital listeners if it
+// changed, as if the board reported it.
+func (b *Board) InjectDigital(pin int, value int) error {
+	b.mu.Lock()
+	if err := b.checkPin(pin); err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	if b.pins[pin].Value == value {
+		b.mu.Unlock()
+		return nil
+	}
+	b.pins[pin].Value = value
+	listeners := make([]firmata.PinListener, 0, len(b.digitalListeners))
+	for _, l := range b.digitalListeners {
+		listeners = append(listeners, l)
+	}
+	b.mu.Unlock()
+	for _, l := range listeners {
+		l(pin, value)
+	}
+	return nil
+}
+
+// HoldDigital records the hold, the simulated board sends no reports that
+// could replace an injected value.
+func (b *Board) HoldDigital(pin int, hold bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPin(pin); err != nil {
+		return err
+	}
+	b.record("HoldDigital", pin, boolValue(hold))
+	return nil
+}
+
+// HoldAnalog records the hold, like HoldDigital.
+func (b *Board) HoldAnalog(channel int, hold bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("HoldAnalog", channel, boolValue(hold))
+	return nil
+}
+
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (b *Board) SendSysex(cmd byte, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("SendSysex", int(cmd), 0)
+	b.calls[len(b.calls)-1].Data = append([]byte{}, data...)
+	return nil
+}
+
+func (b *Board) OnSysex(cmd byte, handler func([]byte)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if handler == nil {
+		delete(b.sysexHandlers, cmd)
+		return
+	}
+	b.sysexHandlers[cmd] = handler
+}
+
+// InjectSysex calls the handler registered for cmd as if the board sent
+// the sysex message.
+func (b *Board) InjectSysex(cmd byte, data []byte) {
+	b.mu.Lock()
+	handler := b.sysexHandlers[cmd]
+	b.mu.Unlock()
+	if handler != nil {
+		handler(data)
+	}
+}
+
+func (b *Board) SendString(s string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("SendString", 0, 0)
+	b.calls[len(b.calls)-1].Data = []byte(s)
+	return nil
+}
+
+func (b *Board) OnString(handler func(string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stringHandler = handler
+}
+
+// InjectString calls the string handler as if the board sent s as
+// STRING_DATA.
+func (b *Board) InjectString(s string) {
+	b.mu.Lock()
+	handler := b.stringHandler
+	b.mu.Unlock()
+	if handler != nil {
+		handler(s)
+	}
+}
+
+// stepper returns the state of device, callers hold mu.
+func (b *Board) stepper(device int) *stepper {
+	s, ok := b.steppers[device]
+	if !ok {
+		s = &stepper{complete: make(chan struct{})}
+		b.steppers[device] = s
+	}
+	return s
+}
+
+func (b *Board) StepperConfig(device int, iface int, pins []int) error {
+	if device < 0 || device >= firmata.MaxSteppers {
+		return firmata.ErrStepperDevice
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("StepperConfig", device, iface, pins...)
+	return nil
+}
+
+func (b *Board) StepperSpeed(device int, speed float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("StepperSpeed", device, int(speed))
+	return nil
+}
+
+// StepperStep completes the move right away.
+func (b *Board) StepperStep(device int, steps int) error {
+	if device < 0 || device >= firmata.MaxSteppers {
+		return firmata.ErrStepperDevice
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("StepperStep", device, steps)
+	s := b.stepper(device)
+	s.position += steps
+	close(s.complete)
+	s.complete = make(chan struct{})
+	return nil
+}
+
+func (b *Board) StepperStop(device int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("StepperStop", device, 0)
+	return nil
+}
+
+func (b *Board) StepperMoveComplete(device int) <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stepper(device).complete
+}
+
+func (b *Board) StepperPosition(device int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stepper(device).position
+}
+
+func (b *Board) Tone(pin int, frequency int, duration int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPin(pin); err != nil {
+		return err
+	}
+	b.record("Tone", pin, frequency, duration)
+	return nil
+}
+
+func (b *Board) NoTone(pin int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("NoTone", pin, 0)
+	return nil
+}
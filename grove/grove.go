@@ -0,0 +1,146 @@
+// Package grove provides constructors for common Grove modules wired to a
+// Grove base shield, using the shield port names and each module's fixed
+// wiring:
+//
+//	ino := goduino.New("uno", "/dev/ttyACM0")
+//	ino.Connect()
+//	button := grove.NewButton(ino, grove.D2)
+//	lcd, err := grove.NewLCD(ino)
+//
+// Digital modules read or drive the first signal pin of their port, analog
+// modules the first channel of theirs.
+package grove
+
+import (
+	"errors"
+	"math"
+
+	"github.com/argandas/goduino"
+)
+
+// Digital ports of the base shield, numbered by their first pin
+const (
+	D2 = 2
+	D3 = 3
+	D4 = 4
+	D5 = 5
+	D6 = 6
+	D7 = 7
+	D8 = 8
+)
+
+// Analog ports of the base shield, numbered by their first channel
+const (
+	A0 = 0
+	A1 = 1
+	A2 = 2
+	A3 = 3
+)
+
+// Button is a Grove button or touch sensor, which drives its signal high
+// while pressed.
+type Button struct {
+	pin *goduino.NativePin
+}
+
+// NewButton returns the button on digital port.
+func NewButton(ino *goduino.Goduino, port int) *Button {
+	return &Button{pin: ino.DigitalPin(port)}
+}
+
+// Pressed reports whether the button is pressed.
+func (b *Button) Pressed() (bool, error) {
+	v, err := b.pin.ReadDigital()
+	return v != 0, err
+}
+
+// Relay is a Grove relay, energized by a high signal.
+type Relay struct {
+	pin *goduino.NativePin
+}
+
+// NewRelay returns the relay on digital port.
+func NewRelay(ino *goduino.Goduino, port int) *Relay {
+	return &Relay{pin: ino.DigitalPin(port)}
+}
+
+// Set energizes the relay if on is set and releases it otherwise.
+func (r *Relay) Set(on bool) error {
+	if on {
+		return r.pin.WriteDigital(1)
+	}
+	return r.pin.WriteDigital(0)
+}
+
+// On energizes the relay.
+func (r *Relay) On() error { return r.Set(true) }
+
+// Off releases the relay.
+func (r *Relay) Off() error { return r.Set(false) }
+
+// LightSensor is a Grove light sensor, a phototransistor whose output
+// rises with light. It is not calibrated in lux.
+type LightSensor struct {
+	ino     *goduino.Goduino
+	channel int
+}
+
+// NewLightSensor returns the light sensor on analog port.
+func NewLightSensor(ino *goduino.Goduino, port int) *LightSensor {
+	return &LightSensor{ino: ino, channel: port}
+}
+
+// Level returns the light level from 0 (dark) to 1.
+func (l *LightSensor) Level() (float64, error) {
+	v, err := l.ino.AnalogRead(l.channel)
+	if err != nil {
+		return 0, err
+	}
+	return float64(v) / 1023, nil
+}
+
+// ErrThermistorRange is returned when the thermistor reads as open or
+// shorted.
+var ErrThermistorRange = errors.New("thermistor reading out of range")
+
+// Grove temperature sensor v1.2 thermistor: 100k at 25°C, B = 4275, in a
+// divider with a 100k resistor
+const (
+	thermistorB  = 4275
+	thermistorT0 = 298.15
+)
+
+// TemperatureSensor is a Grove temperature sensor v1.2. It implements
+// goduino.Thermometer.
+type TemperatureSensor struct {
+	ino     *goduino.Goduino
+	channel int
+}
+
+// NewTemperatureSensor returns the temperature sensor on analog port.
+func NewTemperatureSensor(ino *goduino.Goduino, port int) *TemperatureSensor {
+	return &TemperatureSensor{ino: ino, channel: port}
+}
+
+// Temperature returns the temperature of the thermistor.
+func (t *TemperatureSensor) Temperature() (goduino.Celsius, error) {
+	v, err := t.ino.AnalogRead(t.channel)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 || v >= 1023 {
+		return 0, ErrThermistorRange
+	}
+	// Resistance relative to the 100k at 25°C
+	r := 1023/float64(v) - 1
+	return goduino.Celsius(1/(math.Log(r)/thermistorB+1/thermistorT0) - 273.15), nil
+}
+
+var _ goduino.Thermometer = (*TemperatureSensor)(nil)
+
+// NewUltrasonicRanger returns the Grove ultrasonic ranger on digital port.
+// It uses one pin for trigger and echo, as the UltrasoundReport sysex
+// expects.
+func NewUltrasonicRanger(ino *goduino.Goduino, port int) *goduino.Ultrasound {
+	return ino.Ultrasound(port)
+}
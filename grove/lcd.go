@@ -0,0 +1,67 @@
+package grove
+
+import "github.com/argandas/goduino"
+
+// Grove LCD RGB Backlight addresses and registers. The text controller
+// takes a control byte selecting instruction or data before each byte; the
+// backlight is a PCA9633 (module v4 and earlier).
+const (
+	lcdTextAddr = 0x3E
+	lcdRGBAddr  = 0x62
+
+	lcdInstruction = 0x80
+	lcdData        = 0x40
+
+	rgbMode1  = 0x00
+	rgbMode2  = 0x01
+	rgbBlue   = 0x02
+	rgbGreen  = 0x03
+	rgbRed    = 0x04
+	rgbLEDOut = 0x08
+)
+
+// LCD is a Grove LCD RGB Backlight, a 16x2 display on any I2C port.
+type LCD struct {
+	*goduino.LCD
+	rgb *goduino.I2CDevice
+}
+
+type lcdBus struct {
+	dev *goduino.I2CDevice
+}
+
+// WriteLCD sends v after the control byte selecting instruction or data.
+func (b lcdBus) WriteLCD(v byte, rs bool) error {
+	if rs {
+		return b.dev.WriteRegister(lcdData, v)
+	}
+	return b.dev.WriteRegister(lcdInstruction, v)
+}
+
+// NewLCD initializes the display and turns the backlight white.
+func NewLCD(ino *goduino.Goduino) (*LCD, error) {
+	text, err := goduino.NewLCD(lcdBus{ino.I2C(lcdTextAddr)}, 16, 2)
+	if err != nil {
+		return nil, err
+	}
+	l := &LCD{LCD: text, rgb: ino.I2C(lcdRGBAddr)}
+	// Normal mode, every LED driven by its PWM register
+	for _, reg := range [][2]byte{{rgbMode1, 0x00}, {rgbMode2, 0x00}, {rgbLEDOut, 0xAA}} {
+		if err := l.rgb.WriteRegister(int(reg[0]), reg[1]); err != nil {
+			return nil, err
+		}
+	}
+	return l, l.SetColor(255, 255, 255)
+}
+
+// SetColor sets the backlight color.
+func (l *LCD) SetColor(r, g, b byte) error {
+	for _, reg := range [][2]byte{{rgbRed, r}, {rgbGreen, g}, {rgbBlue, b}} {
+		if err := l.rgb.WriteRegister(int(reg[0]), reg[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ goduino.TextDisplay = (*LCD)(nil)
@@ -0,0 +1,133 @@
+package goduino
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DriverStatus is the health of a managed driver.
+type DriverStatus struct {
+	Name              string
+	Running           bool
+	LastSuccess       time.Time // last operation that succeeded
+	ConsecutiveErrors int       // failed operations since LastSuccess
+	LastError         error
+	LastErrorTime     time.Time
+}
+
+// ReportDriver records the outcome of an operation of the managed driver
+// d, e.g. a sensor read, for its DriverStatus. Bundled drivers report
+// themselves; custom drivers call it from their own loops. Outcomes of
+// drivers that were not added are ignored.
+func (ino *Goduino) ReportDriver(d Driver, err error) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	i := ino.driverIndex(d)
+	if i < 0 {
+		return
+	}
+	ino.drivers[i].report(err)
+}
+
+// report records err, callers hold mu.
+func (m *managedDriver) report(err error) {
+	if err == nil {
+		m.status.LastSuccess = time.Now()
+		m.status.ConsecutiveErrors = 0
+		return
+	}
+	m.status.ConsecutiveErrors++
+	m.status.LastError = err
+	m.status.LastErrorTime = time.Now()
+}
+
+// DriverStatus returns the health of d, false if it was not added.
+func (ino *Goduino) DriverStatus(d Driver) (DriverStatus, bool) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	i := ino.driverIndex(d)
+	if i < 0 {
+		return DriverStatus{}, false
+	}
+	return ino.drivers[i].snapshot(), true
+}
+
+// DriverStatuses returns the health of every driver in start order.
+func (ino *Goduino) DriverStatuses() []DriverStatus {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	statuses := make([]DriverStatus, len(ino.drivers))
+	for i, m := range ino.drivers {
+		statuses[i] = m.snapshot()
+	}
+	return statuses
+}
+
+// snapshot returns a copy of the status, callers hold mu.
+func (m *managedDriver) snapshot() DriverStatus {
+	status := m.status
+	status.Name = m.driver.Name()
+	status.Running = m.running
+	return status
+}
+
+// driverMetrics are the metrics written by WriteDriverMetrics.
+var driverMetrics = []struct {
+	name, help string
+	value      func(DriverStatus) float64
+}{
+	{"goduino_driver_running", "Whether the driver is running.", func(s DriverStatus) float64 {
+		if s.Running {
+			return 1
+		}
+		return 0
+	}},
+	{"goduino_driver_consecutive_errors", "Failed operations since the last success.", func(s DriverStatus) float64 {
+		return float64(s.ConsecutiveErrors)
+	}},
+	{"goduino_driver_last_success_timestamp_seconds", "Time of the last successful operation, 0 if none.", func(s DriverStatus) float64 {
+		return unixSeconds(s.LastSuccess)
+	}},
+	{"goduino_driver_last_error_timestamp_seconds", "Time of the last failed operation, 0 if none.", func(s DriverStatus) float64 {
+		return unixSeconds(s.LastErrorTime)
+	}},
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteDriverMetrics writes the DriverStatuses in the Prometheus text
+// format, one series per driver labelled with the board and driver names.
+func (ino *Goduino) WriteDriverMetrics(w io.Writer) error {
+	statuses := ino.DriverStatuses()
+	bw := bufio.NewWriter(w)
+	for _, m := range driverMetrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, s := range statuses {
+			fmt.Fprintf(bw, "%s{board=\"%s\",driver=\"%s\"} %g\n", m.name,
+				labelEscaper.Replace(ino.name), labelEscaper.Replace(s.Name), m.value(s))
+		}
+	}
+	return bw.Flush()
+}
+
+// DriverMetricsHandler serves WriteDriverMetrics, for a Prometheus scrape
+// endpoint such as /metrics.
+func (ino *Goduino) DriverMetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := ino.WriteDriverMetrics(w); err != nil {
+			ino.logger.Printf("driver metrics: %v\r\n", err)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package goduino
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDriverMetrics(t *testing.T) {
+	ino, _ := newTestGoduino(t)
+	d := &testDriver{name: `pump "north"`}
+	if err := ino.AddDriver(d); err != nil {
+		t.Fatal(err)
+	}
+	ino.ReportDriver(d, errors.New("dry"))
+	ino.ReportDriver(d, errors.New("dry"))
+
+	rec := httptest.NewRecorder()
+	ino.DriverMetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE goduino_driver_running gauge\n",
+		`goduino_driver_running{board="test",driver="pump \"north\""} 1` + "\n",
+		`goduino_driver_consecutive_errors{board="test",driver="pump \"north\""} 2` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics miss %q:\n%s", want, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
@@ -0,0 +1,140 @@
+package goduino
+
+import (
+	"fmt"
+	"github.com/argandas/goduino/firmata"
+	"time"
+)
+
+// i2cTimeout bounds how long an I2C read waits for its reply.
+const i2cTimeout = time.Second
+
+// i2cQueue is the number of I2C replies buffered, and so the most reads a
+// transaction can have in flight.
+const i2cQueue = 32
+
+// I2CDevice is a device on the I2C bus of the board, addressed by its
+// 7 bit address.
+type I2CDevice struct {
+	ino  *Goduino
+	addr int
+}
+
+// I2C returns the device at addr.
+func (ino *Goduino) I2C(addr int) *I2CDevice {
+	return &I2CDevice{ino: ino, addr: addr}
+}
+
+// Address returns the bus address of the device.
+func (d *I2CDevice) Address() int { return d.addr }
+
+// enableI2C sends I2C_CONFIG, which StandardFirmata requires before any
+// I2C request, and starts collecting replies. Callers hold i2cMu.
+func (ino *Goduino) enableI2C() error {
+	if ino.i2cEnabled {
+		return nil
+	}
+	if err := ino.board.I2cConfig(0); err != nil {
+		return err
+	}
+	ino.i2cReplies = make(chan firmata.I2cReply, i2cQueue)
+	ino.board.AddI2cListener(func(reply firmata.I2cReply) {
+		select {
+		case ino.i2cReplies <- reply:
+		default:
+			ino.logger.Printf("unexpected I2C reply from 0x%02X\r\n", reply.Address)
+		}
+	})
+	ino.i2cEnabled = true
+	return nil
+}
+
+// drainI2C discards replies left over from reads that timed out. Callers
+// hold i2cMu.
+func (ino *Goduino) drainI2C() {
+	for {
+		select {
+		case <-ino.i2cReplies:
+		default:
+			return
+		}
+	}
+}
+
+// ReadBytes reads n bytes starting at register reg and blocks until the
+// reply arrives or the read times out.
+func (d *I2CDevice) ReadBytes(reg, n int) ([]byte, error) {
+	ino := d.ino
+	ino.i2cMu.Lock()
+	defer ino.i2cMu.Unlock()
+	if err := ino.enableI2C(); err != nil {
+		return nil, err
+	}
+	ino.drainI2C()
+	if err := ino.board.I2cReadRegister(d.addr, reg, n); err != nil {
+		return nil, err
+	}
+	timeout := time.After(i2cTimeout)
+	for {
+		select {
+		case reply := <-ino.i2cReplies:
+			if reply.Address != d.addr || reply.Register != reg {
+				continue
+			}
+			if len(reply.Data) != n {
+				return nil, fmt.Errorf("I2C 0x%02X register 0x%02X returned %d bytes, want %d", d.addr, reg, len(reply.Data), n)
+			}
+			ino.logger.Printf("i2cRead(0x%02X, 0x%02X) -> % X\r\n", d.addr, reg, reply.Data)
+			return reply.Data, nil
+		case <-timeout:
+			return nil, ErrTimeout
+		}
+	}
+}
+
+// ReadRegister reads the register reg.
+func (d *I2CDevice) ReadRegister(reg int) (byte, error) {
+	data, err := d.ReadBytes(reg, 1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+// ReadWordLE reads a little endian 16 bit word starting at register reg.
+func (d *I2CDevice) ReadWordLE(reg int) (uint16, error) {
+	data, err := d.ReadBytes(reg, 2)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(data[0]) | uint16(data[1])<<8, nil
+}
+
+// ReadWordBE reads a big endian 16 bit word starting at register reg.
+func (d *I2CDevice) ReadWordBE(reg int) (uint16, error) {
+	data, err := d.ReadBytes(reg, 2)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(data[0])<<8 | uint16(data[1]), nil
+}
+
+// WriteBytes writes data starting at register reg.
+func (d *I2CDevice) WriteBytes(reg int, data []byte) error {
+	ino := d.ino
+	if err := ino.checkFence(); err != nil {
+		return err
+	}
+	ino.i2cMu.Lock()
+	defer ino.i2cMu.Unlock()
+	if err := ino.enableI2C(); err != nil {
+		return err
+	}
+	ino.logger.Printf("i2cWrite(0x%02X, 0x%02X, % X)\r\n", d.addr, reg, data)
+	return ino.board.I2cWrite(d.addr, append([]byte{byte(reg)}, data...))
+}
+
+// WriteRegister writes value to the register reg.
+func (d *I2CDevice) WriteRegister(reg int, value byte) error {
+	return d.WriteBytes(reg, []byte{value})
+}
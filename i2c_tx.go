@@ -0,0 +1,107 @@
+package goduino
+
+import (
+	"fmt"
+	"time"
+)
+
+type i2cOp struct {
+	reg  int
+	data []byte // written data, nil for reads
+	n    int    // bytes to read
+}
+
+// I2CTransaction queues register writes and reads for one device and sends
+// them back to back, without waiting for each read to be answered. The
+// firmware runs them in order, so reads see the preceding writes.
+//
+//	tx := mpu.Transaction()
+//	tx.WriteRegister(0x6B, 0x00) // wake up
+//	tx.WriteRegister(0x1B, 0x08) // gyro range
+//	accel := tx.Read(0x3B, 6)
+//	results, err := tx.Commit()
+//	// results[accel] holds the 6 bytes
+type I2CTransaction struct {
+	dev   *I2CDevice
+	ops   []i2cOp
+	reads int
+}
+
+// Transaction starts an empty transaction on the device.
+func (d *I2CDevice) Transaction() *I2CTransaction {
+	return &I2CTransaction{dev: d}
+}
+
+// Write queues writing data starting at register reg.
+func (tx *I2CTransaction) Write(reg int, data ...byte) *I2CTransaction {
+	tx.ops = append(tx.ops, i2cOp{reg: reg, data: append([]byte{}, data...)})
+	return tx
+}
+
+// WriteRegister queues writing value to the register reg.
+func (tx *I2CTransaction) WriteRegister(reg int, value byte) *I2CTransaction {
+	return tx.Write(reg, value)
+}
+
+// Read queues reading n bytes starting at register reg and returns the
+// index of its result in the slice returned by Commit.
+func (tx *I2CTransaction) Read(reg, n int) int {
+	tx.ops = append(tx.ops, i2cOp{reg: reg, n: n})
+	tx.reads++
+	return tx.reads - 1
+}
+
+// Commit sends the queued operations and waits for the replies of the
+// reads, returned in the order of the reads. The transaction can be
+// committed again.
+func (tx *I2CTransaction) Commit() ([][]byte, error) {
+	if tx.reads > i2cQueue {
+		return nil, fmt.Errorf("I2C transaction has %d reads, at most %d allowed", tx.reads, i2cQueue)
+	}
+	d := tx.dev
+	ino := d.ino
+	if err := ino.checkFence(); err != nil {
+		return nil, err
+	}
+	ino.i2cMu.Lock()
+	defer ino.i2cMu.Unlock()
+	if err := ino.enableI2C(); err != nil {
+		return nil, err
+	}
+	ino.drainI2C()
+	pending := make([]i2cOp, 0, tx.reads)
+	for _, op := range tx.ops {
+		var err error
+		if op.data != nil {
+			err = ino.board.I2cWrite(d.addr, append([]byte{byte(op.reg)}, op.data...))
+		} else {
+			err = ino.board.I2cReadRegister(d.addr, op.reg, op.n)
+			pending = append(pending, op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	ino.logger.Printf("i2cTransaction(0x%02X, %d ops)\r\n", d.addr, len(tx.ops))
+
+	// Replies arrive in request order; match them by register in case a
+	// stray reply is interleaved
+	results := make([][]byte, 0, len(pending))
+	timeout := time.After(i2cTimeout)
+	for len(results) < len(pending) {
+		op := pending[len(results)]
+		select {
+		case reply := <-ino.i2cReplies:
+			if reply.Address != d.addr || reply.Register != op.reg {
+				continue
+			}
+			if len(reply.Data) != op.n {
+				return nil, fmt.Errorf("I2C 0x%02X register 0x%02X returned %d bytes, want %d", d.addr, op.reg, len(reply.Data), op.n)
+			}
+			results = append(results, reply.Data)
+		case <-timeout:
+			return nil, ErrTimeout
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,138 @@
+package goduino
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The identity extension uses the user defined sysex command 0x0D. A
+// sketch implementing it keeps a 16 byte UUID and a name of up to 30
+// ASCII characters in EEPROM:
+//
+//	query: 0x0D 0x00
+//	store: 0x0D 0x01 <uuid as 32 nibbles, high first> <name>
+//	reply: 0x0D 0x02 <uuid as 32 nibbles, high first> <name>
+//
+// The sketch answers both query and store with a reply.
+const (
+	identitySysex byte = 0x0D
+	identityQuery byte = 0x00
+	identityStore byte = 0x01
+	identityReply byte = 0x02
+
+	// a store message with the longest name fills the 64 byte sysex
+	// buffer of StandardFirmata
+	maxIdentityName = 30
+	identityTimeout = time.Second
+)
+
+// BoardIdentity identifies a physical board independently of the port it
+// is attached to.
+type BoardIdentity struct {
+	Name string
+	UUID [16]byte
+}
+
+// NewBoardIdentity returns an identity named name with a random (version
+// 4) UUID.
+func NewBoardIdentity(name string) (BoardIdentity, error) {
+	id := BoardIdentity{Name: name}
+	if _, err := rand.Read(id.UUID[:]); err != nil {
+		return id, err
+	}
+	id.UUID[6] = id.UUID[6]&0x0F | 0x40
+	id.UUID[8] = id.UUID[8]&0x3F | 0x80
+	return id, nil
+}
+
+// UUIDString returns the UUID in its canonical text form.
+func (id BoardIdentity) UUIDString() string {
+	u := id.UUID
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+func (id BoardIdentity) String() string {
+	return fmt.Sprintf("%s (%s)", id.Name, id.UUIDString())
+}
+
+// ReadIdentity asks the board for the identity stored in its EEPROM. The
+// board must run a sketch with the identity extension.
+func (ino *Goduino) ReadIdentity() (BoardIdentity, error) {
+	return ino.identityRequest([]byte{identityQuery})
+}
+
+// WriteIdentity stores id in the EEPROM of the board and returns once the
+// board confirmed it.
+func (ino *Goduino) WriteIdentity(id BoardIdentity) error {
+	if len(id.Name) > maxIdentityName {
+		return fmt.Errorf("identity name longer than %d characters", maxIdentityName)
+	}
+	data := []byte{identityStore}
+	for _, b := range id.UUID {
+		data = append(data, b>>4, b&0x0F)
+	}
+	for _, c := range []byte(id.Name) {
+		if c > 0x7F {
+			return errors.New("identity name must be ASCII")
+		}
+		data = append(data, c)
+	}
+	stored, err := ino.identityRequest(data)
+	if err != nil {
+		return err
+	}
+	if stored != id {
+		return fmt.Errorf("board stored identity %v instead of %v", stored, id)
+	}
+	return nil
+}
+
+// identityRequest sends data and waits for the identity reply. Requests
+// are serialized so replies match their request.
+func (ino *Goduino) identityRequest(data []byte) (BoardIdentity, error) {
+	if err := ino.checkFence(); err != nil {
+		return BoardIdentity{}, err
+	}
+	ino.identityMu.Lock()
+	defer ino.identityMu.Unlock()
+	replies := make(chan BoardIdentity, 1)
+	ino.mu.Lock()
+	ino.routeSysex(identitySysex, true, func(data []byte) {
+		if id, ok := parseIdentity(data); ok {
+			select {
+			case replies <- id:
+			default:
+			}
+		}
+	})
+	ino.mu.Unlock()
+	defer func() {
+		ino.mu.Lock()
+		ino.routeSysex(identitySysex, true, nil)
+		ino.mu.Unlock()
+	}()
+	if err := ino.board.SendSysex(identitySysex, data); err != nil {
+		return BoardIdentity{}, err
+	}
+	select {
+	case id := <-replies:
+		ino.logger.Printf("identity: %v\r\n", id)
+		return id, nil
+	case <-time.After(identityTimeout):
+		return BoardIdentity{}, ErrTimeout
+	}
+}
+
+func parseIdentity(data []byte) (BoardIdentity, bool) {
+	var id BoardIdentity
+	if len(data) < 33 || data[0] != identityReply {
+		return id, false
+	}
+	for i := range id.UUID {
+		id.UUID[i] = data[1+2*i]<<4 | data[2+2*i]&0x0F
+	}
+	id.Name = string(data[33:])
+	return id, true
+}
@@ -0,0 +1,75 @@
+package goduino
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIdentity(t *testing.T) {
+	data := []byte{identityReply}
+	for i := 0; i < 16; i++ {
+		data = append(data, byte(i), byte(15-i))
+	}
+	data = append(data, "pump"...)
+	id, ok := parseIdentity(data)
+	if !ok {
+		t.Fatal("parseIdentity rejected a reply")
+	}
+	if got, want := id.UUIDString(), "0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0"; got != want {
+		t.Errorf("UUID = %s, want %s", got, want)
+	}
+	if id.Name != "pump" {
+		t.Errorf("Name = %q, want pump", id.Name)
+	}
+	for _, bad := range [][]byte{
+		data[:32],
+		append([]byte{identityStore}, data[1:]...),
+	} {
+		if _, ok := parseIdentity(bad); ok {
+			t.Errorf("parseIdentity(% X) accepted", bad)
+		}
+	}
+}
+
+func TestWriteIdentity(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	user := make(chan []byte, 4)
+	ino.OnSysex(identitySysex, func(data []byte) { user <- data })
+
+	id, err := NewBoardIdentity(strings.Repeat("n", maxIdentityName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sketch stores the message and echoes it as a reply
+	go answerSysex(board, identitySysex, func(data []byte) []byte {
+		if data[0] != identityStore {
+			return nil
+		}
+		// StandardFirmata buffers 64 bytes, the command included
+		if n := 1 + len(data); n > 64 {
+			t.Errorf("store message of %d bytes overflows the sysex buffer", n)
+		}
+		return append([]byte{identityReply}, data[1:]...)
+	})
+	if err := ino.WriteIdentity(id); err != nil {
+		t.Fatalf("WriteIdentity: %v", err)
+	}
+	// The handler registered with OnSysex sees the reply and stays
+	// registered afterwards
+	for i, inject := range []bool{true, false} {
+		select {
+		case <-user:
+		case <-time.After(time.Second):
+			t.Fatalf("OnSysex handler got %d messages, want 2", i)
+		}
+		if inject {
+			board.InjectSysex(identitySysex, []byte{0x7F})
+		}
+	}
+
+	id.Name += "n"
+	if err := ino.WriteIdentity(id); err == nil {
+		t.Errorf("WriteIdentity accepted a %d character name", len(id.Name))
+	}
+}
@@ -0,0 +1,64 @@
+package goduino
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventKind is the kind of a synthetic pin event.
+type EventKind int
+
+const (
+	DigitalEvent EventKind = iota
+	AnalogEvent
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case DigitalEvent:
+		return "DIGITAL"
+	case AnalogEvent:
+		return "ANALOG"
+	}
+	return "UNKNOWN"
+}
+
+// Event is a synthetic pin report. Pin is the digital pin for digital
+// events and the analog pin for analog events. After is the delay before
+// the event when replayed.
+type Event struct {
+	Kind  EventKind
+	Pin   int
+	Value int
+	After time.Duration
+}
+
+// Inject processes e exactly like a report received from the board, so
+// DigitalRead, AnalogRead, change channels, watchers and every other
+// consumer see it. It lets driver and application logic be exercised
+// without hardware.
+func (ino *Goduino) Inject(e Event) error {
+	ino.logger.Printf("inject(%s, %d, %d)\r\n", e.Kind, e.Pin, e.Value)
+	switch e.Kind {
+	case DigitalEvent:
+		return ino.board.InjectDigital(e.Pin, e.Value)
+	case AnalogEvent:
+		ino.board.InjectAnalog(e.Pin, e.Value)
+		return nil
+	}
+	return fmt.Errorf("unknown event kind %v", e.Kind)
+}
+
+// Replay injects events in order, waiting the After delay of each event
+// before injecting it, and returns when the last one is injected.
+func (ino *Goduino) Replay(events []Event) error {
+	for _, e := range events {
+		if e.After > 0 {
+			time.Sleep(e.After)
+		}
+		if err := ino.Inject(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package goduino
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// rainCheckInterval is how often a running program checks the rain sensor.
+const rainCheckInterval = time.Second
+
+// ErrRainLockout is returned when watering is refused because of rain.
+var ErrRainLockout = errors.New("irrigation locked out by rain sensor")
+
+// Zone is an irrigation zone valve and its default watering time.
+type Zone struct {
+	Name     string
+	Pin      int
+	Duration time.Duration
+}
+
+// IrrigationConfig configures an Irrigation controller. Pins set to -1 are
+// not used.
+type IrrigationConfig struct {
+	Zones     []Zone
+	MasterPin int // master valve or pump, on while any zone runs
+	RainPin   int // rain sensor input
+	RainLevel int // RainPin value that means it is raining
+}
+
+// Irrigation sequences zone valves one at a time, with an optional master
+// valve or pump and a rain sensor lockout. Valve and master pins are given
+// a safe state of off.
+type Irrigation struct {
+	ino    *Goduino
+	config IrrigationConfig
+
+	mu     sync.Mutex
+	active string
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewIrrigation creates a zone controller.
+func (ino *Goduino) NewIrrigation(config IrrigationConfig) *Irrigation {
+	for _, z := range config.Zones {
+		ino.SetSafeState(z.Pin, 0)
+	}
+	if config.MasterPin >= 0 {
+		ino.SetSafeState(config.MasterPin, 0)
+	}
+	return &Irrigation{ino: ino, config: config}
+}
+
+// Active returns the name of the zone being watered, or "".
+func (i *Irrigation) Active() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.active
+}
+
+// Raining reports whether the rain sensor locks out watering.
+func (i *Irrigation) Raining() (bool, error) {
+	if i.config.RainPin < 0 {
+		return false, nil
+	}
+	value, err := i.ino.DigitalRead(i.config.RainPin)
+	i.ino.ReportDriver(i, err)
+	if err != nil {
+		return false, err
+	}
+	return value == i.config.RainLevel, nil
+}
+
+// RunZone waters the named zone for d, or its default duration if d is
+// zero, stopping any running program first. It returns once watering has
+// started.
+func (i *Irrigation) RunZone(name string, d time.Duration) error {
+	for _, z := range i.config.Zones {
+		if z.Name == name {
+			if d > 0 {
+				z.Duration = d
+			}
+			return i.run([]Zone{z})
+		}
+	}
+	return fmt.Errorf("unknown zone %q", name)
+}
+
+// RunAll waters every zone in order for its default duration, stopping any
+// running program first.
+func (i *Irrigation) RunAll() error {
+	return i.run(i.config.Zones)
+}
+
+// Schedule runs every zone in order on the cron schedule spec of s.
+func (i *Irrigation) Schedule(s *Scheduler, spec string) (int, error) {
+	return s.Add(spec, i.RunAll)
+}
+
+// Name returns the name of the controller as a Driver.
+func (i *Irrigation) Name() string { return "irrigation" }
+
+// Init sets the valve and master pins to output mode.
+func (i *Irrigation) Init() error {
+	for _, z := range i.config.Zones {
+		if err := i.ino.PinMode(z.Pin, Output); err != nil {
+			return err
+		}
+	}
+	if i.config.MasterPin >= 0 {
+		return i.ino.PinMode(i.config.MasterPin, Output)
+	}
+	return nil
+}
+
+// Start does nothing, programs are started by RunZone, RunAll and
+// Schedule.
+func (i *Irrigation) Start() error { return nil }
+
+// Halt ends the running program and closes every valve.
+func (i *Irrigation) Halt() error {
+	i.Stop()
+	return nil
+}
+
+// Stop ends the running program and closes every valve.
+func (i *Irrigation) Stop() {
+	i.mu.Lock()
+	stop, done := i.stop, i.done
+	i.stop, i.done = nil, nil
+	i.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (i *Irrigation) run(zones []Zone) error {
+	if raining, err := i.Raining(); err != nil {
+		return err
+	} else if raining {
+		return ErrRainLockout
+	}
+	i.Stop()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stop = make(chan struct{})
+	i.done = make(chan struct{})
+	go i.program(zones, i.stop, i.done)
+	return nil
+}
+
+func (i *Irrigation) program(zones []Zone, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	defer i.closeAll()
+	if i.config.MasterPin >= 0 {
+		if err := i.write(i.config.MasterPin, 1); err != nil {
+			i.ino.logger.Printf("irrigation master on failed: %v\r\n", err)
+			return
+		}
+	}
+	for _, z := range zones {
+		if err := i.write(z.Pin, 1); err != nil {
+			i.ino.logger.Printf("irrigation zone %s failed: %v\r\n", z.Name, err)
+			return
+		}
+		i.mu.Lock()
+		i.active = z.Name
+		i.mu.Unlock()
+		i.ino.logger.Printf("irrigation zone %s on for %v\r\n", z.Name, z.Duration)
+
+		end := time.After(z.Duration)
+		check := time.NewTicker(rainCheckInterval)
+		watering := true
+		for watering {
+			select {
+			case <-stop:
+				check.Stop()
+				return
+			case <-end:
+				watering = false
+			case <-check.C:
+				if raining, _ := i.Raining(); raining {
+					i.ino.logger.Printf("irrigation stopped by rain\r\n")
+					check.Stop()
+					return
+				}
+			}
+		}
+		check.Stop()
+		if err := i.write(z.Pin, 0); err != nil {
+			i.ino.logger.Printf("irrigation zone %s off failed: %v\r\n", z.Name, err)
+			return
+		}
+	}
+}
+
+// closeAll switches off every valve and the master.
+func (i *Irrigation) closeAll() {
+	i.mu.Lock()
+	i.active = ""
+	i.mu.Unlock()
+	for _, z := range i.config.Zones {
+		if err := i.write(z.Pin, 0); err != nil {
+			i.ino.logger.Printf("irrigation zone %s off failed: %v\r\n", z.Name, err)
+		}
+	}
+	if i.config.MasterPin >= 0 {
+		if err := i.write(i.config.MasterPin, 0); err != nil {
+			i.ino.logger.Printf("irrigation master off failed: %v\r\n", err)
+		}
+	}
+}
+
+// write drives pin, reporting the outcome for the driver status.
+func (i *Irrigation) write(pin, value int) error {
+	err := i.ino.DigitalWrite(pin, value)
+	i.ino.ReportDriver(i, err)
+	return err
+}
@@ -0,0 +1,429 @@
+package goduino
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Journal defaults
+const (
+	journalQueue       = 1024
+	journalFlush       = time.Second
+	defaultSegmentSize = 4 << 20
+	segmentExt         = ".jsonl"
+)
+
+// JournalRecord is a pin report stored in a Journal.
+type JournalRecord struct {
+	Time  time.Time `json:"time"`
+	Kind  EventKind `json:"kind"`
+	Pin   int       `json:"pin"` // digital pin or analog pin
+	Value int       `json:"value"`
+}
+
+// JournalOptions configure the size and retention of a Journal.
+type JournalOptions struct {
+	// SegmentSize is the size in bytes at which the journal starts a new
+	// segment file, 4 MiB by default.
+	SegmentSize int64
+	// Retention is how long records are kept; segments whose newest
+	// record is older are deleted. Zero keeps everything.
+	Retention time.Duration
+	// MaxSize bounds the total size of the segments in bytes by deleting
+	// the oldest ones. Zero means no bound.
+	MaxSize int64
+}
+
+// Journal is an on-disk log of pin reports, stored as JSON lines in
+// segment files named after the time of their first record. Attach it
+// with SetJournal; query it with Query and ValueAt, also after a restart.
+type Journal struct {
+	dir  string
+	opts JournalOptions
+
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	size    int64
+	err     error // first error writing queued records
+	records chan JournalRecord
+	stop    chan struct{}
+	done    chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// OpenJournal opens or creates the journal in dir. New records go to a
+// new segment.
+func OpenJournal(dir string, opts JournalOptions) (*Journal, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = defaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		dir:     dir,
+		opts:    opts,
+		records: make(chan JournalRecord, journalQueue),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go j.run()
+	return j, nil
+}
+
+// SetJournal records every digital change and analog value change the
+// board reports in j. A nil journal stops recording.
+func (ino *Goduino) SetJournal(j *Journal) {
+	ino.mu.Lock()
+	old := ino.journal
+	ino.journal = nil
+	ino.mu.Unlock()
+	if old != nil {
+		ino.board.RemoveDigitalListener(old.digital)
+		ino.board.RemoveAnalogListener(old.analog)
+	}
+	if j == nil {
+		return
+	}
+	a := &journalAttachment{}
+	a.digital = ino.board.AddDigitalListener(func(pin, value int) {
+		ino.journalRecord(j, DigitalEvent, pin, value)
+	})
+	last := map[int]int{}
+	a.analog = ino.board.AddAnalogListener(func(channel, value int) {
+		if v, ok := last[channel]; ok && v == value {
+			return
+		}
+		last[channel] = value
+		ino.journalRecord(j, AnalogEvent, channel, value)
+	})
+	ino.mu.Lock()
+	ino.journal = a
+	ino.mu.Unlock()
+}
+
+// journalAttachment holds the listeners feeding the journal.
+type journalAttachment struct {
+	digital int
+	analog  int
+}
+
+// journalRecord queues a record without blocking the read loop.
+func (ino *Goduino) journalRecord(j *Journal, kind EventKind, pin, value int) {
+	select {
+	case j.records <- JournalRecord{Time: time.Now(), Kind: kind, Pin: pin, Value: value}:
+	default:
+		ino.logger.Printf("journal queue full, dropping %s %d = %d\r\n", kind, pin, value)
+	}
+}
+
+// Record appends r to the journal directly.
+func (j *Journal) Record(r JournalRecord) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.write(r)
+}
+
+func (j *Journal) run() {
+	defer close(j.done)
+	flush := time.NewTicker(journalFlush)
+	defer flush.Stop()
+	for {
+		select {
+		case r := <-j.records:
+			j.mu.Lock()
+			if err := j.write(r); err != nil && j.err == nil {
+				j.err = err
+			}
+			j.mu.Unlock()
+		case now := <-flush.C:
+			j.mu.Lock()
+			if j.w != nil {
+				j.w.Flush()
+			}
+			// segments also expire while no new one is started
+			if j.opts.Retention > 0 || j.opts.MaxSize > 0 {
+				if err := j.retain(now); err != nil && j.err == nil {
+					j.err = err
+				}
+			}
+			j.mu.Unlock()
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// write appends r, starting a new segment when needed. Callers hold mu.
+func (j *Journal) write(r JournalRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if j.file == nil || j.size+int64(len(line)) > j.opts.SegmentSize {
+		if err := j.rotate(r.Time); err != nil {
+			return err
+		}
+	}
+	n, err := j.w.Write(line)
+	j.size += int64(n)
+	return err
+}
+
+// rotate closes the current segment, starts one at t and applies the
+// retention settings. Callers hold mu.
+func (j *Journal) rotate(t time.Time) error {
+	if err := j.closeSegment(); err != nil {
+		return err
+	}
+	path := filepath.Join(j.dir, fmt.Sprintf("%020d%s", t.UnixNano(), segmentExt))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	j.file, j.w, j.size = f, bufio.NewWriter(f), info.Size()
+	return j.retain(t)
+}
+
+func (j *Journal) closeSegment() error {
+	if j.file == nil {
+		return nil
+	}
+	err := j.w.Flush()
+	if e := j.file.Close(); err == nil {
+		err = e
+	}
+	j.file, j.w = nil, nil
+	return err
+}
+
+// segment is a journal file and the time of its first record.
+type segment struct {
+	path  string
+	start time.Time
+	size  int64
+}
+
+// segments returns the segment files oldest first.
+func (j *Journal) segments() ([]segment, error) {
+	matches, err := filepath.Glob(filepath.Join(j.dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	var segs []segment
+	for _, path := range matches {
+		ns, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, segment{path: path, start: time.Unix(0, ns), size: info.Size()})
+	}
+	sort.Slice(segs, func(a, b int) bool { return segs[a].start.Before(segs[b].start) })
+	return segs, nil
+}
+
+// retain deletes the closed segments beyond the retention settings. A
+// segment ends where the next one starts. Callers hold mu.
+func (j *Journal) retain(now time.Time) error {
+	segs, err := j.segments()
+	if err != nil || len(segs) < 2 {
+		return err
+	}
+	var total int64
+	for _, s := range segs {
+		total += s.size
+	}
+	// The last segment is the current one
+	for i := 0; i < len(segs)-1; i++ {
+		expired := j.opts.Retention > 0 && now.Sub(segs[i+1].start) > j.opts.Retention
+		oversize := j.opts.MaxSize > 0 && total > j.opts.MaxSize
+		if !expired && !oversize {
+			break
+		}
+		if err := os.Remove(segs[i].path); err != nil {
+			return err
+		}
+		total -= segs[i].size
+	}
+	return nil
+}
+
+// Query returns the records of pin of kind received between from and to,
+// inclusive, in time order.
+func (j *Journal) Query(kind EventKind, pin int, from, to time.Time) ([]JournalRecord, error) {
+	var records []JournalRecord
+	err := j.scan(to, func(r JournalRecord) {
+		if r.Kind == kind && r.Pin == pin && !r.Time.Before(from) && !r.Time.After(to) {
+			records = append(records, r)
+		}
+	})
+	return records, err
+}
+
+// ValueAt returns the value pin of kind had at t, that is the value of
+// its last record at or before t. ok is false if there is none.
+func (j *Journal) ValueAt(kind EventKind, pin int, t time.Time) (value int, ok bool, err error) {
+	err = j.scan(t, func(r JournalRecord) {
+		if r.Kind == kind && r.Pin == pin && !r.Time.After(t) {
+			value, ok = r.Value, true
+		}
+	})
+	return
+}
+
+// scan calls fn for every record of the segments starting at or before
+// to, oldest first.
+func (j *Journal) scan(to time.Time, fn func(JournalRecord)) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.w != nil {
+		if err := j.w.Flush(); err != nil {
+			return err
+		}
+	}
+	segs, err := j.segments()
+	if err != nil {
+		return err
+	}
+	for _, s := range segs {
+		if s.start.After(to) {
+			break
+		}
+		if err := readSegment(s.path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSegment(path string, fn func(JournalRecord)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r JournalRecord
+		// A torn last line after a crash is skipped
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			fn(r)
+		}
+	}
+	return scanner.Err()
+}
+
+// Compact thins out the closed segments that end before cutoff, keeping
+// per pin only the last record of each resolution window, so old history
+// stays queryable at a coarser time resolution.
+func (j *Journal) Compact(cutoff time.Time, resolution time.Duration) error {
+	if resolution <= 0 {
+		return fmt.Errorf("compaction resolution must be positive, got %v", resolution)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	segs, err := j.segments()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(segs)-1 && !segs[i+1].start.After(cutoff); i++ {
+		if err := compactSegment(segs[i].path, resolution); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compactSegment(path string, resolution time.Duration) error {
+	type window struct {
+		key   reportKey
+		start int64
+	}
+	var kept []JournalRecord
+	index := map[window]int{}
+	err := readSegment(path, func(r JournalRecord) {
+		w := window{reportKey{r.Kind, r.Pin}, r.Time.UnixNano() / int64(resolution)}
+		if i, ok := index[w]; ok {
+			kept[i] = r
+			return
+		}
+		index[w] = len(kept)
+		kept = append(kept, r)
+	})
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range kept {
+		if err = enc.Encode(r); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Close flushes pending records and closes the journal. It returns the
+// first error writing records received from the board, if any. Detach the
+// journal with SetJournal(nil) first. Closing it again returns the same
+// result.
+func (j *Journal) Close() error {
+	j.closeOnce.Do(func() { j.closeErr = j.close() })
+	return j.closeErr
+}
+
+func (j *Journal) close() error {
+	close(j.stop)
+	<-j.done
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for {
+		select {
+		case r := <-j.records:
+			if err := j.write(r); err != nil {
+				return err
+			}
+		default:
+			if err := j.closeSegment(); err != nil {
+				return err
+			}
+			return j.err
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package goduino
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalQuery(t *testing.T) {
+	dir := t.TempDir()
+	j, err := OpenJournal(dir, JournalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, v := range []int{0, 1, 0, 1} {
+		if err := j.Record(JournalRecord{Time: base.Add(time.Duration(i) * time.Minute), Kind: DigitalEvent, Pin: 2, Value: v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Record(JournalRecord{Time: base, Kind: AnalogEvent, Pin: 2, Value: 512}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The records survive reopening the journal
+	j, err = OpenJournal(dir, JournalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	records, err := j.Query(DigitalEvent, 2, base.Add(time.Minute), base.Add(2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Value != 1 || records[1].Value != 0 {
+		t.Errorf("Query = %+v, want values 1 and 0", records)
+	}
+	if v, ok, err := j.ValueAt(DigitalEvent, 2, base.Add(90*time.Second)); err != nil || !ok || v != 1 {
+		t.Errorf("ValueAt = %d, %v, %v, want 1", v, ok, err)
+	}
+	if _, ok, _ := j.ValueAt(DigitalEvent, 2, base.Add(-time.Second)); ok {
+		t.Errorf("ValueAt before the first record found a value")
+	}
+}
+
+func TestJournalBoard(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	j, err := OpenJournal(t.TempDir(), JournalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if err := ino.PinMode(0, Analog); err != nil {
+		t.Fatal(err)
+	}
+	ino.SetJournal(j)
+	start := time.Now()
+	for _, v := range []int{100, 100, 300} {
+		board.InjectAnalog(0, v)
+	}
+	ino.SetJournal(nil)
+	board.InjectAnalog(0, 400)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		records, err := j.Query(AnalogEvent, 0, start, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) == 2 {
+			if records[0].Value != 100 || records[1].Value != 300 {
+				t.Errorf("journaled %+v, want 100 and 300", records)
+			}
+			break
+		}
+		if len(records) > 2 || time.Now().After(deadline) {
+			t.Fatalf("journaled %+v, want 100 and 300", records)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJournalRetention(t *testing.T) {
+	dir := t.TempDir()
+	// Every record starts a new segment
+	j, err := OpenJournal(dir, JournalOptions{SegmentSize: 1, Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, d := range []time.Duration{0, time.Second, 2 * time.Hour} {
+		if err := j.Record(JournalRecord{Time: base.Add(d), Kind: DigitalEvent, Pin: 2, Value: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first segment ended two hours ago, the second one just now
+	segs, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(segs) != 2 {
+		t.Errorf("%d segments, want 2 after the first expired", len(segs))
+	}
+	records, err := j.Query(DigitalEvent, 2, base, base.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || !records[0].Time.Equal(base.Add(time.Second)) {
+		t.Errorf("Query after retention = %+v", records)
+	}
+}
+
+func TestJournalRetentionIdle(t *testing.T) {
+	dir := t.TempDir()
+	j, err := OpenJournal(dir, JournalOptions{SegmentSize: 1, Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, d := range []time.Duration{2 * time.Hour, 90 * time.Minute} {
+		if err := j.Record(JournalRecord{Time: now.Add(-d), Kind: DigitalEvent, Pin: 2, Value: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// No record starts a new segment, the flush ticker expires the first
+	time.Sleep(journalFlush + 200*time.Millisecond)
+	segs, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(segs) != 1 {
+		t.Errorf("%d segments, want 1 after the first expired", len(segs))
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestJournalCompact(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	record := func(j *Journal, d time.Duration, v int) {
+		t.Helper()
+		if err := j.Record(JournalRecord{Time: base.Add(d), Kind: DigitalEvent, Pin: 2, Value: v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j, err := OpenJournal(dir, JournalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(j, 0, 1)
+	record(j, 10*time.Second, 0)
+	record(j, 20*time.Second, 1)
+	record(j, 70*time.Second, 0)
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Reopening starts a new segment, closing the first one
+	j, err = OpenJournal(dir, JournalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	record(j, 2*time.Hour, 1)
+
+	for _, resolution := range []time.Duration{0, -time.Minute} {
+		if err := j.Compact(base.Add(3*time.Hour), resolution); err == nil {
+			t.Errorf("Compact accepted resolution %v", resolution)
+		}
+	}
+	if err := j.Compact(base.Add(3*time.Hour), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	records, err := j.Query(DigitalEvent, 2, base, base.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{20 * time.Second, 70 * time.Second, 2 * time.Hour}
+	if len(records) != len(want) {
+		t.Fatalf("Query after Compact = %+v", records)
+	}
+	for i, r := range records {
+		if !r.Time.Equal(base.Add(want[i])) {
+			t.Errorf("record %d at %v, want %v", i, r.Time, base.Add(want[i]))
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package goduino
+
+import (
+	"fmt"
+	"github.com/argandas/goduino/firmata"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBounds are the upper bounds of the LatencyHistogram buckets. A
+// final bucket collects everything slower.
+var latencyBounds = []time.Duration{
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second,
+}
+
+// LatencyHistogram is a distribution of latencies.
+type LatencyHistogram struct {
+	Count   int
+	Sum     time.Duration
+	Min     time.Duration
+	Max     time.Duration
+	Buckets []int // Buckets[i] counts latencies up to LatencyBounds()[i], the last bucket the rest
+}
+
+// LatencyBounds returns the upper bounds of the histogram buckets.
+func LatencyBounds() []time.Duration {
+	return append([]time.Duration(nil), latencyBounds...)
+}
+
+func (h *LatencyHistogram) add(d time.Duration) {
+	if h.Buckets == nil {
+		h.Buckets = make([]int, len(latencyBounds)+1)
+	}
+	if h.Count == 0 || d < h.Min {
+		h.Min = d
+	}
+	if d > h.Max {
+		h.Max = d
+	}
+	h.Count++
+	h.Sum += d
+	h.Buckets[sort.Search(len(latencyBounds), func(i int) bool { return latencyBounds[i] >= d })]++
+}
+
+// Mean returns the average latency.
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Percentile returns an upper bound of the latency under which fraction q
+// (0-1) of the samples fall, at the resolution of the buckets.
+func (h LatencyHistogram) Percentile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q * float64(h.Count)))
+	seen := 0
+	for i, n := range h.Buckets {
+		seen += n
+		if seen >= rank {
+			if i < len(latencyBounds) && latencyBounds[i] < h.Max {
+				return latencyBounds[i]
+			}
+			break
+		}
+	}
+	return h.Max
+}
+
+func (h LatencyHistogram) String() string {
+	return fmt.Sprintf("n=%d min=%v mean=%v p99=%v max=%v", h.Count, h.Min, h.Mean(), h.Percentile(0.99), h.Max)
+}
+
+// LatencyStats are the latencies of the traced iterations of a control
+// loop, split in stages.
+type LatencyStats struct {
+	Dispatch LatencyHistogram // board report received to handler start
+	Handler  LatencyHistogram // handler start to actuator write returned
+	Total    LatencyHistogram // board report received to actuator write returned
+}
+
+func (s LatencyStats) String() string {
+	lines := []string{
+		"dispatch: " + s.Dispatch.String(),
+		"handler:  " + s.Handler.String(),
+		"total:    " + s.Total.String(),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reportKey identifies a reported digital pin or analog channel.
+type reportKey struct {
+	kind EventKind
+	pin  int
+}
+
+// LatencyProfile measures the latency of a control loop reacting to board
+// reports. Each iteration is traced from Begin, at the start of the
+// handler, to End, after the actuator write returned:
+//
+//	prof := ino.NewLatencyProfile()
+//	defer prof.Close()
+//	for v := range changes {
+//		t := prof.Begin(goduino.DigitalEvent, 2)
+//		ino.DigitalWrite(13, v)
+//		t.End()
+//	}
+//	fmt.Println(prof.Stats())
+type LatencyProfile struct {
+	ino      *Goduino
+	digital  int
+	analog   int
+	mu       sync.Mutex
+	received map[reportKey]time.Time
+	stats    LatencyStats
+}
+
+// NewLatencyProfile starts timestamping board reports for Begin.
+func (ino *Goduino) NewLatencyProfile() *LatencyProfile {
+	p := &LatencyProfile{ino: ino, received: map[reportKey]time.Time{}}
+	stamp := func(kind EventKind) firmata.PinListener {
+		return func(pin, value int) {
+			now := time.Now()
+			p.mu.Lock()
+			p.received[reportKey{kind, pin}] = now
+			p.mu.Unlock()
+		}
+	}
+	p.digital = ino.board.AddDigitalListener(stamp(DigitalEvent))
+	p.analog = ino.board.AddAnalogListener(stamp(AnalogEvent))
+	return p
+}
+
+// Close stops timestamping reports. Stats stay available.
+func (p *LatencyProfile) Close() {
+	p.ino.board.RemoveDigitalListener(p.digital)
+	p.ino.board.RemoveAnalogListener(p.analog)
+}
+
+// LoopTrace is one traced iteration of a control loop.
+type LoopTrace struct {
+	p        *LatencyProfile
+	received time.Time // zero if the pin has not been reported
+	begun    time.Time
+}
+
+// Begin starts tracing an iteration handling the last report of pin, a
+// digital pin or an analog channel depending on kind.
+func (p *LatencyProfile) Begin(kind EventKind, pin int) *LoopTrace {
+	now := time.Now()
+	p.mu.Lock()
+	received := p.received[reportKey{kind, pin}]
+	p.mu.Unlock()
+	return &LoopTrace{p: p, received: received, begun: now}
+}
+
+// End completes the iteration. Call it once the actuator write returned,
+// i.e. was handed to the link.
+func (t *LoopTrace) End() {
+	now := time.Now()
+	p := t.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Handler.add(now.Sub(t.begun))
+	if !t.received.IsZero() {
+		p.stats.Dispatch.add(t.begun.Sub(t.received))
+		p.stats.Total.add(now.Sub(t.received))
+	}
+}
+
+// Stats returns the latencies traced so far.
+func (p *LatencyProfile) Stats() LatencyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	for _, h := range []*LatencyHistogram{&s.Dispatch, &s.Handler, &s.Total} {
+		h.Buckets = append([]int(nil), h.Buckets...)
+	}
+	return s
+}
+
+// Reset discards the latencies traced so far.
+func (p *LatencyProfile) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats = LatencyStats{}
+}
@@ -0,0 +1,253 @@
+package goduino
+
+import (
+	"fmt"
+	"time"
+)
+
+// TextDisplay is a character display. Every LCD driver implements it, so
+// code printing text does not depend on how the display is wired.
+type TextDisplay interface {
+	// Clear blanks the display and moves the cursor home.
+	Clear() error
+	// SetCursor moves the cursor to col and row, counted from 0.
+	SetCursor(col, row int) error
+	// Print writes s at the cursor.
+	Print(s string) error
+}
+
+// HD44780 instructions
+const (
+	lcdClear       = 0x01
+	lcdHome        = 0x02
+	lcdEntryMode   = 0x04
+	lcdDisplayCtrl = 0x08
+	lcdFunctionSet = 0x20
+	lcdSetDDRAM    = 0x80
+
+	lcdEntryLeft = 0x02
+	lcdDisplayOn = 0x04
+	lcdCursorOn  = 0x02
+	lcdBlinkOn   = 0x01
+	lcdTwoLines  = 0x08
+
+	// Clear and home take 1.52ms, the other instructions under 40us
+	lcdClearDelay = 2 * time.Millisecond
+)
+
+// LCDBus sends bytes to an HD44780 compatible controller, as an
+// instruction or as data depending on rs. Implement it to drive
+// controllers wired in other ways with the LCD text API.
+type LCDBus interface {
+	WriteLCD(b byte, rs bool) error
+}
+
+// LCD is an HD44780 compatible character display.
+type LCD struct {
+	bus  LCDBus
+	cols int
+	rows int
+}
+
+// NewLCD initializes the cols by rows display behind bus, which must
+// already be in a mode that takes whole bytes.
+func NewLCD(bus LCDBus, cols, rows int) (*LCD, error) {
+	l := &LCD{bus: bus, cols: cols, rows: rows}
+	function := byte(lcdFunctionSet)
+	if rows > 1 {
+		function |= lcdTwoLines
+	}
+	if err := l.command(function); err != nil {
+		return nil, err
+	}
+	if err := l.SetDisplay(true, false, false); err != nil {
+		return nil, err
+	}
+	if err := l.Clear(); err != nil {
+		return nil, err
+	}
+	return l, l.command(lcdEntryMode | lcdEntryLeft)
+}
+
+func (l *LCD) command(cmd byte) error {
+	return l.bus.WriteLCD(cmd, false)
+}
+
+// Clear blanks the display and moves the cursor home.
+func (l *LCD) Clear() error {
+	if err := l.command(lcdClear); err != nil {
+		return err
+	}
+	time.Sleep(lcdClearDelay)
+	return nil
+}
+
+// Home moves the cursor to the top left corner.
+func (l *LCD) Home() error {
+	if err := l.command(lcdHome); err != nil {
+		return err
+	}
+	time.Sleep(lcdClearDelay)
+	return nil
+}
+
+// SetDisplay turns the display, the underline cursor and the blinking
+// block cursor on or off.
+func (l *LCD) SetDisplay(display, cursor, blink bool) error {
+	cmd := byte(lcdDisplayCtrl)
+	if display {
+		cmd |= lcdDisplayOn
+	}
+	if cursor {
+		cmd |= lcdCursorOn
+	}
+	if blink {
+		cmd |= lcdBlinkOn
+	}
+	return l.command(cmd)
+}
+
+// SetCursor moves the cursor to col and row, counted from 0.
+func (l *LCD) SetCursor(col, row int) error {
+	if col < 0 || col >= l.cols || row < 0 || row >= l.rows {
+		return fmt.Errorf("Invalid LCD position %v,%v\n", col, row)
+	}
+	// Rows 2 and 3 of four line displays continue rows 0 and 1 in memory
+	offsets := []int{0x00, 0x40, l.cols, 0x40 + l.cols}
+	return l.command(lcdSetDDRAM | byte(offsets[row]+col))
+}
+
+// Print writes s at the cursor. Characters outside ASCII are shown as '?'.
+func (l *LCD) Print(s string) error {
+	for _, r := range s {
+		if r > 0x7F {
+			r = '?'
+		}
+		if err := l.bus.WriteLCD(byte(r), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ TextDisplay = (*LCD)(nil)
+
+// lcdParallel drives the controller in 4-bit mode through six outputs.
+type lcdParallel struct {
+	rs, en DigitalWriter
+	data   [4]DigitalWriter // D4 to D7
+}
+
+// NewParallelLCD creates a cols by rows HD44780 display wired in 4-bit
+// mode to rs, en and d4 to d7, which may be native or expander pins. RW
+// must be tied to ground.
+func NewParallelLCD(rs, en, d4, d5, d6, d7 DigitalWriter, cols, rows int) (*LCD, error) {
+	p := &lcdParallel{rs: rs, en: en, data: [4]DigitalWriter{d4, d5, d6, d7}}
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	return NewLCD(p, cols, rows)
+}
+
+// init switches the controller to 4-bit mode from any state, following
+// the initialization by instruction of the datasheet.
+func (p *lcdParallel) init() error {
+	time.Sleep(50 * time.Millisecond)
+	if err := p.rs.WriteDigital(0); err != nil {
+		return err
+	}
+	if err := p.en.WriteDigital(0); err != nil {
+		return err
+	}
+	for _, step := range []struct {
+		nibble byte
+		wait   time.Duration
+	}{{0x3, 5 * time.Millisecond}, {0x3, time.Millisecond}, {0x3, time.Millisecond}, {0x2, 0}} {
+		if err := p.nibble(step.nibble); err != nil {
+			return err
+		}
+		time.Sleep(step.wait)
+	}
+	return nil
+}
+
+// nibble latches the low four bits of n on the falling edge of EN.
+func (p *lcdParallel) nibble(n byte) error {
+	for i, d := range p.data {
+		if err := d.WriteDigital(int(n>>uint(i)) & 1); err != nil {
+			return err
+		}
+	}
+	if err := p.en.WriteDigital(1); err != nil {
+		return err
+	}
+	return p.en.WriteDigital(0)
+}
+
+// WriteLCD sends b as two nibbles, high nibble first.
+func (p *lcdParallel) WriteLCD(b byte, rs bool) error {
+	value := 0
+	if rs {
+		value = 1
+	}
+	if err := p.rs.WriteDigital(value); err != nil {
+		return err
+	}
+	if err := p.nibble(b >> 4); err != nil {
+		return err
+	}
+	return p.nibble(b)
+}
+
+// PCF8574 backpack wiring: P0 RS, P1 RW, P2 EN, P3 backlight, P4-P7 D4-D7
+const (
+	lcdBackpackRS        = 0x01
+	lcdBackpackEN        = 0x04
+	lcdBackpackBacklight = 0x08
+)
+
+// lcdBackpack drives the controller in 4-bit mode through a PCF8574 I2C
+// backpack.
+type lcdBackpack struct {
+	dev       *I2CDevice
+	backlight byte
+}
+
+// NewI2CLCD creates a cols by rows HD44780 display behind a PCF8574 I2C
+// backpack at addr, usually 0x27 or 0x3F, with the backlight on.
+func (ino *Goduino) NewI2CLCD(addr, cols, rows int) (*LCD, error) {
+	b := &lcdBackpack{dev: ino.I2C(addr), backlight: lcdBackpackBacklight}
+	time.Sleep(50 * time.Millisecond)
+	for _, wait := range []time.Duration{5 * time.Millisecond, time.Millisecond, time.Millisecond} {
+		if err := b.nibble(0x3, 0); err != nil {
+			return nil, err
+		}
+		time.Sleep(wait)
+	}
+	if err := b.nibble(0x2, 0); err != nil {
+		return nil, err
+	}
+	return NewLCD(b, cols, rows)
+}
+
+// nibble latches the low four bits of n on the falling edge of EN.
+func (b *lcdBackpack) nibble(n byte, rs byte) error {
+	out := n<<4 | rs | b.backlight
+	// The PCF8574 has no registers, the "register" byte is the output
+	if err := b.dev.WriteBytes(int(out|lcdBackpackEN), nil); err != nil {
+		return err
+	}
+	return b.dev.WriteBytes(int(out), nil)
+}
+
+// WriteLCD sends v as two nibbles, high nibble first.
+func (b *lcdBackpack) WriteLCD(v byte, rs bool) error {
+	var bit byte
+	if rs {
+		bit = lcdBackpackRS
+	}
+	if err := b.nibble(v>>4, bit); err != nil {
+		return err
+	}
+	return b.nibble(v&0x0F, bit)
+}
@@ -0,0 +1,130 @@
+package goduino
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Step is one command of a macro, followed by an optional delay.
+type Step struct {
+	Action func() error
+	Delay  time.Duration
+}
+
+// WriteStep returns a step writing value to a digital pin.
+func (ino *Goduino) WriteStep(pin, value int) Step {
+	return Step{Action: func() error { return ino.DigitalWrite(pin, value) }}
+}
+
+// PwmStep returns a step writing level to a PWM pin.
+func (ino *Goduino) PwmStep(pin int, level byte) Step {
+	return Step{Action: func() error { return ino.PwmWrite(pin, level) }}
+}
+
+// ServoStep returns a step moving a servo to angle.
+func (ino *Goduino) ServoStep(pin int, angle byte) Step {
+	return Step{Action: func() error { return ino.ServoWrite(pin, angle) }}
+}
+
+// DelayStep returns a step doing nothing for d.
+func DelayStep(d time.Duration) Step {
+	return Step{Delay: d}
+}
+
+// DefineMacro stores steps under name, replacing any macro with that name.
+func (ino *Goduino) DefineMacro(name string, steps ...Step) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.macros == nil {
+		ino.macros = make(map[string][]Step)
+	}
+	// copied, so a recorder going on with the same steps leaves it alone
+	ino.macros[name] = append([]Step(nil), steps...)
+}
+
+// Macros returns the names of the defined macros, sorted.
+func (ino *Goduino) Macros() []string {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	names := make([]string, 0, len(ino.macros))
+	for name := range ino.macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunMacro runs the steps of the named macro in order and returns when the
+// last one is done. It stops at the first failing step.
+func (ino *Goduino) RunMacro(name string) error {
+	ino.mu.Lock()
+	steps, ok := ino.macros[name]
+	ino.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown macro %q", name)
+	}
+	ino.logger.Printf("runMacro(%s)\r\n", name)
+	for i, step := range steps {
+		if step.Action != nil {
+			if err := step.Action(); err != nil {
+				return fmt.Errorf("macro %q step %d: %v", name, i, err)
+			}
+		}
+		if step.Delay > 0 {
+			time.Sleep(step.Delay)
+		}
+	}
+	return nil
+}
+
+// MacroRecorder executes commands on the board while recording them as the
+// steps of a macro.
+type MacroRecorder struct {
+	ino   *Goduino
+	name  string
+	steps []Step
+}
+
+// RecordMacro starts recording a macro to be stored under name by Save.
+func (ino *Goduino) RecordMacro(name string) *MacroRecorder {
+	return &MacroRecorder{ino: ino, name: name}
+}
+
+func (r *MacroRecorder) record(step Step) error {
+	if err := step.Action(); err != nil {
+		return err
+	}
+	r.steps = append(r.steps, step)
+	return nil
+}
+
+// DigitalWrite writes value to a digital pin and records it.
+func (r *MacroRecorder) DigitalWrite(pin, value int) error {
+	return r.record(r.ino.WriteStep(pin, value))
+}
+
+// PwmWrite writes level to a PWM pin and records it.
+func (r *MacroRecorder) PwmWrite(pin int, level byte) error {
+	return r.record(r.ino.PwmStep(pin, level))
+}
+
+// ServoWrite moves a servo to angle and records it.
+func (r *MacroRecorder) ServoWrite(pin int, angle byte) error {
+	return r.record(r.ino.ServoStep(pin, angle))
+}
+
+// Delay waits for d and records the delay after the last command.
+func (r *MacroRecorder) Delay(d time.Duration) {
+	time.Sleep(d)
+	if len(r.steps) == 0 {
+		r.steps = append(r.steps, DelayStep(d))
+		return
+	}
+	r.steps[len(r.steps)-1].Delay += d
+}
+
+// Save defines the recorded macro.
+func (r *MacroRecorder) Save() {
+	r.ino.DefineMacro(r.name, r.steps...)
+}
@@ -0,0 +1,32 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMacroRecorder(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	r := ino.RecordMacro("blink")
+	if err := r.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	r.Save()
+	// Going on recording does not change the saved macro
+	r.Delay(50 * time.Millisecond)
+
+	board.ClearCalls()
+	start := time.Now()
+	if err := ino.RunMacro("blink"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= 50*time.Millisecond {
+		t.Errorf("RunMacro took %v, the delay recorded after Save leaked into the macro", d)
+	}
+	if _, ok := hasCall(board, "DigitalWrite", 13); !ok {
+		t.Errorf("RunMacro did not write pin 13")
+	}
+	if err := ino.RunMacro("missing"); err == nil {
+		t.Errorf("RunMacro of an unknown macro succeeded")
+	}
+}
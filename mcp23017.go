@@ -0,0 +1,168 @@
+package goduino
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MCP23017 registers with IOCON.BANK = 0, port B follows port A
+const (
+	mcp23017IODIR = 0x00
+	mcp23017GPPU  = 0x0C
+	mcp23017GPIO  = 0x12
+	mcp23017OLAT  = 0x14
+
+	mcp23017Pins = 16
+)
+
+// MCP23017 is a 16 pin I/O expander on the I2C bus. Pins 0-7 are port A,
+// 8-15 port B.
+type MCP23017 struct {
+	dev *I2CDevice
+
+	mu     sync.Mutex
+	iodir  uint16 // 1 for inputs
+	pullup uint16
+	olat   uint16
+}
+
+// NewMCP23017 resets the direction, pull-ups and outputs of the MCP23017 at
+// addr (0x20 to 0x27) to their power on state: every pin an input without
+// pull-up.
+func (ino *Goduino) NewMCP23017(addr int) (*MCP23017, error) {
+	m := &MCP23017{dev: ino.I2C(addr), iodir: 0xFFFF}
+	if err := m.writeWord(mcp23017IODIR, m.iodir); err != nil {
+		return nil, err
+	}
+	if err := m.writeWord(mcp23017GPPU, 0); err != nil {
+		return nil, err
+	}
+	if err := m.writeWord(mcp23017OLAT, 0); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeWord writes value to the A and B registers of reg.
+func (m *MCP23017) writeWord(reg int, value uint16) error {
+	return m.dev.WriteBytes(reg, []byte{byte(value), byte(value >> 8)})
+}
+
+func checkExpanderPin(pin, pins int) error {
+	if pin < 0 || pin >= pins {
+		return fmt.Errorf("Invalid expander pin number %v\n", pin)
+	}
+	return nil
+}
+
+// SetInput makes pin an input, with the internal 100k pull-up if pullup is
+// set.
+func (m *MCP23017) SetInput(pin int, pullup bool) error {
+	if err := checkExpanderPin(pin, mcp23017Pins); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bit := uint16(1) << uint(pin)
+	p := m.pullup &^ bit
+	if pullup {
+		p |= bit
+	}
+	if p != m.pullup {
+		if err := m.writeWord(mcp23017GPPU, p); err != nil {
+			return err
+		}
+		m.pullup = p
+	}
+	return m.setDirection(bit, true)
+}
+
+// setDirection switches the pins of mask to input or output. Callers hold
+// mu.
+func (m *MCP23017) setDirection(mask uint16, input bool) error {
+	d := m.iodir &^ mask
+	if input {
+		d |= mask
+	}
+	if d == m.iodir {
+		return nil
+	}
+	if err := m.writeWord(mcp23017IODIR, d); err != nil {
+		return err
+	}
+	m.iodir = d
+	return nil
+}
+
+// Write drives pin low for 0 and high otherwise, making it an output.
+func (m *MCP23017) Write(pin, value int) error {
+	if err := checkExpanderPin(pin, mcp23017Pins); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bit := uint16(1) << uint(pin)
+	olat := m.olat &^ bit
+	if value != 0 {
+		olat |= bit
+	}
+	if olat != m.olat {
+		// Only the latch of the port of pin changes
+		port := pin / 8
+		if err := m.dev.WriteRegister(mcp23017OLAT+port, byte(olat>>uint(8*port))); err != nil {
+			return err
+		}
+		m.olat = olat
+	}
+	return m.setDirection(bit, false)
+}
+
+// ReadAll returns the level of all 16 pins, port A in the low byte.
+func (m *MCP23017) ReadAll() (uint16, error) {
+	data, err := m.dev.ReadBytes(mcp23017GPIO, 2)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(data[0]) | uint16(data[1])<<8, nil
+}
+
+// Read returns the level of pin, making it an input if it is an output.
+func (m *MCP23017) Read(pin int) (int, error) {
+	if err := checkExpanderPin(pin, mcp23017Pins); err != nil {
+		return 0, err
+	}
+	bit := uint16(1) << uint(pin)
+	m.mu.Lock()
+	err := m.setDirection(bit, true)
+	m.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	all, err := m.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	if all&bit != 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Pin returns pin as a DigitalPin.
+func (m *MCP23017) Pin(pin int) *MCP23017Pin {
+	return &MCP23017Pin{m: m, pin: pin}
+}
+
+// MCP23017Pin is one pin of an MCP23017.
+type MCP23017Pin struct {
+	m   *MCP23017
+	pin int
+}
+
+// WriteDigital writes value to the pin.
+func (p *MCP23017Pin) WriteDigital(value int) error { return p.m.Write(p.pin, value) }
+
+// ReadDigital reads the pin.
+func (p *MCP23017Pin) ReadDigital() (int, error) { return p.m.Read(p.pin) }
+
+var _ DigitalPin = (*MCP23017Pin)(nil)
@@ -0,0 +1,46 @@
+package goduino
+
+// MCP4725 commands
+const (
+	mcp4725WriteDAC    = 0x40
+	mcp4725WriteEEPROM = 0x60
+)
+
+// MCP4725 is a 12 bit DAC on the I2C bus.
+type MCP4725 struct {
+	dev *I2CDevice
+}
+
+// NewMCP4725 returns the MCP4725 at addr (0x60 to 0x67).
+func (ino *Goduino) NewMCP4725(addr int) *MCP4725 {
+	return &MCP4725{dev: ino.I2C(addr)}
+}
+
+// Write outputs value, 0 to 4095.
+func (d *MCP4725) Write(value uint16) error {
+	return d.write(mcp4725WriteDAC, value)
+}
+
+// WritePowerOn outputs value and stores it in the EEPROM as the output at
+// power on. EEPROM writes wear the chip; do not use it for routine updates.
+func (d *MCP4725) WritePowerOn(value uint16) error {
+	return d.write(mcp4725WriteEEPROM, value)
+}
+
+func (d *MCP4725) write(cmd int, value uint16) error {
+	if value > 4095 {
+		value = 4095
+	}
+	// The command byte is followed by D11-D4 and D3-D0 in the high nibble
+	return d.dev.WriteBytes(cmd, []byte{byte(value >> 4), byte(value << 4)})
+}
+
+// WriteLevel outputs level as a fraction of the supply voltage.
+func (d *MCP4725) WriteLevel(level float64) error {
+	return d.Write(uint16(scaleLevel(level, 12)))
+}
+
+// Resolution returns 12.
+func (d *MCP4725) Resolution() int { return 12 }
+
+var _ AnalogWriter = (*MCP4725)(nil)
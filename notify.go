@@ -0,0 +1,108 @@
+package goduino
+
+import (
+	"fmt"
+	"sync"
+)
+
+// notifyQueue is the number of values a change channel buffers.
+const notifyQueue = 16
+
+// pinNotifier feeds a change channel from a board listener.
+type pinNotifier struct {
+	c        chan int
+	listener int
+	analog   bool
+
+	// the read loop may still deliver while the channel is being closed
+	mu     sync.Mutex
+	closed bool
+}
+
+// OnDigitalChange configures pin as an input and returns a channel that
+// receives the new value of the pin every time the board reports a change.
+// Values are dropped while the channel is full. Stop notifications with
+// StopChange.
+func (ino *Goduino) OnDigitalChange(pin int) (<-chan int, error) {
+	pins := ino.board.Pins()
+	if pin < 0 || pin >= len(pins) {
+		return nil, fmt.Errorf("Invalid pin number %v\n", pin)
+	}
+	if pins[pin].Mode != Input && pins[pin].Mode != Pullup {
+		if err := ino.PinMode(pin, Input); err != nil {
+			return nil, err
+		}
+	}
+	n := &pinNotifier{c: make(chan int, notifyQueue)}
+	n.listener = ino.board.AddDigitalListener(func(p int, value int) {
+		if p == pin {
+			ino.deliver(n, pin, value)
+		}
+	})
+	ino.addNotifier(n)
+	return n.c, nil
+}
+
+// OnAnalogChange configures analog pin for reporting and returns a channel
+// that receives the value of the pin every time a report differs from the
+// previous one. Values are dropped while the channel is full. Stop
+// notifications with StopChange.
+func (ino *Goduino) OnAnalogChange(pin int) (<-chan int, error) {
+	if err := ino.ensureAnalog(pin); err != nil {
+		return nil, err
+	}
+	n := &pinNotifier{c: make(chan int, notifyQueue), analog: true}
+	last := -1
+	n.listener = ino.board.AddAnalogListener(func(channel int, value int) {
+		if channel == pin && value != last {
+			last = value
+			ino.deliver(n, pin, value)
+		}
+	})
+	ino.addNotifier(n)
+	return n.c, nil
+}
+
+// StopChange stops the notifications of a channel returned by
+// OnDigitalChange or OnAnalogChange and closes it.
+func (ino *Goduino) StopChange(c <-chan int) {
+	ino.mu.Lock()
+	n, ok := ino.notifiers[c]
+	delete(ino.notifiers, c)
+	ino.mu.Unlock()
+	if !ok {
+		return
+	}
+	if n.analog {
+		ino.board.RemoveAnalogListener(n.listener)
+	} else {
+		ino.board.RemoveDigitalListener(n.listener)
+	}
+	n.mu.Lock()
+	n.closed = true
+	close(n.c)
+	n.mu.Unlock()
+}
+
+func (ino *Goduino) addNotifier(n *pinNotifier) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.notifiers == nil {
+		ino.notifiers = make(map[<-chan int]*pinNotifier)
+	}
+	ino.notifiers[n.c] = n
+}
+
+// deliver sends value without blocking the read loop.
+func (ino *Goduino) deliver(n *pinNotifier, pin int, value int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.c <- value:
+	default:
+		ino.logger.Printf("change channel of pin %d full, dropping %d\r\n", pin, value)
+	}
+}
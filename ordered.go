@@ -0,0 +1,160 @@
+package goduino
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// subscriptionQueue is the number of reports a Subscription queues for a
+// busy receiver.
+const subscriptionQueue = 1024
+
+// OrderedEvent is a pin report delivered by a Subscription. Seq counts the
+// reports of the subscription from 1; a gap means reports were dropped.
+type OrderedEvent struct {
+	Seq   uint64
+	Kind  EventKind
+	Pin   int
+	Value int
+	Time  time.Time // when the report was received
+}
+
+// Subscription delivers the reports of a pin in arrival order. Unlike
+// the change channels of OnDigitalChange and OnAnalogChange reports queue
+// up while the receiver is busy, up to 1024 of them. Reports arriving on a
+// full queue are dropped and still counted, so the receiver sees a gap in
+// Seq and Dropped tells how many were lost. The subscription survives
+// reconnects, so consumers maintaining derived state can rely on Seq.
+type Subscription struct {
+	// C receives the reports. It is closed by Close.
+	C <-chan OrderedEvent
+
+	ino      *Goduino
+	kind     EventKind
+	listener int
+
+	mu      sync.Mutex
+	queue   []OrderedEvent
+	seq     uint64
+	dropped uint64
+	ready   chan struct{} // signalled when queue grows
+	closed  chan struct{}
+	once    sync.Once
+	done    chan struct{}
+}
+
+// Subscribe configures pin for reporting and subscribes to its reports in
+// order. Pin is a digital pin for DigitalEvent and an analog pin for
+// AnalogEvent; analog reports are delivered when the value changes.
+func (ino *Goduino) Subscribe(kind EventKind, pin int) (*Subscription, error) {
+	c := make(chan OrderedEvent)
+	s := &Subscription{
+		C:      c,
+		ino:    ino,
+		kind:   kind,
+		ready:  make(chan struct{}, 1),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	switch kind {
+	case DigitalEvent:
+		pins := ino.board.Pins()
+		if pin < 0 || pin >= len(pins) {
+			return nil, fmt.Errorf("Invalid pin number %v\n", pin)
+		}
+		if pins[pin].Mode != Input && pins[pin].Mode != Pullup {
+			if err := ino.PinMode(pin, Input); err != nil {
+				return nil, err
+			}
+		}
+		s.listener = ino.board.AddDigitalListener(func(p int, value int) {
+			if p == pin {
+				s.push(pin, value)
+			}
+		})
+	case AnalogEvent:
+		if err := ino.ensureAnalog(pin); err != nil {
+			return nil, err
+		}
+		last := -1
+		s.listener = ino.board.AddAnalogListener(func(channel int, value int) {
+			if channel == pin && value != last {
+				last = value
+				s.push(pin, value)
+			}
+		})
+	default:
+		return nil, fmt.Errorf("unknown event kind %v", kind)
+	}
+	go s.run(c)
+	return s, nil
+}
+
+// push queues a report. It is called from the read loop and never blocks
+// on the receiver.
+func (s *Subscription) push(pin, value int) {
+	s.mu.Lock()
+	s.seq++
+	if len(s.queue) >= subscriptionQueue {
+		if s.dropped == 0 {
+			s.ino.logger.Printf("subscription to pin %d overflowed, dropping reports\r\n", pin)
+		}
+		s.dropped++
+		s.mu.Unlock()
+		return
+	}
+	s.queue = append(s.queue, OrderedEvent{Seq: s.seq, Kind: s.kind, Pin: pin, Value: value, Time: time.Now()})
+	s.mu.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription) run(c chan<- OrderedEvent) {
+	defer close(s.done)
+	defer close(c)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.ready:
+				continue
+			case <-s.closed:
+				return
+			}
+		}
+		// taken one at a time so the queue stays bounded
+		e := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		select {
+		case c <- e:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// Dropped returns the number of reports dropped because the queue was
+// full.
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close ends the subscription, discarding queued reports, and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		if s.kind == AnalogEvent {
+			s.ino.board.RemoveAnalogListener(s.listener)
+		} else {
+			s.ino.board.RemoveDigitalListener(s.listener)
+		}
+		close(s.closed)
+		<-s.done
+	})
+}
@@ -0,0 +1,107 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	s, err := ino.Subscribe(DigitalEvent, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	board.AssertMode(t, 2, Input)
+
+	// Reports queue up while nobody receives
+	for i := 0; i < 10; i++ {
+		if err := board.InjectDigital(2, (i+1)%2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		select {
+		case e := <-s.C:
+			if e.Seq != uint64(i+1) || e.Pin != 2 || e.Value != (i+1)%2 || e.Kind != DigitalEvent {
+				t.Errorf("event %d = %+v", i, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("received %d events, want 10", i)
+		}
+	}
+
+	s.Close()
+	if _, ok := <-s.C; ok {
+		t.Errorf("C is open after Close")
+	}
+	if _, err := ino.Subscribe(DigitalEvent, 20); err == nil {
+		t.Errorf("Subscribe accepted pin 20")
+	}
+}
+
+func TestSubscribeAnalogChanges(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	s, err := ino.Subscribe(AnalogEvent, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, v := range []int{100, 100, 200} {
+		board.InjectAnalog(0, v)
+	}
+	for _, want := range []int{100, 200} {
+		select {
+		case e := <-s.C:
+			if e.Value != want {
+				t.Errorf("received %d, want %d", e.Value, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event for %d", want)
+		}
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	s, err := ino.Subscribe(DigitalEvent, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const n = subscriptionQueue + 100
+	for i := 0; i < n; i++ {
+		if err := board.InjectDigital(2, (i+1)%2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dropped := s.Dropped()
+	// one report may be waiting in the hands of the sender
+	if dropped != n-subscriptionQueue && dropped != n-subscriptionQueue-1 {
+		t.Errorf("Dropped = %d, want %d", dropped, n-subscriptionQueue)
+	}
+	received := uint64(0)
+	for received < n-dropped {
+		select {
+		case e := <-s.C:
+			received++
+			if e.Seq != received {
+				t.Fatalf("event %d has Seq %d", received, e.Seq)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("received %d events, want %d", received, n-dropped)
+		}
+	}
+	// The next report shows the gap
+	if err := board.InjectDigital(2, (n+1)%2); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-s.C:
+		if e.Seq != n+1 {
+			t.Errorf("Seq after the overflow = %d, want %d", e.Seq, n+1)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event after the overflow")
+	}
+}
@@ -0,0 +1,71 @@
+package goduino
+
+import (
+	"fmt"
+	"sort"
+)
+
+// overrideKey identifies an overridden digital pin or analog pin.
+type overrideKey struct {
+	kind EventKind
+	pin  int
+}
+
+// Override forces the input pin of kind to read value, e.g. to simulate
+// an open door switch, while the rest of the board keeps running on the
+// hardware. Reports from the board for the pin are ignored until
+// ClearOverride; reads, change channels and watchers see value.
+func (ino *Goduino) Override(kind EventKind, pin int, value int) error {
+	var err error
+	switch kind {
+	case DigitalEvent:
+		err = ino.board.HoldDigital(pin, true)
+	case AnalogEvent:
+		err = ino.board.HoldAnalog(pin, true)
+	default:
+		err = fmt.Errorf("unknown event kind %v", kind)
+	}
+	if err != nil {
+		return err
+	}
+	ino.mu.Lock()
+	if ino.overrides == nil {
+		ino.overrides = map[overrideKey]int{}
+	}
+	ino.overrides[overrideKey{kind, pin}] = value
+	ino.mu.Unlock()
+	return ino.Inject(Event{Kind: kind, Pin: pin, Value: value})
+}
+
+// ClearOverride hands the pin back to the hardware, restoring the last
+// value the board reported for it.
+func (ino *Goduino) ClearOverride(kind EventKind, pin int) error {
+	ino.mu.Lock()
+	delete(ino.overrides, overrideKey{kind, pin})
+	ino.mu.Unlock()
+	ino.logger.Printf("clearOverride(%s, %d)\r\n", kind, pin)
+	switch kind {
+	case DigitalEvent:
+		return ino.board.HoldDigital(pin, false)
+	case AnalogEvent:
+		return ino.board.HoldAnalog(pin, false)
+	}
+	return fmt.Errorf("unknown event kind %v", kind)
+}
+
+// Overrides returns the active overrides ordered by kind and pin.
+func (ino *Goduino) Overrides() []Event {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	events := make([]Event, 0, len(ino.overrides))
+	for key, value := range ino.overrides {
+		events = append(events, Event{Kind: key.kind, Pin: key.pin, Value: value})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Kind != events[j].Kind {
+			return events[i].Kind < events[j].Kind
+		}
+		return events[i].Pin < events[j].Pin
+	})
+	return events
+}
@@ -0,0 +1,108 @@
+package goduino
+
+import (
+	"sort"
+)
+
+// OversampleMode selects how oversampled analog reports are combined.
+type OversampleMode int
+
+const (
+	OversampleMean OversampleMode = iota
+	OversampleMedian
+)
+
+// oversampler keeps the last n reports of an analog channel.
+type oversampler struct {
+	mode    OversampleMode
+	samples []int
+	next    int
+	count   int
+}
+
+func (o *oversampler) add(value int) {
+	o.samples[o.next] = value
+	o.next = (o.next + 1) % len(o.samples)
+	if o.count < len(o.samples) {
+		o.count++
+	}
+}
+
+func (o *oversampler) value() float64 {
+	samples := append([]int(nil), o.samples[:o.count]...)
+	if o.mode == OversampleMedian {
+		sort.Ints(samples)
+		if len(samples)%2 == 1 {
+			return float64(samples[len(samples)/2])
+		}
+		return float64(samples[len(samples)/2-1]+samples[len(samples)/2]) / 2
+	}
+	sum := 0
+	for _, s := range samples {
+		sum += s
+	}
+	return float64(sum) / float64(len(samples))
+}
+
+// SetOversampling makes readings of analog pin combine its last n reports
+// with mode, which steadies slow signals such as temperature and, with
+// OversampleMean, adds resolution below one ADC step. Each reading then
+// spans n sampling intervals. An n of 1 or less disables oversampling.
+func (ino *Goduino) SetOversampling(pin, n int, mode OversampleMode) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if n <= 1 {
+		delete(ino.oversamplers, pin)
+		return
+	}
+	if ino.oversamplers == nil {
+		ino.oversamplers = make(map[int]*oversampler)
+		ino.board.AddAnalogListener(ino.oversample)
+	}
+	ino.oversamplers[pin] = &oversampler{mode: mode, samples: make([]int, n)}
+	ino.logger.Printf("oversampling(%d, %d)\r\n", pin, n)
+}
+
+func (ino *Goduino) oversample(channel int, value int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if o, ok := ino.oversamplers[channel]; ok {
+		o.add(value)
+	}
+}
+
+// oversampled returns the combined reading of pin if it is oversampled and
+// has received reports.
+func (ino *Goduino) oversampled(pin int) (float64, bool) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	o, ok := ino.oversamplers[pin]
+	if !ok || o.count == 0 {
+		return 0, false
+	}
+	return o.value(), true
+}
+
+// analogValue returns the current reading of analog pin, oversampled if
+// configured.
+func (ino *Goduino) analogValue(pin int) float64 {
+	if v, ok := ino.oversampled(pin); ok {
+		return v
+	}
+	p, err := ino.analogPin(pin)
+	if err != nil {
+		return 0
+	}
+	return float64(ino.board.Pins()[p].Value)
+}
+
+// AnalogReadOversampled retrieves the value of analog pin like AnalogRead
+// but without rounding, keeping the resolution gained by oversampling.
+func (ino *Goduino) AnalogReadOversampled(pin int) (value float64, err error) {
+	if err = ino.ensureAnalog(pin); err != nil {
+		return
+	}
+	value = ino.analogValue(pin)
+	ino.logger.Printf("analogReadOversampled(%d) -> %.2f\r\n", pin, value)
+	return
+}
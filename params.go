@@ -0,0 +1,219 @@
+package goduino
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The parameter extension uses the user defined sysex command 0x0C. A
+// sketch implementing it exposes named 32 bit integer parameters, such as
+// thresholds and modes, that the host can list, read and change:
+//
+//	list:   0x0C 0x00
+//	get:    0x0C 0x01 <name>
+//	set:    0x0C 0x02 <value> <name>
+//	value:  0x0C 0x03 <value> <name>
+//	end:    0x0C 0x04
+//
+// Values are two's complement, sent as five 7 bit bytes, least
+// significant first. Names are ASCII. The sketch answers get and set with
+// a value message holding the (possibly clamped) current value, and list
+// with a value message per parameter followed by end. It sends a value
+// message on its own whenever it changes a parameter. The GoduinoParams
+// Arduino library in extras/GoduinoParams implements the sketch side.
+const (
+	paramSysex byte = 0x0C
+	paramList  byte = 0x00
+	paramGet   byte = 0x01
+	paramSet   byte = 0x02
+	paramValue byte = 0x03
+	paramEnd   byte = 0x04
+
+	paramTimeout = time.Second
+)
+
+// ParamStore mirrors the parameters of a sketch implementing the
+// parameter extension. Get from the Goduino with Params.
+type ParamStore struct {
+	ino *Goduino
+
+	// requests are serialized so replies match their request
+	reqMu sync.Mutex
+
+	mu       sync.Mutex
+	values   map[string]int32
+	handler  func(name string, value int32)
+	replies  chan paramReply
+	awaiting bool
+}
+
+// paramReply is a value message, or the end of a listing if end is set.
+type paramReply struct {
+	name  string
+	value int32
+	end   bool
+}
+
+// Params returns the parameter store of the board, listening for the
+// parameter messages of the sketch from the first call on.
+func (ino *Goduino) Params() *ParamStore {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.params == nil {
+		p := &ParamStore{ino: ino, values: map[string]int32{}, replies: make(chan paramReply, 64)}
+		ino.routeSysex(paramSysex, true, p.receive)
+		ino.params = p
+	}
+	return ino.params
+}
+
+// OnChange calls handler with every value the board reports, whether
+// answering a request or changed by the sketch itself, replacing any
+// previous handler; nil removes it. It runs on the read loop and must
+// return quickly.
+func (p *ParamStore) OnChange(handler func(name string, value int32)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler = handler
+}
+
+// receive handles a parameter message on the read loop.
+func (p *ParamStore) receive(data []byte) {
+	var r paramReply
+	switch {
+	case len(data) == 1 && data[0] == paramEnd:
+		r.end = true
+	case len(data) >= 6 && data[0] == paramValue:
+		r.value = decodeParam(data[1:6])
+		r.name = string(data[6:])
+	default:
+		p.ino.logger.Printf("invalid parameter message % X\r\n", data)
+		return
+	}
+	p.mu.Lock()
+	if !r.end {
+		p.values[r.name] = r.value
+	}
+	handler, awaiting := p.handler, p.awaiting
+	p.mu.Unlock()
+	if awaiting {
+		select {
+		case p.replies <- r:
+		default:
+			p.ino.logger.Printf("parameter reply queue full, dropping %q\r\n", r.name)
+		}
+	}
+	if handler != nil && !r.end {
+		handler(r.name, r.value)
+	}
+}
+
+// request sends data and collects replies until done returns true.
+func (p *ParamStore) request(data []byte, done func(paramReply) bool) error {
+	if err := p.ino.checkFence(); err != nil {
+		return err
+	}
+	p.reqMu.Lock()
+	defer p.reqMu.Unlock()
+	p.mu.Lock()
+	p.awaiting = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.awaiting = false
+		p.mu.Unlock()
+		for len(p.replies) > 0 {
+			<-p.replies
+		}
+	}()
+	if err := p.ino.board.SendSysex(paramSysex, data); err != nil {
+		return err
+	}
+	timeout := time.After(paramTimeout)
+	for {
+		select {
+		case r := <-p.replies:
+			if done(r) {
+				return nil
+			}
+		case <-timeout:
+			return ErrTimeout
+		}
+	}
+}
+
+func paramName(name string) ([]byte, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty parameter name")
+	}
+	for _, c := range []byte(name) {
+		if c > 0x7F {
+			return nil, fmt.Errorf("parameter name %q must be ASCII", name)
+		}
+	}
+	return []byte(name), nil
+}
+
+// List asks the board for all its parameters.
+func (p *ParamStore) List() (map[string]int32, error) {
+	params := map[string]int32{}
+	err := p.request([]byte{paramList}, func(r paramReply) bool {
+		if !r.end {
+			params[r.name] = r.value
+		}
+		return r.end
+	})
+	if err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// Get reads the parameter name from the board.
+func (p *ParamStore) Get(name string) (int32, error) {
+	n, err := paramName(name)
+	if err != nil {
+		return 0, err
+	}
+	var value int32
+	err = p.request(append([]byte{paramGet}, n...), func(r paramReply) bool {
+		value = r.value
+		return r.name == name
+	})
+	return value, err
+}
+
+// Set changes the parameter name on the board and returns the value the
+// sketch accepted, which may be clamped.
+func (p *ParamStore) Set(name string, value int32) (int32, error) {
+	n, err := paramName(name)
+	if err != nil {
+		return 0, err
+	}
+	data := append([]byte{paramSet}, encodeParam(value)...)
+	var stored int32
+	err = p.request(append(data, n...), func(r paramReply) bool {
+		stored = r.value
+		return r.name == name
+	})
+	return stored, err
+}
+
+// Cached returns the last value of name reported by the board, without
+// asking it.
+func (p *ParamStore) Cached(name string) (int32, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v, ok := p.values[name]
+	return v, ok
+}
+
+func encodeParam(v int32) []byte {
+	u := uint32(v)
+	return []byte{byte(u & 0x7F), byte(u >> 7 & 0x7F), byte(u >> 14 & 0x7F), byte(u >> 21 & 0x7F), byte(u >> 28 & 0x0F)}
+}
+
+func decodeParam(b []byte) int32 {
+	return int32(uint32(b[0]&0x7F) | uint32(b[1]&0x7F)<<7 | uint32(b[2]&0x7F)<<14 | uint32(b[3]&0x7F)<<21 | uint32(b[4]&0x0F)<<28)
+}
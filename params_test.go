@@ -0,0 +1,53 @@
+package goduino
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestEncodeParam(t *testing.T) {
+	tests := []struct {
+		value int32
+		want  []byte
+	}{
+		{0, []byte{0x00, 0x00, 0x00, 0x00, 0x00}},
+		{1, []byte{0x01, 0x00, 0x00, 0x00, 0x00}},
+		{300, []byte{0x2C, 0x02, 0x00, 0x00, 0x00}},
+		{-1, []byte{0x7F, 0x7F, 0x7F, 0x7F, 0x0F}},
+		{-300, []byte{0x54, 0x7D, 0x7F, 0x7F, 0x0F}},
+		{math.MaxInt32, []byte{0x7F, 0x7F, 0x7F, 0x7F, 0x07}},
+		{math.MinInt32, []byte{0x00, 0x00, 0x00, 0x00, 0x08}},
+	}
+	for _, tt := range tests {
+		got := encodeParam(tt.value)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeParam(%d) = % X, want % X", tt.value, got, tt.want)
+		}
+		if v := decodeParam(got); v != tt.value {
+			t.Errorf("decodeParam(encodeParam(%d)) = %d", tt.value, v)
+		}
+	}
+}
+
+func TestParamSet(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	p := ino.Params()
+	// The sketch clamps the threshold to 1000
+	go answerSysex(board, paramSysex, func(data []byte) []byte {
+		if data[0] != paramSet {
+			return nil
+		}
+		return append(append([]byte{paramValue}, encodeParam(1000)...), data[6:]...)
+	})
+	stored, err := p.Set("threshold", 5000)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if stored != 1000 {
+		t.Errorf("Set returned %d, want the clamped 1000", stored)
+	}
+	if v, ok := p.Cached("threshold"); !ok || v != 1000 {
+		t.Errorf("Cached = %d, %v, want 1000", v, ok)
+	}
+}
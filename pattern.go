@@ -0,0 +1,209 @@
+package goduino
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Frame is one step of a Pattern: the values written to the pattern pins,
+// in pin order, and how long they are held before the next frame.
+type Frame struct {
+	Values   []int
+	Duration time.Duration
+}
+
+// Pattern drives a group of digital or PWM pins through a looping sequence
+// of frames from a single goroutine.
+type Pattern struct {
+	ino    *Goduino
+	pins   []int
+	pwm    bool
+	frames []Frame
+
+	mu    sync.Mutex
+	stop  chan struct{}
+	pause chan bool
+	done  chan struct{}
+}
+
+// NewPattern creates a pattern over pins. When pwm is set the frame values
+// are written with PwmWrite (0-255), otherwise with DigitalWrite (0/1).
+func (ino *Goduino) NewPattern(pins []int, pwm bool, frames []Frame) *Pattern {
+	return &Pattern{ino: ino, pins: pins, pwm: pwm, frames: frames}
+}
+
+// Name returns the name of the pattern as a Driver.
+func (p *Pattern) Name() string { return fmt.Sprintf("pattern%v", p.pins) }
+
+// Init sets the pattern pins to output or PWM mode.
+func (p *Pattern) Init() error {
+	mode := Output
+	if p.pwm {
+		mode = Pwm
+	}
+	for _, pin := range p.pins {
+		if err := p.ino.PinMode(pin, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Halt stops the pattern.
+func (p *Pattern) Halt() error {
+	p.Stop()
+	return nil
+}
+
+// Start runs the pattern until Stop is called.
+func (p *Pattern) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return errors.New("pattern is already running")
+	}
+	if len(p.frames) == 0 {
+		return errors.New("pattern has no frames")
+	}
+	for i, frame := range p.frames {
+		if len(frame.Values) != len(p.pins) {
+			return fmt.Errorf("frame %d has %d values for %d pins", i, len(frame.Values), len(p.pins))
+		}
+		if frame.Duration <= 0 {
+			return fmt.Errorf("frame %d has no duration", i)
+		}
+	}
+	p.stop = make(chan struct{})
+	p.pause = make(chan bool)
+	p.done = make(chan struct{})
+	go p.run(p.stop, p.pause, p.done)
+	return nil
+}
+
+// Stop ends the pattern, leaving the pins at the last written frame.
+func (p *Pattern) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.stop, p.pause, p.done = nil, nil, nil
+}
+
+// Pause holds the current frame until Resume is called.
+func (p *Pattern) Pause() { p.control(true) }
+
+// Resume continues a paused pattern with the next frame.
+func (p *Pattern) Resume() { p.control(false) }
+
+func (p *Pattern) control(paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pause == nil {
+		return
+	}
+	select {
+	case p.pause <- paused:
+	case <-p.done:
+	}
+}
+
+func (p *Pattern) run(stop <-chan struct{}, pause <-chan bool, done chan<- struct{}) {
+	defer close(done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	paused := false
+	next := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case v := <-pause:
+			if v == paused {
+				continue
+			}
+			paused = v
+			if paused {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+			} else {
+				timer.Reset(0)
+			}
+		case <-timer.C:
+			if paused {
+				continue
+			}
+			frame := p.frames[next]
+			p.write(frame)
+			next = (next + 1) % len(p.frames)
+			timer.Reset(frame.Duration)
+		}
+	}
+}
+
+func (p *Pattern) write(frame Frame) {
+	var failed error
+	for i, pin := range p.pins {
+		var err error
+		if p.pwm {
+			err = p.ino.PwmWrite(pin, byte(frame.Values[i]))
+		} else {
+			err = p.ino.DigitalWrite(pin, frame.Values[i])
+		}
+		if err != nil {
+			p.ino.logger.Printf("pattern write to pin %d failed: %v\r\n", pin, err)
+			failed = err
+		}
+	}
+	p.ino.ReportDriver(p, failed)
+}
+
+// Chase returns frames lighting n pins one at a time, each for step.
+func Chase(n int, step time.Duration) []Frame {
+	frames := make([]Frame, n)
+	for i := range frames {
+		values := make([]int, n)
+		values[i] = 1
+		frames[i] = Frame{Values: values, Duration: step}
+	}
+	return frames
+}
+
+// Strobe returns frames flashing n pins together, on for on and off for off.
+func Strobe(n int, on, off time.Duration) []Frame {
+	high := make([]int, n)
+	for i := range high {
+		high[i] = 1
+	}
+	return []Frame{
+		{Values: high, Duration: on},
+		{Values: make([]int, n), Duration: off},
+	}
+}
+
+// Breathe returns PWM frames fading n pins up and down together over period,
+// using steps frames per period, at least one.
+func Breathe(n int, period time.Duration, steps int) []Frame {
+	if steps < 1 {
+		steps = 1
+	}
+	frames := make([]Frame, steps)
+	for i := range frames {
+		level := int(math.Round(127.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(steps)))))
+		values := make([]int, n)
+		for j := range values {
+			values[j] = level
+		}
+		frames[i] = Frame{Values: values, Duration: period / time.Duration(steps)}
+	}
+	return frames
+}
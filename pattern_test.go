@@ -0,0 +1,25 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreathe(t *testing.T) {
+	frames := Breathe(2, time.Second, 4)
+	want := []int{0, 127, 255, 127} // half way up and down round either way
+	if len(frames) != len(want) {
+		t.Fatalf("Breathe returned %d frames, want %d", len(frames), len(want))
+	}
+	for i, f := range frames {
+		if len(f.Values) != 2 || f.Values[0] != f.Values[1] || f.Values[0]-want[i] > 1 || f.Values[0] < want[i] ||
+			f.Duration != 250*time.Millisecond {
+			t.Errorf("frame %d = %+v, want level %d for 250ms", i, f, want[i])
+		}
+	}
+	for _, steps := range []int{0, -3} {
+		if frames := Breathe(1, time.Second, steps); len(frames) != 1 || frames[0].Duration != time.Second {
+			t.Errorf("Breathe with %d steps = %+v, want one frame", steps, frames)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package goduino
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PCA9685 registers and bits
+const (
+	pca9685Mode1    = 0x00
+	pca9685Mode2    = 0x01
+	pca9685Led0     = 0x06 // LED0_ON_L, each channel takes 4 registers
+	pca9685Prescale = 0xFE
+
+	pca9685Sleep   = 0x10
+	pca9685AutoInc = 0x20
+	pca9685Restart = 0x80
+	pca9685Totem   = 0x04
+	pca9685Full    = 0x1000 // full on / full off bit of the ON and OFF counts
+
+	pca9685Clock    = 25000000
+	pca9685Channels = 16
+)
+
+// PCA9685 is a 16 channel, 12 bit PWM controller on the I2C bus.
+type PCA9685 struct {
+	dev *I2CDevice
+}
+
+// NewPCA9685 sets up the PCA9685 at addr (0x40 by default) for PWM
+// frequency freq in Hz, 24 to 1526.
+func (ino *Goduino) NewPCA9685(addr int, freq float64) (*PCA9685, error) {
+	prescale := math.Round(pca9685Clock/(4096*freq)) - 1
+	if prescale < 3 || prescale > 255 {
+		return nil, fmt.Errorf("PCA9685 frequency %v Hz out of range", freq)
+	}
+	p := &PCA9685{dev: ino.I2C(addr)}
+	// The prescaler can only be written while sleeping
+	if err := p.dev.WriteRegister(pca9685Mode1, pca9685Sleep); err != nil {
+		return nil, err
+	}
+	if err := p.dev.WriteRegister(pca9685Prescale, byte(prescale)); err != nil {
+		return nil, err
+	}
+	if err := p.dev.WriteRegister(pca9685Mode1, pca9685AutoInc); err != nil {
+		return nil, err
+	}
+	// The oscillator needs 500us to start before restarting the outputs
+	time.Sleep(time.Millisecond)
+	if err := p.dev.WriteRegister(pca9685Mode1, pca9685AutoInc|pca9685Restart); err != nil {
+		return nil, err
+	}
+	if err := p.dev.WriteRegister(pca9685Mode2, pca9685Totem); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetPWM sets the 12 bit counts at which channel turns on and off in each
+// period.
+func (p *PCA9685) SetPWM(channel int, on, off int) error {
+	if channel < 0 || channel >= pca9685Channels {
+		return fmt.Errorf("Invalid PCA9685 channel %v\n", channel)
+	}
+	return p.dev.WriteBytes(pca9685Led0+4*channel, []byte{
+		byte(on), byte(on >> 8),
+		byte(off), byte(off >> 8),
+	})
+}
+
+// SetDuty sets the duty cycle of channel to duty, 0 to 4095. The extremes
+// drive the output fully off and fully on.
+func (p *PCA9685) SetDuty(channel int, duty int) error {
+	switch {
+	case duty <= 0:
+		return p.SetPWM(channel, 0, pca9685Full)
+	case duty >= 4095:
+		return p.SetPWM(channel, pca9685Full, 0)
+	}
+	return p.SetPWM(channel, 0, duty)
+}
+
+// Channel returns channel as an AnalogWriter.
+func (p *PCA9685) Channel(channel int) *PCA9685Channel {
+	return &PCA9685Channel{pca: p, channel: channel}
+}
+
+// PCA9685Channel is one output of a PCA9685.
+type PCA9685Channel struct {
+	pca     *PCA9685
+	channel int
+}
+
+// WriteLevel sets the duty cycle of the channel.
+func (c *PCA9685Channel) WriteLevel(level float64) error {
+	return c.pca.SetDuty(c.channel, scaleLevel(level, 12))
+}
+
+// Resolution returns 12.
+func (c *PCA9685Channel) Resolution() int { return 12 }
+
+var _ AnalogWriter = (*PCA9685Channel)(nil)
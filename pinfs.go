@@ -0,0 +1,234 @@
+package goduino
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PinFS presents the board as a tree of small text files, for shell
+// scripts and file based tools:
+//
+//	pins/<n>/mode    the mode name of pin n, e.g. OUTPUT
+//	pins/<n>/value   the value of pin n
+//	analog/<n>       the last reported value of analog pin n
+//
+// PinFS implements fs.FS, fs.ReadDirFS and fs.StatFS for reading; files
+// are read from the state at Open. WriteFile changes modes and values.
+// Mounting it through FUSE or serving it over 9P is left to an adapter
+// built on those libraries; PinFS has the operations they need.
+//
+// PinFS is experimental.
+type PinFS struct {
+	ino *Goduino
+}
+
+// FS returns the board as a PinFS.
+func (ino *Goduino) FS() *PinFS {
+	return &PinFS{ino: ino}
+}
+
+// node is a file or directory of a PinFS.
+type node struct {
+	name     string
+	dir      bool
+	data     []byte
+	children []string
+}
+
+// lookup builds the node at name from the current board state.
+func (p *PinFS) lookup(name string) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, fs.ErrInvalid
+	}
+	pins := p.ino.Pins()
+	parts := strings.Split(name, "/")
+	switch {
+	case name == ".":
+		return &node{name: ".", dir: true, children: []string{"analog", "pins"}}, nil
+	case name == "pins":
+		n := &node{name: name, dir: true}
+		for _, pin := range pins {
+			n.children = append(n.children, strconv.Itoa(pin.Pin))
+		}
+		// fs.ReadDirFS lists entries sorted by name
+		sort.Strings(n.children)
+		return n, nil
+	case name == "analog":
+		n := &node{name: name, dir: true}
+		for _, pin := range pins {
+			if pin.AnalogChannel >= 0 {
+				n.children = append(n.children, strconv.Itoa(pin.AnalogChannel))
+			}
+		}
+		sort.Strings(n.children)
+		return n, nil
+	case parts[0] == "pins" && len(parts) <= 3:
+		i, err := strconv.Atoi(parts[1])
+		if err != nil || i < 0 || i >= len(pins) || parts[1] != strconv.Itoa(i) {
+			break
+		}
+		if len(parts) == 2 {
+			return &node{name: parts[1], dir: true, children: []string{"mode", "value"}}, nil
+		}
+		switch parts[2] {
+		case "mode":
+			return &node{name: "mode", data: []byte(pins[i].Mode.String() + "\n")}, nil
+		case "value":
+			return &node{name: "value", data: []byte(strconv.Itoa(pins[i].Value) + "\n")}, nil
+		}
+	case parts[0] == "analog" && len(parts) == 2:
+		channel, err := strconv.Atoi(parts[1])
+		if err != nil || parts[1] != strconv.Itoa(channel) {
+			break
+		}
+		for _, pin := range pins {
+			if pin.AnalogChannel == channel {
+				return &node{name: parts[1], data: []byte(strconv.Itoa(pin.Value) + "\n")}, nil
+			}
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// Open opens the file or directory at name.
+func (p *PinFS) Open(name string) (fs.File, error) {
+	n, err := p.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	f := &pinFile{fs: p, path: name, node: n}
+	f.Reader = bytes.NewReader(n.data)
+	return f, nil
+}
+
+// Stat returns the FileInfo of name.
+func (p *PinFS) Stat(name string) (fs.FileInfo, error) {
+	n, err := p.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return pinFileInfo{node: n}, nil
+}
+
+// ReadDir returns the entries of the directory at name.
+func (p *PinFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := p.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok || !f.(*pinFile).node.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+	return dir.ReadDir(-1)
+}
+
+// WriteFile writes data, as text, to the file at name. Writing a mode name
+// to pins/<n>/mode sets the mode of pin n. Writing a number to
+// pins/<n>/value writes it according to the mode of the pin: a level for
+// output pins, a duty cycle for PWM pins and an angle for servo pins.
+func (p *PinFS) WriteFile(name string, data []byte) error {
+	fail := func(err error) error { return &fs.PathError{Op: "write", Path: name, Err: err} }
+	if _, err := p.lookup(name); err != nil {
+		return fail(err)
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != "pins" {
+		return fail(fs.ErrPermission)
+	}
+	pin, _ := strconv.Atoi(parts[1])
+	text := strings.TrimSpace(string(data))
+	if parts[2] == "mode" {
+		for mode := PinMode(0); mode <= Pullup; mode++ {
+			if !strings.EqualFold(text, mode.String()) {
+				continue
+			}
+			if mode == Analog {
+				// PinMode takes the analog pin for Analog
+				channel := p.ino.Pins()[pin].AnalogChannel
+				if channel < 0 {
+					return fail(fmt.Errorf("pin %d has no analog input", pin))
+				}
+				return p.ino.PinMode(channel, Analog)
+			}
+			return p.ino.PinMode(pin, int(mode))
+		}
+		return fail(fmt.Errorf("unknown mode %q", text))
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return fail(err)
+	}
+	switch p.ino.board.Pins()[pin].Mode {
+	case Pwm:
+		return p.ino.PwmWrite(pin, byte(value))
+	case Servo:
+		return p.ino.ServoWrite(pin, byte(value))
+	}
+	return p.ino.DigitalWrite(pin, value)
+}
+
+// pinFile is an open file or directory of a PinFS.
+type pinFile struct {
+	*bytes.Reader
+	fs   *PinFS
+	path string
+	node *node
+	read int // directory entries already returned
+}
+
+func (f *pinFile) Stat() (fs.FileInfo, error) {
+	return pinFileInfo{node: f.node}, nil
+}
+
+func (f *pinFile) Close() error { return nil }
+
+func (f *pinFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	names := f.node.children[f.read:]
+	if n > 0 && len(names) > n {
+		names = names[:n]
+	}
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, name := range names {
+		child, err := f.fs.lookup(path.Join(f.path, name))
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(pinFileInfo{node: child}))
+	}
+	f.read += len(names)
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	return entries, nil
+}
+
+// pinFileInfo describes a node. Values change all the time, so no
+// modification time is tracked.
+type pinFileInfo struct {
+	node *node
+}
+
+func (i pinFileInfo) Name() string       { return i.node.name }
+func (i pinFileInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i pinFileInfo) ModTime() time.Time { return time.Time{} }
+func (i pinFileInfo) IsDir() bool        { return i.node.dir }
+func (i pinFileInfo) Sys() interface{}   { return nil }
+
+func (i pinFileInfo) Mode() fs.FileMode {
+	if i.node.dir {
+		return fs.ModeDir | 0555
+	}
+	if i.node.name == "mode" || i.node.name == "value" {
+		return 0644
+	}
+	return 0444
+}
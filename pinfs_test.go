@@ -0,0 +1,48 @@
+package goduino
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPinFS(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	p := ino.FS()
+	if err := fstest.TestFS(p, "pins/13/mode", "pins/13/value", "analog/5"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.WriteFile("pins/13/mode", []byte("output\n")); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertMode(t, 13, Output)
+	if err := p.WriteFile("pins/13/value", []byte("1\n")); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertPin(t, 13, 1)
+	if data, err := fs.ReadFile(p, "pins/13/value"); err != nil || string(data) != "1\n" {
+		t.Errorf("pins/13/value = %q, %v, want 1", data, err)
+	}
+	if data, err := fs.ReadFile(p, "pins/13/mode"); err != nil || string(data) != "OUTPUT\n" {
+		t.Errorf("pins/13/mode = %q, %v, want OUTPUT", data, err)
+	}
+
+	if err := p.WriteFile("pins/14/mode", []byte("analog")); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertMode(t, 14, Analog)
+	board.InjectAnalog(0, 321)
+	if data, err := fs.ReadFile(p, "analog/0"); err != nil || string(data) != "321\n" {
+		t.Errorf("analog/0 = %q, %v, want 321", data, err)
+	}
+
+	for _, name := range []string{"pins/20/value", "pins/13", "analog/0", "pins/13/mode/x"} {
+		if err := p.WriteFile(name, []byte("1")); err == nil {
+			t.Errorf("WriteFile(%q) succeeded", name)
+		}
+	}
+	if err := p.WriteFile("pins/13/mode", []byte("warp")); err == nil {
+		t.Errorf("WriteFile accepted an unknown mode")
+	}
+}
@@ -0,0 +1,102 @@
+package goduino
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PlotFormat selects the text format written by a Plotter.
+type PlotFormat int
+
+const (
+	// PlotArduino writes "name:value" pairs separated by commas, one line
+	// per sample, as read by the Arduino IDE Serial Plotter.
+	PlotArduino PlotFormat = iota
+	// PlotTSV writes a header line and then one tab separated line per
+	// sample, starting with the seconds since the plot started.
+	PlotTSV
+)
+
+// Plotter samples named values periodically and writes them as text.
+type Plotter struct {
+	ino  *Goduino
+	task int
+
+	mu  sync.Mutex
+	err error
+}
+
+// Plot writes the values of names to w every interval until the plotter
+// is closed. Names are resolved like in watch expressions: variables bound
+// with Bind or BindSensor, and D13 or A0 for reported pin values. Values
+// that fail to read are left out of Arduino lines and written as NaN in
+// TSV. The interval must be positive.
+func (ino *Goduino) Plot(w io.Writer, interval time.Duration, format PlotFormat, names ...string) (*Plotter, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("plot interval %v must be positive", interval)
+	}
+	vars := make([]Variable, len(names))
+	for i, name := range names {
+		v, err := ino.resolveVariable(name)
+		if err != nil {
+			return nil, err
+		}
+		vars[i] = v
+	}
+	if format == PlotTSV {
+		if _, err := fmt.Fprintln(w, "time\t"+strings.Join(names, "\t")); err != nil {
+			return nil, err
+		}
+	}
+	p := &Plotter{ino: ino}
+	start := time.Now()
+	p.task = ino.every(interval, priorityLow, func() {
+		p.mu.Lock()
+		failed := p.err != nil
+		p.mu.Unlock()
+		if failed {
+			return
+		}
+		fields := []string{}
+		if format == PlotTSV {
+			fields = append(fields, strconv.FormatFloat(time.Since(start).Seconds(), 'f', 3, 64))
+		}
+		for i, v := range vars {
+			value, err := v()
+			switch {
+			case format == PlotTSV && err != nil:
+				fields = append(fields, "NaN")
+			case format == PlotTSV:
+				fields = append(fields, strconv.FormatFloat(value, 'g', -1, 64))
+			case err == nil:
+				fields = append(fields, names[i]+":"+strconv.FormatFloat(value, 'g', -1, 64))
+			}
+		}
+		sep := ","
+		if format == PlotTSV {
+			sep = "\t"
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(fields, sep)); err != nil {
+			p.mu.Lock()
+			if p.err == nil {
+				p.err = err
+				ino.logger.Printf("plot failed: %v\r\n", err)
+			}
+			p.mu.Unlock()
+		}
+	})
+	return p, nil
+}
+
+// Close stops plotting and returns the write error that stopped output,
+// if any.
+func (p *Plotter) Close() error {
+	p.ino.cancelTask(p.task)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
@@ -0,0 +1,176 @@
+package goduino
+
+import (
+	"fmt"
+	"github.com/argandas/goduino/firmata"
+)
+
+// noAnalogChannel is the analog mapping of pins without an analog input.
+const noAnalogChannel = 127
+
+// BoardProfile summarizes the capabilities the board reported during
+// Connect. Pin lists hold digital pin numbers.
+type BoardProfile struct {
+	Model       string // best guess from the pin layout, empty if unknown
+	Pins        int
+	DigitalPins []int
+	AnalogPins  []int // indexed by analog channel, -1 for unmapped channels
+	PwmPins     []int
+	ServoPins   []int
+	I2CPins     []int
+}
+
+// knownBoards maps pin and analog channel counts to common boards.
+var knownBoards = map[[2]int]string{
+	{20, 6}:  "Arduino Uno",
+	{22, 8}:  "Arduino Nano",
+	{30, 12}: "Arduino Leonardo",
+	{70, 16}: "Arduino Mega",
+}
+
+// newBoardProfile synthesizes the profile of a board from its pins.
+func newBoardProfile(pins []firmata.Pin) *BoardProfile {
+	p := &BoardProfile{Pins: len(pins)}
+	channels := map[int]int{}
+	for i, pin := range pins {
+		for _, mode := range pin.SupportedModes {
+			switch mode {
+			case Input, Output:
+				if len(p.DigitalPins) == 0 || p.DigitalPins[len(p.DigitalPins)-1] != i {
+					p.DigitalPins = append(p.DigitalPins, i)
+				}
+			case Pwm:
+				p.PwmPins = append(p.PwmPins, i)
+			case Servo:
+				p.ServoPins = append(p.ServoPins, i)
+			case I2C:
+				p.I2CPins = append(p.I2CPins, i)
+			}
+		}
+		if pin.AnalogChannel != noAnalogChannel && supportsMode(pin, Analog) {
+			channels[pin.AnalogChannel] = i
+		}
+	}
+	size := 0
+	for channel := range channels {
+		if channel+1 > size {
+			size = channel + 1
+		}
+	}
+	p.AnalogPins = make([]int, size)
+	for channel := range p.AnalogPins {
+		p.AnalogPins[channel] = -1
+	}
+	for channel, pin := range channels {
+		p.AnalogPins[channel] = pin
+	}
+	p.Model = knownBoards[[2]int{p.Pins, len(channels)}]
+	return p
+}
+
+func supportsMode(pin firmata.Pin, mode int) bool {
+	for _, m := range pin.SupportedModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Profile returns the capabilities of the connected board, or nil before
+// Connect has succeeded.
+func (ino *Goduino) Profile() *BoardProfile {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	return ino.profile
+}
+
+// checkMode fails if the board profile says pin cannot be used in mode.
+// For Analog, pin is the analog channel.
+func (ino *Goduino) checkMode(pin, mode int) error {
+	p := ino.Profile()
+	if p == nil {
+		return ErrNotConnected
+	}
+	if mode == Analog {
+		if pin < 0 || pin >= len(p.AnalogPins) || p.AnalogPins[pin] < 0 {
+			return fmt.Errorf("Invalid analog pin number %v\n", pin)
+		}
+		return nil
+	}
+	pins := ino.board.Pins()
+	if pin < 0 || pin >= len(pins) {
+		return fmt.Errorf("Invalid pin number %v\n", pin)
+	}
+	if !supportsMode(pins[pin], mode) {
+		return fmt.Errorf("Pin %v does not support %s mode\n", pin, PinMode(mode))
+	}
+	return nil
+}
+
+// PinInfo describes a pin of the connected board as reported by the
+// capability and analog mapping queries.
+type PinInfo struct {
+	Pin           int
+	Modes         []PinMode
+	Resolution    map[PinMode]int // bits of resolution of each supported mode
+	AnalogChannel int             // -1 for pins without analog input
+	Mode          PinMode
+	Value         int
+}
+
+// Pins returns the pins of the connected board.
+func (ino *Goduino) Pins() []PinInfo {
+	pins := ino.board.Pins()
+	infos := make([]PinInfo, len(pins))
+	for i, pin := range pins {
+		info := PinInfo{
+			Pin:           i,
+			Resolution:    map[PinMode]int{},
+			AnalogChannel: pin.AnalogChannel,
+			Mode:          PinMode(pin.Mode),
+			Value:         pin.Value,
+		}
+		for _, mode := range pin.SupportedModes {
+			info.Modes = append(info.Modes, PinMode(mode))
+			info.Resolution[PinMode(mode)] = pin.Resolutions[mode]
+		}
+		if info.AnalogChannel == noAnalogChannel || !supportsMode(pin, Analog) {
+			info.AnalogChannel = -1
+		}
+		infos[i] = info
+	}
+	return infos
+}
+
+// AnalogPin returns the digital pin number of analog channel, e.g.
+// AnalogPin(0) is A0, or -1 if the board has no such channel.
+func (ino *Goduino) AnalogPin(channel int) int {
+	pin, err := ino.analogPin(channel)
+	if err != nil {
+		return -1
+	}
+	return pin
+}
+
+// analogPin returns the digital pin of analog channel from the analog
+// mapping of the board.
+func (ino *Goduino) analogPin(channel int) (int, error) {
+	if err := ino.checkMode(channel, Analog); err != nil {
+		return 0, err
+	}
+	return ino.Profile().AnalogPins[channel], nil
+}
+
+// ensureAnalog switches analog channel to analog mode unless it already
+// is.
+func (ino *Goduino) ensureAnalog(channel int) error {
+	pin, err := ino.analogPin(channel)
+	if err != nil {
+		return err
+	}
+	if ino.board.Pins()[pin].Mode != Analog {
+		return ino.PinMode(channel, Analog)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package goduino
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/argandas/goduino/firmata"
+)
+
+func TestGappedAnalogMapping(t *testing.T) {
+	// Channels 0-5 on pins 2-7 and 8-9 on pins 10-11
+	pins := make([]firmata.Pin, 12)
+	for i := range pins {
+		pins[i] = firmata.Pin{SupportedModes: []int{Input, Output}, AnalogChannel: noAnalogChannel}
+	}
+	for channel, pin := range map[int]int{0: 2, 1: 3, 2: 4, 3: 5, 4: 6, 5: 7, 8: 10, 9: 11} {
+		pins[pin].SupportedModes = append(pins[pin].SupportedModes, Analog)
+		pins[pin].AnalogChannel = channel
+	}
+	p := newBoardProfile(pins)
+	want := []int{2, 3, 4, 5, 6, 7, -1, -1, 10, 11}
+	if !reflect.DeepEqual(p.AnalogPins, want) {
+		t.Fatalf("AnalogPins = %v, want %v", p.AnalogPins, want)
+	}
+
+	ino := &Goduino{profile: p}
+	for channel, ok := range map[int]bool{0: true, 5: true, 6: false, 7: false, 9: true, 10: false, -1: false} {
+		if err := ino.checkMode(channel, Analog); (err == nil) != ok {
+			t.Errorf("checkMode(%d, Analog) = %v", channel, err)
+		}
+	}
+}
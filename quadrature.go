@@ -0,0 +1,112 @@
+package goduino
+
+import (
+	"sync"
+)
+
+// quadratureOrder is the Gray code sequence of forward rotation, as A<<1|B.
+var quadratureOrder = [4]int{0, 1, 3, 2}
+
+// QuadratureDecoder keeps the position of a quadrature encoder wired to two
+// digital pins by decoding the digital reports of the board, for low-speed
+// encoders without firmware support.
+//
+// Every edge needs its own digital message, so at 57600 baud the decoder is
+// reliable up to roughly 500 transitions per second, less when analog
+// reporting shares the link. Faster rotation makes both channels change
+// between two reports; those steps cannot be decoded and are counted by
+// Errors instead.
+type QuadratureDecoder struct {
+	ino      *Goduino
+	pinA     int
+	pinB     int
+	listener int
+
+	mu       sync.Mutex
+	state    int
+	position int64
+	errors   int64
+}
+
+// NewQuadratureDecoder configures pinA and pinB as inputs, with pull-ups if
+// pullup is set, and starts decoding their changes.
+func (ino *Goduino) NewQuadratureDecoder(pinA, pinB int, pullup bool) (*QuadratureDecoder, error) {
+	mode := Input
+	if pullup {
+		mode = Pullup
+	}
+	for _, pin := range []int{pinA, pinB} {
+		if err := ino.PinMode(pin, mode); err != nil {
+			return nil, err
+		}
+	}
+	q := &QuadratureDecoder{ino: ino, pinA: pinA, pinB: pinB}
+	q.state = q.read()
+	q.listener = ino.board.AddDigitalListener(q.update)
+	return q, nil
+}
+
+// Position returns the number of steps counted, negative when the encoder
+// turned backwards.
+func (q *QuadratureDecoder) Position() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.position
+}
+
+// Errors returns the number of undecodable transitions seen.
+func (q *QuadratureDecoder) Errors() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.errors
+}
+
+// Reset sets the position and error count back to zero.
+func (q *QuadratureDecoder) Reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.position = 0
+	q.errors = 0
+}
+
+// Close stops decoding.
+func (q *QuadratureDecoder) Close() {
+	q.ino.board.RemoveDigitalListener(q.listener)
+}
+
+func (q *QuadratureDecoder) read() int {
+	pins := q.ino.board.Pins()
+	return pins[q.pinA].Value<<1 | pins[q.pinB].Value
+}
+
+func (q *QuadratureDecoder) update(pin int, value int) {
+	if pin != q.pinA && pin != q.pinB {
+		return
+	}
+	// Both channels may have changed in the same report, so compare the
+	// whole state rather than the single pin
+	state := q.read()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if state == q.state {
+		return
+	}
+	switch (quadraturePhase(state) - quadraturePhase(q.state) + 4) % 4 {
+	case 1:
+		q.position++
+	case 3:
+		q.position--
+	default:
+		q.errors++
+	}
+	q.state = state
+}
+
+func quadraturePhase(state int) int {
+	for i, s := range quadratureOrder {
+		if s == state {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,219 @@
+package goduino
+
+import (
+	"context"
+	"errors"
+	"github.com/argandas/goduino/firmata"
+	"time"
+)
+
+// ErrNoReconnect is returned by Reconnect when the Goduino was given a
+// connection instead of a port, so there is nothing to reopen.
+var ErrNoReconnect = errors.New("connection cannot be reopened")
+
+// ReconnectPolicy configures how a Goduino recovers from a lost link.
+type ReconnectPolicy struct {
+	// MaxRetries limits the reconnect attempts per lost link, zero retries
+	// forever
+	MaxRetries int
+	// the delay before an attempt starts at MinBackoff and doubles up to
+	// MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// ConnectTimeout bounds a single connect attempt
+	ConnectTimeout time.Duration
+	// Heartbeat is the interval of protocol version queries used to
+	// detect a dead link, zero disables the heartbeat; a query not
+	// answered within HeartbeatTimeout closes the link
+	Heartbeat        time.Duration
+	HeartbeatTimeout time.Duration
+}
+
+// DefaultReconnectPolicy retries forever with a heartbeat every 5 seconds.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MinBackoff:       500 * time.Millisecond,
+	MaxBackoff:       30 * time.Second,
+	ConnectTimeout:   connectTimeout,
+	Heartbeat:        5 * time.Second,
+	HeartbeatTimeout: 2 * time.Second,
+}
+
+// supervisor watches the link of a connected Goduino.
+type supervisor struct {
+	policy ReconnectPolicy
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// SetReconnectPolicy makes the Goduino reopen its port and reconnect when
+// the link to the board is lost, restoring the pin modes, reports and
+// servo settings of the previous connection. It takes effect on the next
+// Connect, or immediately when already connected.
+func (ino *Goduino) SetReconnectPolicy(policy ReconnectPolicy) {
+	ino.mu.Lock()
+	ino.reconnect = &policy
+	running := ino.supervisor != nil
+	ino.mu.Unlock()
+	if running {
+		ino.stopSupervisor()
+		ino.startSupervisor()
+	}
+}
+
+// startSupervisor starts watching the link if a reconnect policy is set.
+func (ino *Goduino) startSupervisor() {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.reconnect == nil || ino.supervisor != nil {
+		return
+	}
+	s := &supervisor{
+		policy: *ino.reconnect,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	ino.supervisor = s
+	go ino.supervise(s)
+}
+
+// stopSupervisor stops watching the link and waits for a reconnect in
+// progress to give up.
+func (ino *Goduino) stopSupervisor() {
+	ino.mu.Lock()
+	s := ino.supervisor
+	ino.supervisor = nil
+	ino.mu.Unlock()
+	if s != nil {
+		close(s.stop)
+		<-s.done
+	}
+}
+
+func (ino *Goduino) supervise(s *supervisor) {
+	defer close(s.done)
+	var heartbeat <-chan time.Time
+	if s.policy.Heartbeat > 0 {
+		t := time.NewTicker(s.policy.Heartbeat)
+		defer t.Stop()
+		heartbeat = t.C
+	}
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-heartbeat:
+			if ino.ping(s.policy.HeartbeatTimeout, s.stop) {
+				continue
+			}
+			ino.logger.Printf("heartbeat timed out, closing link\r\n")
+			ino.board.Disconnect()
+		case <-ino.board.Done():
+		}
+		if !ino.recover(s) {
+			return
+		}
+	}
+}
+
+// ping reports whether the board answers a protocol version query within
+// timeout.
+func (ino *Goduino) ping(timeout time.Duration, stop <-chan struct{}) bool {
+	received := ino.board.VersionReceived()
+	if err := ino.board.ProtocolVersionQuery(); err != nil {
+		return false
+	}
+	select {
+	case <-received:
+		return true
+	case <-stop:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// recover reconnects with backoff until it succeeds, the retries are
+// exhausted or the supervisor is stopped.
+func (ino *Goduino) recover(s *supervisor) bool {
+	ino.logger.Printf("link lost: %v\r\n", ino.board.Err())
+	backoff := s.policy.MinBackoff
+	for attempt := 1; s.policy.MaxRetries == 0 || attempt <= s.policy.MaxRetries; attempt++ {
+		select {
+		case <-s.stop:
+			return false
+		case <-time.After(backoff):
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), s.policy.ConnectTimeout)
+		go func() {
+			select {
+			case <-s.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err := ino.Reconnect(ctx)
+		cancel()
+		if err == nil {
+			ino.logger.Printf("reconnected after %d attempts\r\n", attempt)
+			return true
+		}
+		ino.logger.Printf("reconnect attempt %d: %v\r\n", attempt, err)
+		if err == ErrNoReconnect {
+			return false
+		}
+		if backoff *= 2; backoff > s.policy.MaxBackoff {
+			backoff = s.policy.MaxBackoff
+		}
+	}
+	ino.logger.Printf("giving up reconnecting\r\n")
+	return false
+}
+
+// Reconnect reopens the port of a Goduino whose link was lost and
+// restores the state of the previous connection.
+func (ino *Goduino) Reconnect(ctx context.Context) error {
+	if ino.port == "" {
+		return ErrNoReconnect
+	}
+	ino.board.Disconnect()
+	<-ino.board.Done()
+	conn, err := ino.openSP(ino.port)
+	if err != nil {
+		return err
+	}
+	ino.conn = conn
+	if err := ino.board.ConnectContext(ctx, conn); err != nil {
+		return err
+	}
+	ino.forgetWrites()
+	ino.i2cMu.Lock()
+	if ino.i2cEnabled {
+		err = ino.board.I2cConfig(0)
+	}
+	ino.i2cMu.Unlock()
+	if err != nil {
+		return err
+	}
+	ino.mu.Lock()
+	servos := map[int]servoState{}
+	for pin, s := range ino.servos {
+		servos[pin] = *s
+	}
+	ino.mu.Unlock()
+	for pin, s := range servos {
+		if s.configured && ino.board.Pins()[pin].Mode == firmata.Servo {
+			if err := ino.board.ServoConfig(pin, s.max, s.min); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ino.reopenUARTs(); err != nil {
+		return err
+	}
+	// the firmware is back at its default sampling interval, which the
+	// cache must not hide from updateSamplingInterval
+	ino.mu.Lock()
+	ino.samplingInterval = 0
+	ino.mu.Unlock()
+	return ino.updateSamplingInterval()
+}
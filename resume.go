@@ -0,0 +1,116 @@
+package goduino
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StateStore persists the runtime state of state machines, watches and
+// schedulers in a JSON file, so a restarted process resumes its
+// automations where they were instead of re-running entry actions,
+// re-raising alerts or missing scheduled jobs. Attach it with
+// SetStateStore before starting the automations.
+//
+// Machines are identified by name, watches by expression and scheduler
+// jobs by the order they were added, so a restarted program must set them
+// up the same way.
+type StateStore struct {
+	path string
+
+	mu    sync.Mutex
+	state map[string]json.RawMessage
+	saved map[string]bool // keys saved by this process
+}
+
+// OpenStateStore loads the state file at path, which need not exist yet.
+func OpenStateStore(path string) (*StateStore, error) {
+	s := &StateStore{path: path, state: map[string]json.RawMessage{}, saved: map[string]bool{}}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetStateStore makes machines, watches and schedulers started from now on
+// persist their state in s and resume from it. A nil store disables
+// persistence.
+func (ino *Goduino) SetStateStore(s *StateStore) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	ino.stateStore = s
+}
+
+func (ino *Goduino) states() *StateStore {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	return ino.stateStore
+}
+
+// load decodes the state saved under key into v and reports whether there
+// was any. A nil store has none.
+func (s *StateStore) load(key string, v interface{}) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	data, ok := s.state[key]
+	s.mu.Unlock()
+	return ok && json.Unmarshal(data, v) == nil
+}
+
+// loadPrevious is load ignoring state saved by this process, so only a
+// restarted process resumes from it.
+func (s *StateStore) loadPrevious(key string, v interface{}) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	saved := s.saved[key]
+	s.mu.Unlock()
+	return !saved && s.load(key, v)
+}
+
+// save stores v under key and writes the file, replacing it atomically so
+// a crash leaves either the old or the new state.
+func (s *StateStore) save(key string, v interface{}) error {
+	if s == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state[key] = data
+	s.saved[key] = true
+	file, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(file)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,130 @@
+package goduino
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SetSafeState declares the value pin must be driven to whenever the program
+// stops controlling the board: on Disconnect, from SafeOnPanic and on
+// watchdog expiry. PWM and servo pins get value through an analog write,
+// other pins through a digital write.
+func (ino *Goduino) SetSafeState(pin, value int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.safeStates == nil {
+		ino.safeStates = make(map[int]int)
+	}
+	ino.safeStates[pin] = value
+}
+
+// ClearSafeState removes the safe state of pin.
+func (ino *Goduino) ClearSafeState(pin int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	delete(ino.safeStates, pin)
+}
+
+// ApplySafeStates drives every pin with a safe state to its value, in pin
+// order. All pins are attempted; the first error is returned.
+func (ino *Goduino) ApplySafeStates() (err error) {
+	ino.mu.Lock()
+	pins := make([]int, 0, len(ino.safeStates))
+	states := make(map[int]int, len(ino.safeStates))
+	for pin, value := range ino.safeStates {
+		pins = append(pins, pin)
+		states[pin] = value
+	}
+	ino.mu.Unlock()
+	sort.Ints(pins)
+
+	for _, pin := range pins {
+		if e := ino.applySafeState(pin, states[pin]); e != nil {
+			ino.logger.Printf("safe state of pin %d failed: %v\r\n", pin, e)
+			if err == nil {
+				err = e
+			}
+		}
+	}
+	return
+}
+
+func (ino *Goduino) applySafeState(pin, value int) error {
+	pins := ino.board.Pins()
+	if pin >= len(pins) {
+		// Pin map not retrieved yet, nothing was driven
+		return nil
+	}
+	ino.logger.Printf("safeState(%d, %d)\r\n", pin, value)
+	ino.forgetWrites(pin)
+	switch pins[pin].Mode {
+	case Pwm, Servo:
+		return ino.board.AnalogWrite(pin, value)
+	case Output:
+		return ino.board.DigitalWrite(pin, value)
+	}
+	if err := ino.board.SetPinMode(pin, Output); err != nil {
+		return err
+	}
+	return ino.board.DigitalWrite(pin, value)
+}
+
+// SafeOnPanic applies the safe states if the calling goroutine is panicking
+// and then continues panicking. Use it as
+//
+//	defer ino.SafeOnPanic()
+//
+// at the top of main and of every goroutine that drives the board.
+func (ino *Goduino) SafeOnPanic() {
+	if r := recover(); r != nil {
+		ino.logger.Printf("panic: %v, applying safe states\r\n", r)
+		ino.ApplySafeStates()
+		panic(r)
+	}
+}
+
+// Watchdog applies the safe states when it is not kicked in time.
+type Watchdog struct {
+	ino     *Goduino
+	timeout time.Duration
+	mu      sync.Mutex
+	timer   *time.Timer
+	expired bool
+}
+
+// Watchdog starts a watchdog that applies the safe states when Kick has not
+// been called for timeout. Kicking an expired watchdog re-arms it.
+func (ino *Goduino) Watchdog(timeout time.Duration) *Watchdog {
+	w := &Watchdog{ino: ino, timeout: timeout}
+	w.timer = time.AfterFunc(timeout, w.expire)
+	return w
+}
+
+func (w *Watchdog) expire() {
+	w.mu.Lock()
+	w.expired = true
+	w.mu.Unlock()
+	w.ino.logger.Printf("watchdog expired, applying safe states\r\n")
+	w.ino.ApplySafeStates()
+}
+
+// Kick postpones expiry by the timeout.
+func (w *Watchdog) Kick() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expired = false
+	w.timer.Reset(w.timeout)
+}
+
+// Expired reports whether the watchdog expired since the last Kick.
+func (w *Watchdog) Expired() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.expired
+}
+
+// Stop disarms the watchdog.
+func (w *Watchdog) Stop() {
+	w.timer.Stop()
+}
@@ -0,0 +1,273 @@
+package goduino
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schedule is a parsed cron expression with minute resolution.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// scheduleMacros are the supported shorthand schedules.
+var scheduleMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseSchedule parses a standard five field cron expression
+// ("minute hour day-of-month month day-of-week"), supporting *, lists,
+// ranges and steps, or one of @hourly, @daily, @weekly, @monthly and
+// @yearly. "0 18 * * *" runs every day at 18:00.
+func ParseSchedule(spec string) (*Schedule, error) {
+	if macro, ok := scheduleMacros[spec]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields", spec)
+	}
+	s := &Schedule{}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := [5]*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, field := range fields {
+		set, err := parseScheduleField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %v", spec, err)
+		}
+		*sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+	return s, nil
+}
+
+func parseScheduleField(field string, min, max int) (set uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Matches reports whether t falls in a minute selected by the schedule.
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 ||
+		s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	// As in cron, a restricted day matches when either day field matches
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first minute after t selected by the schedule, or the
+// zero time if there is none within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for end := t.AddDate(5, 0, 0); t.Before(end); t = t.Add(time.Minute) {
+		if s.Matches(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+type scheduledJob struct {
+	id       int
+	spec     string
+	schedule *Schedule
+	action   func() error
+}
+
+// Scheduler runs actions on cron schedules from a single goroutine, in the
+// local time zone.
+type Scheduler struct {
+	ino    *Goduino
+	mu     sync.Mutex
+	jobs   map[int]*scheduledJob
+	nextID int
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewScheduler creates an empty scheduler. Call Start to run it.
+func (ino *Goduino) NewScheduler() *Scheduler {
+	return &Scheduler{ino: ino, jobs: map[int]*scheduledJob{}}
+}
+
+// Add runs action on every minute selected by spec and returns an id for
+// Remove.
+func (s *Scheduler) Add(spec string, action func() error) (int, error) {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.jobs[s.nextID] = &scheduledJob{id: s.nextID, spec: spec, schedule: schedule, action: action}
+	return s.nextID, nil
+}
+
+// AddPinWrite writes value to the digital pin on every minute selected by
+// spec, e.g. AddPinWrite("0 18 * * *", 7, 1) sets pin 7 high daily at 18:00.
+func (s *Scheduler) AddPinWrite(spec string, pin, value int) (int, error) {
+	return s.Add(spec, func() error { return s.ino.DigitalWrite(pin, value) })
+}
+
+// Remove deletes the job with id.
+func (s *Scheduler) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+}
+
+// Name returns the name of the scheduler as a Driver.
+func (s *Scheduler) Name() string { return "scheduler" }
+
+// Init does nothing, jobs configure their own pins.
+func (s *Scheduler) Init() error { return nil }
+
+// jobState is the state of a scheduled job saved in a StateStore.
+type jobState struct {
+	Spec string    `json:"spec"`
+	Last time.Time `json:"last"` // minute of the last run
+}
+
+// Start begins running jobs. With a StateStore set, jobs whose minute
+// passed while the process was not running are run once right away.
+func (s *Scheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return nil
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.stop, s.done)
+	return nil
+}
+
+// Halt stops running jobs.
+func (s *Scheduler) Halt() error {
+	s.Stop()
+	return nil
+}
+
+// Stop ends running jobs, waiting for a running action to return.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (s *Scheduler) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	store := s.ino.states()
+	now := time.Now().Truncate(time.Minute)
+	s.mu.Lock()
+	missed := []*scheduledJob{}
+	for _, job := range s.jobs {
+		var saved jobState
+		if store.load(job.stateKey(), &saved) && saved.Spec == job.spec {
+			if next := job.schedule.Next(saved.Last); !next.IsZero() && !next.After(now) {
+				missed = append(missed, job)
+			}
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(missed, func(i, j int) bool { return missed[i].id < missed[j].id })
+	for _, job := range missed {
+		s.ino.logger.Printf("running missed job %q\r\n", job.spec)
+		s.runJob(store, job, now)
+	}
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-stop:
+			return
+		case <-time.After(next.Sub(now)):
+		}
+		s.mu.Lock()
+		due := []*scheduledJob{}
+		for _, job := range s.jobs {
+			if job.schedule.Matches(next) {
+				due = append(due, job)
+			}
+		}
+		s.mu.Unlock()
+		for _, job := range due {
+			s.runJob(store, job, next)
+		}
+	}
+}
+
+func (j *scheduledJob) stateKey() string { return fmt.Sprintf("scheduler:%d", j.id) }
+
+// runJob runs job for minute and saves the minute as its last run.
+func (s *Scheduler) runJob(store *StateStore, job *scheduledJob, minute time.Time) {
+	err := job.action()
+	if err != nil {
+		s.ino.logger.Printf("scheduled job %q failed: %v\r\n", job.spec, err)
+	}
+	s.ino.ReportDriver(s, err)
+	if err := store.save(job.stateKey(), jobState{Spec: job.spec, Last: minute}); err != nil {
+		s.ino.logger.Printf("scheduled job %q state not saved: %v\r\n", job.spec, err)
+	}
+}
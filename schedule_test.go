@@ -0,0 +1,61 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, 3, 15, 17, 59, 30, 0, time.Local)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"0 18 * * *", time.Date(2024, 3, 15, 18, 0, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2024, 3, 15, 18, 0, 0, 0, time.Local)},
+		{"30 6 * * 1-5", time.Date(2024, 3, 18, 6, 30, 0, 0, time.Local)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tt.next) {
+			t.Errorf("%q: Next = %v, want %v", tt.spec, got, tt.next)
+		}
+		if !s.Matches(tt.next) {
+			t.Errorf("%q does not match its next time %v", tt.spec, tt.next)
+		}
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *", "@often"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestSchedulerPinWrite(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	s := ino.NewScheduler()
+	id, err := s.AddPinWrite("0 18 * * *", 7, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddPinWrite("0 25 * * *", 7, 1); err == nil {
+		t.Errorf("AddPinWrite accepted hour 25")
+	}
+	if err := s.jobs[id].action(); err != nil {
+		t.Fatalf("job: %v", err)
+	}
+	board.AssertPin(t, 7, 1)
+
+	if err := ino.AddDriver(s); err != nil {
+		t.Fatal(err)
+	}
+	s.Remove(id)
+	if len(s.jobs) != 0 {
+		t.Errorf("Remove left %d jobs", len(s.jobs))
+	}
+}
@@ -0,0 +1,155 @@
+package goduino
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Self test defaults
+const (
+	selfTestRoundTrips = 20
+	selfTestTimeout    = time.Second
+)
+
+// SelfTestOptions configures SelfTest.
+type SelfTestOptions struct {
+	RoundTrips int // protocol round trips to time, 20 if zero
+
+	// Loopback checks that LoopbackIn follows LoopbackOut, which requires
+	// a jumper wire between the two pins.
+	Loopback    bool
+	LoopbackOut int
+	LoopbackIn  int
+}
+
+// SelfTestReport is the result of SelfTest.
+type SelfTestReport struct {
+	RoundTrips  int
+	Lost        int
+	MinLatency  time.Duration
+	MaxLatency  time.Duration
+	MeanLatency time.Duration
+	Jitter      time.Duration // standard deviation of the latency
+
+	CapabilityErrors []string
+	LoopbackErrors   []string
+}
+
+// Passed reports whether every check succeeded.
+func (r *SelfTestReport) Passed() bool {
+	return r.Lost == 0 && len(r.CapabilityErrors) == 0 && len(r.LoopbackErrors) == 0
+}
+
+func (r *SelfTestReport) String() string {
+	return fmt.Sprintf("round trips %d (lost %d), latency min %v mean %v max %v jitter %v, %d capability errors, %d loopback errors",
+		r.RoundTrips, r.Lost, r.MinLatency, r.MeanLatency, r.MaxLatency, r.Jitter,
+		len(r.CapabilityErrors), len(r.LoopbackErrors))
+}
+
+// SelfTest exercises the connected board: it times protocol version round
+// trips, checks that the reported capabilities are consistent and
+// optionally validates a digital loopback between two jumpered pins.
+func (ino *Goduino) SelfTest(opts SelfTestOptions) (*SelfTestReport, error) {
+	if opts.RoundTrips <= 0 {
+		opts.RoundTrips = selfTestRoundTrips
+	}
+	r := &SelfTestReport{RoundTrips: opts.RoundTrips}
+	if err := ino.selfTestLatency(r); err != nil {
+		return nil, err
+	}
+	r.CapabilityErrors = ino.selfTestCapabilities()
+	if opts.Loopback {
+		errs, err := ino.selfTestLoopback(opts.LoopbackOut, opts.LoopbackIn)
+		if err != nil {
+			return nil, err
+		}
+		r.LoopbackErrors = errs
+	}
+	ino.logger.Printf("selfTest: %v\r\n", r)
+	return r, nil
+}
+
+func (ino *Goduino) selfTestLatency(r *SelfTestReport) error {
+	latencies := []time.Duration{}
+	for i := 0; i < r.RoundTrips; i++ {
+		received := ino.board.VersionReceived()
+		start := time.Now()
+		if err := ino.board.ProtocolVersionQuery(); err != nil {
+			return err
+		}
+		select {
+		case <-received:
+			latencies = append(latencies, time.Since(start))
+		case <-time.After(selfTestTimeout):
+			r.Lost++
+		}
+	}
+	if len(latencies) == 0 {
+		return nil
+	}
+	var sum time.Duration
+	r.MinLatency = latencies[0]
+	for _, l := range latencies {
+		sum += l
+		if l < r.MinLatency {
+			r.MinLatency = l
+		}
+		if l > r.MaxLatency {
+			r.MaxLatency = l
+		}
+	}
+	r.MeanLatency = sum / time.Duration(len(latencies))
+	variance := 0.0
+	for _, l := range latencies {
+		d := float64(l - r.MeanLatency)
+		variance += d * d
+	}
+	r.Jitter = time.Duration(math.Sqrt(variance / float64(len(latencies))))
+	return nil
+}
+
+func (ino *Goduino) selfTestCapabilities() (errs []string) {
+	pins := ino.board.Pins()
+	if len(pins) == 0 {
+		return []string{"board reported no pins"}
+	}
+	channels := map[int]int{}
+	for i, pin := range pins {
+		if len(pin.SupportedModes) == 0 {
+			continue // reserved pins, e.g. the serial lines on some boards
+		}
+		analog := supportsMode(pin, Analog)
+		switch {
+		case analog && pin.AnalogChannel == noAnalogChannel:
+			errs = append(errs, fmt.Sprintf("pin %d supports analog but has no channel", i))
+		case !analog && pin.AnalogChannel != noAnalogChannel:
+			errs = append(errs, fmt.Sprintf("pin %d has channel %d but no analog mode", i, pin.AnalogChannel))
+		}
+		if pin.AnalogChannel != noAnalogChannel {
+			if other, ok := channels[pin.AnalogChannel]; ok {
+				errs = append(errs, fmt.Sprintf("pins %d and %d share analog channel %d", other, i, pin.AnalogChannel))
+			}
+			channels[pin.AnalogChannel] = i
+		}
+	}
+	return
+}
+
+func (ino *Goduino) selfTestLoopback(out, in int) (errs []string, err error) {
+	if err = ino.PinMode(out, Output); err != nil {
+		return
+	}
+	if err = ino.PinMode(in, Input); err != nil {
+		return
+	}
+	for _, value := range []int{1, 0, 1, 0} {
+		if err = ino.DigitalWrite(out, value); err != nil {
+			return
+		}
+		if got := ino.waitPinValue(in, value, selfTestTimeout); got != value {
+			errs = append(errs, fmt.Sprintf("pin %d read %d after writing %d to pin %d", in, got, value, out))
+		}
+	}
+	return
+}
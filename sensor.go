@@ -0,0 +1,64 @@
+package goduino
+
+import (
+	"fmt"
+)
+
+// Celsius is a temperature in degrees Celsius.
+type Celsius float64
+
+// Fahrenheit returns the temperature in degrees Fahrenheit.
+func (c Celsius) Fahrenheit() float64 { return float64(c)*9/5 + 32 }
+
+func (c Celsius) String() string { return fmt.Sprintf("%.2f°C", float64(c)) }
+
+// Pascal is a pressure in pascals.
+type Pascal float64
+
+// Hectopascals returns the pressure in hectopascals (millibars).
+func (p Pascal) Hectopascals() float64 { return float64(p) / 100 }
+
+func (p Pascal) String() string { return fmt.Sprintf("%.0fPa", float64(p)) }
+
+// Millimeter is a distance in millimeters.
+type Millimeter float64
+
+// Centimeters returns the distance in centimeters.
+func (m Millimeter) Centimeters() float64 { return float64(m) / 10 }
+
+func (m Millimeter) String() string { return fmt.Sprintf("%.1fmm", float64(m)) }
+
+// RelativeHumidity is a relative humidity in percent.
+type RelativeHumidity float64
+
+func (h RelativeHumidity) String() string { return fmt.Sprintf("%.1f%%RH", float64(h)) }
+
+// Lux is an illuminance in lux.
+type Lux float64
+
+func (l Lux) String() string { return fmt.Sprintf("%.1flx", float64(l)) }
+
+// Thermometer is implemented by sensors measuring temperature.
+type Thermometer interface {
+	Temperature() (Celsius, error)
+}
+
+// Hygrometer is implemented by sensors measuring relative humidity.
+type Hygrometer interface {
+	Humidity() (RelativeHumidity, error)
+}
+
+// Barometer is implemented by sensors measuring atmospheric pressure.
+type Barometer interface {
+	Pressure() (Pascal, error)
+}
+
+// Distancer is implemented by sensors measuring distance.
+type Distancer interface {
+	Distance() (Millimeter, error)
+}
+
+// Luxmeter is implemented by sensors measuring illuminance.
+type Luxmeter interface {
+	Illuminance() (Lux, error)
+}
@@ -0,0 +1,288 @@
+package goduino
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/argandas/goduino/firmata"
+)
+
+// Serial Firmata exposes the extra hardware UARTs of the board over the
+// SERIAL_DATA sysex command. The low nibble of each subcommand is the
+// port:
+//
+//	config: 0x60 0x1p <baud, three 7 bit bytes>
+//	write:  0x60 0x2p <data as 7 bit pairs>
+//	read:   0x60 0x3p 0x00        (report continuously)
+//	reply:  0x60 0x4p <data as 7 bit pairs>
+//	close:  0x60 0x5p
+const (
+	serialConfig byte = 0x10
+	serialWrite  byte = 0x20
+	serialRead   byte = 0x30
+	serialReply  byte = 0x40
+	serialClose  byte = 0x50
+
+	serialContinuous byte = 0x00
+
+	// pin mode of pins claimed by a UART
+	serialMode = 0x0A
+
+	// bytes per write message, keeping the sysex within the 64 byte
+	// buffer of the sketch
+	serialChunk = 28
+
+	// bytes buffered per port before the oldest are dropped
+	serialBuffer = 4096
+)
+
+// ErrSerialClosed is returned by the Read and Write of a closed UART.
+var ErrSerialClosed = errors.New("serial port is closed")
+
+// UARTPins are the receive and transmit pins of a hardware serial port.
+type UARTPins struct {
+	RX, TX int
+}
+
+// MegaUARTs maps the names of the extra hardware serial ports of the
+// Arduino Mega to their ports and pins. Serial0 is the port Firmata
+// itself runs on and cannot be opened.
+var MegaUARTs = map[string]struct {
+	Port firmata.SerialPort
+	Pins UARTPins
+}{
+	"Serial1": {firmata.HardSerial1, UARTPins{RX: 19, TX: 18}},
+	"Serial2": {firmata.HardSerial2, UARTPins{RX: 17, TX: 16}},
+	"Serial3": {firmata.HardSerial3, UARTPins{RX: 15, TX: 14}},
+}
+
+// UART is a hardware serial port of the board driven through Serial
+// Firmata. It implements io.ReadWriteCloser. Get one with ino.UART.
+type UART struct {
+	ino  *Goduino
+	name string
+	port firmata.SerialPort
+	pins UARTPins
+
+	mu      sync.Mutex
+	open    bool
+	baud    int // kept to reopen the port after a reconnect
+	buf     []byte
+	ready   chan struct{} // closed and replaced when data arrives
+	timeout time.Duration
+}
+
+// UART returns the hardware serial port name, such as "Serial1", of an
+// Arduino Mega. The port is not configured until Open is called. Asking
+// for the same name again returns the same UART.
+func (ino *Goduino) UART(name string) (*UART, error) {
+	uart, ok := MegaUARTs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown serial port %q", name)
+	}
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if !ino.supportsUART(uart.Pins) {
+		return nil, fmt.Errorf("board has no serial port %q", name)
+	}
+	if u := ino.uarts[uart.Port]; u != nil {
+		return u, nil
+	}
+	if ino.uarts == nil {
+		ino.uarts = map[firmata.SerialPort]*UART{}
+		ino.routeSysex(byte(firmata.Serial), true, ino.receiveSerial)
+	}
+	u := &UART{ino: ino, name: name, port: uart.Port, pins: uart.Pins, ready: make(chan struct{})}
+	ino.uarts[uart.Port] = u
+	return u, nil
+}
+
+// supportsUART reports whether the board has the UART on pins, either
+// because its pins advertise the serial mode or because it was profiled
+// as a Mega by a firmware that leaves the mode out of its capabilities.
+// ino.mu must be held.
+func (ino *Goduino) supportsUART(pins UARTPins) bool {
+	board := ino.board.Pins()
+	if pins.RX < len(board) && pins.TX < len(board) &&
+		supportsMode(board[pins.RX], serialMode) && supportsMode(board[pins.TX], serialMode) {
+		return true
+	}
+	return ino.profile != nil && ino.profile.Model == "Arduino Mega"
+}
+
+// receiveSerial handles SERIAL_DATA replies on the read loop.
+func (ino *Goduino) receiveSerial(data []byte) {
+	if len(data) == 0 || data[0]&0xF0 != serialReply {
+		return
+	}
+	ino.mu.Lock()
+	u := ino.uarts[firmata.SerialPort(data[0]&0x0F)]
+	ino.mu.Unlock()
+	if u == nil {
+		ino.logger.Printf("serial reply for unknown port %d\r\n", data[0]&0x0F)
+		return
+	}
+	u.receive(data[1:])
+}
+
+func (u *UART) receive(data []byte) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.open {
+		return
+	}
+	for i := 0; i+1 < len(data); i += 2 {
+		u.buf = append(u.buf, data[i]|data[i+1]<<7)
+	}
+	if over := len(u.buf) - serialBuffer; over > 0 {
+		u.ino.logger.Printf("%s buffer full, dropping %d bytes\r\n", u.name, over)
+		u.buf = u.buf[over:]
+	}
+	close(u.ready)
+	u.ready = make(chan struct{})
+}
+
+// Name returns the name of the port, such as "Serial1".
+func (u *UART) Name() string {
+	return u.name
+}
+
+// Pins returns the receive and transmit pins of the port.
+func (u *UART) Pins() UARTPins {
+	return u.pins
+}
+
+// Open configures the port for baud and starts reporting received bytes.
+// The firmware claims the RX and TX pins for the port.
+func (u *UART) Open(baud int) error {
+	if baud <= 0 || baud >= 1<<21 {
+		return fmt.Errorf("Invalid baud rate %v\n", baud)
+	}
+	if err := u.ino.checkFence(); err != nil {
+		return err
+	}
+	if err := u.configure(baud); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	u.open = true
+	u.baud = baud
+	u.buf = nil
+	u.mu.Unlock()
+	return nil
+}
+
+// configure sets the port up for baud and starts its reports.
+func (u *UART) configure(baud int) error {
+	config := []byte{serialConfig | byte(u.port), byte(baud & 0x7F), byte(baud >> 7 & 0x7F), byte(baud >> 14 & 0x7F)}
+	if err := u.ino.board.SendSysex(byte(firmata.Serial), config); err != nil {
+		return err
+	}
+	return u.ino.board.SendSysex(byte(firmata.Serial), []byte{serialRead | byte(u.port), serialContinuous})
+}
+
+// reopenUARTs configures the open UARTs again on a reset board.
+func (ino *Goduino) reopenUARTs() error {
+	ino.mu.Lock()
+	uarts := make([]*UART, 0, len(ino.uarts))
+	for _, u := range ino.uarts {
+		uarts = append(uarts, u)
+	}
+	ino.mu.Unlock()
+	for _, u := range uarts {
+		u.mu.Lock()
+		open, baud := u.open, u.baud
+		u.mu.Unlock()
+		if !open {
+			continue
+		}
+		if err := u.configure(baud); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetReadTimeout bounds how long Read waits for data; zero, the default,
+// waits until data arrives or the port is closed.
+func (u *UART) SetReadTimeout(timeout time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.timeout = timeout
+}
+
+// Read reads received bytes into p, waiting for at least one. It returns
+// ErrTimeout when the read timeout passes first.
+func (u *UART) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	u.mu.Lock()
+	var timeout <-chan time.Time
+	if u.timeout > 0 {
+		timeout = time.After(u.timeout)
+	}
+	for len(u.buf) == 0 {
+		if !u.open {
+			u.mu.Unlock()
+			return 0, ErrSerialClosed
+		}
+		ready := u.ready
+		u.mu.Unlock()
+		select {
+		case <-ready:
+		case <-timeout:
+			return 0, ErrTimeout
+		}
+		u.mu.Lock()
+	}
+	n := copy(p, u.buf)
+	u.buf = u.buf[n:]
+	u.mu.Unlock()
+	return n, nil
+}
+
+// Write transmits p on the port.
+func (u *UART) Write(p []byte) (int, error) {
+	u.mu.Lock()
+	open := u.open
+	u.mu.Unlock()
+	if !open {
+		return 0, ErrSerialClosed
+	}
+	if err := u.ino.checkFence(); err != nil {
+		return 0, err
+	}
+	for n := 0; n < len(p); n += serialChunk {
+		end := n + serialChunk
+		if end > len(p) {
+			end = len(p)
+		}
+		data := []byte{serialWrite | byte(u.port)}
+		for _, b := range p[n:end] {
+			data = append(data, b&0x7F, b>>7)
+		}
+		if err := u.ino.board.SendSysex(byte(firmata.Serial), data); err != nil {
+			return n, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close stops the port and releases its pins. Pending and later Reads
+// return ErrSerialClosed. The UART can be opened again.
+func (u *UART) Close() error {
+	u.mu.Lock()
+	if !u.open {
+		u.mu.Unlock()
+		return nil
+	}
+	u.open = false
+	u.buf = nil
+	close(u.ready)
+	u.ready = make(chan struct{})
+	u.mu.Unlock()
+	return u.ino.board.SendSysex(byte(firmata.Serial), []byte{serialClose | byte(u.port)})
+}
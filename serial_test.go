@@ -0,0 +1,112 @@
+package goduino
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/argandas/goduino/firmata"
+	"github.com/argandas/goduino/goduinotest"
+)
+
+// newTestUART opens Serial1 of a simulated board profiled as a Mega.
+func newTestUART(t *testing.T, args ...interface{}) (*UART, *goduinotest.Board) {
+	t.Helper()
+	ino, board := newTestGoduino(t, args...)
+	if _, err := ino.UART("Serial1"); err == nil {
+		t.Errorf("UART succeeded on an Uno")
+	}
+	ino.mu.Lock()
+	ino.profile = &BoardProfile{Model: "Arduino Mega"}
+	ino.mu.Unlock()
+	u, err := ino.UART("Serial1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Open(115200); err != nil {
+		t.Fatal(err)
+	}
+	return u, board
+}
+
+// serialMessages returns the SERIAL_DATA messages sent to board.
+func serialMessages(board *goduinotest.Board) [][]byte {
+	var msgs [][]byte
+	for _, c := range board.Calls() {
+		if c.Method == "SendSysex" && c.Pin == int(firmata.Serial) {
+			msgs = append(msgs, c.Data)
+		}
+	}
+	return msgs
+}
+
+func TestUARTWrite(t *testing.T) {
+	u, board := newTestUART(t)
+	want := [][]byte{
+		{0x11, 0x00, 0x04, 0x07}, // 115200 baud
+		{0x31, 0x00},
+	}
+	if got := serialMessages(board); len(got) != 2 || !bytes.Equal(got[0], want[0]) || !bytes.Equal(got[1], want[1]) {
+		t.Errorf("Open sent % X, want % X", got, want)
+	}
+
+	board.ClearCalls()
+	if n, err := u.Write([]byte{'A', 0xFF}); n != 2 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	msgs := serialMessages(board)
+	if len(msgs) != 1 || !bytes.Equal(msgs[0], []byte{0x21, 'A', 0x00, 0x7F, 0x01}) {
+		t.Errorf("Write sent % X", msgs)
+	}
+}
+
+func TestUARTRead(t *testing.T) {
+	u, board := newTestUART(t)
+	u.SetReadTimeout(20 * time.Millisecond)
+	buf := make([]byte, 8)
+	if _, err := u.Read(buf); err != ErrTimeout {
+		t.Errorf("Read without data = %v, want ErrTimeout", err)
+	}
+
+	board.InjectSysex(byte(firmata.Serial), []byte{0x41, 'o', 0x00, 'k', 0x00, 0x7F, 0x01})
+	n, err := u.Read(buf)
+	if err != nil || !bytes.Equal(buf[:n], []byte{'o', 'k', 0xFF}) {
+		t.Errorf("Read = %q, %v, want \"ok\\xff\"", buf[:n], err)
+	}
+
+	board.ClearCalls()
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := serialMessages(board); len(msgs) != 1 || !bytes.Equal(msgs[0], []byte{0x51}) {
+		t.Errorf("Close sent % X", msgs)
+	}
+	if _, err := u.Read(buf); err != ErrSerialClosed {
+		t.Errorf("Read after Close = %v, want ErrSerialClosed", err)
+	}
+	if _, err := u.Write([]byte("x")); err != ErrSerialClosed {
+		t.Errorf("Write after Close = %v, want ErrSerialClosed", err)
+	}
+}
+
+func TestUARTReconnect(t *testing.T) {
+	u, board := newTestUART(t, "sim")
+	u.ino.openSP = func(string) (io.ReadWriteCloser, error) { return nil, nil }
+	board.ClearCalls()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := u.ino.Reconnect(ctx); err != nil {
+		t.Fatalf("Reconnect: %v", err)
+	}
+	msgs := serialMessages(board)
+	if len(msgs) != 2 || !bytes.Equal(msgs[0], []byte{0x11, 0x00, 0x04, 0x07}) || !bytes.Equal(msgs[1], []byte{0x31, 0x00}) {
+		t.Errorf("Reconnect sent % X, want the port configured again", msgs)
+	}
+	board.InjectSysex(byte(firmata.Serial), []byte{0x41, 'x', 0x00})
+	buf := make([]byte, 1)
+	if n, err := u.Read(buf); err != nil || n != 1 || buf[0] != 'x' {
+		t.Errorf("Read after Reconnect = %q, %v", buf[:n], err)
+	}
+}
@@ -0,0 +1,167 @@
+package goduino
+
+import (
+	"time"
+)
+
+// servoState keeps the host side settings of a servo pin.
+type servoState struct {
+	min, max   int
+	configured bool
+	idle       time.Duration
+	timer      *time.Timer
+	trim       int
+	reverse    bool
+
+	// motion estimate in logical degrees, see ServoPosition
+	slew     float64 // degrees per second, 0 moves instantly
+	from     float64
+	target   float64
+	moved    time.Time
+	written  bool
+	feedback *servoFeedback
+}
+
+// setRange records the pulse range of the servo. Callers hold ino.mu.
+func (s *servoState) setRange(min, max int) {
+	s.min, s.max = min, max
+	s.configured = true
+}
+
+// servo returns the state of pin, creating it if needed.
+func (ino *Goduino) servo(pin int) *servoState {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	s, ok := ino.servos[pin]
+	if !ok {
+		s = &servoState{}
+		ino.servos[pin] = s
+	}
+	return s
+}
+
+// ServoTrim offsets every angle written to the servo on pin by trim
+// degrees, compensating for horn and mounting misalignment.
+func (ino *Goduino) ServoTrim(pin int, trim int) {
+	s := ino.servo(pin)
+	ino.mu.Lock()
+	s.trim = trim
+	ino.mu.Unlock()
+}
+
+// ServoReverse mirrors the angles written to the servo on pin (0 becomes
+// 180), so mechanically mirrored servos share the same logical angles.
+func (ino *Goduino) ServoReverse(pin int, reverse bool) {
+	s := ino.servo(pin)
+	ino.mu.Lock()
+	s.reverse = reverse
+	ino.mu.Unlock()
+}
+
+// servoAngle converts a logical angle into the angle sent for pin by
+// applying its reverse and trim settings.
+func (ino *Goduino) servoAngle(pin int, angle byte) int {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	a := int(angle)
+	s, ok := ino.servos[pin]
+	if !ok {
+		return a
+	}
+	if s.reverse {
+		a = 180 - a
+	}
+	a += s.trim
+	if a < 0 {
+		a = 0
+	} else if a > 180 {
+		a = 180
+	}
+	return a
+}
+
+// ServoGroupWrite writes the same logical angle to every servo in pins,
+// each adjusted by its own trim and reverse settings.
+func (ino *Goduino) ServoGroupWrite(angle byte, pins ...int) error {
+	for _, pin := range pins {
+		if err := ino.ServoWrite(pin, angle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServoAutoDetach detaches the servo on pin after idle has elapsed without
+// a new ServoWrite, which stops the holding pulses and the jitter hum they
+// cause. The next ServoWrite re-attaches the servo transparently.
+// An idle of 0 disables auto-detach.
+func (ino *Goduino) ServoAutoDetach(pin int, idle time.Duration) {
+	s := ino.servo(pin)
+	ino.mu.Lock()
+	s.idle = idle
+	if idle <= 0 && s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	ino.mu.Unlock()
+	ino.logger.Printf("ServoAutoDetach(%d, %v)\r\n", pin, idle)
+}
+
+// ServoDetach stops driving the servo on pin. StandardFirmata detaches a
+// servo whenever its pin leaves SERVO mode.
+func (ino *Goduino) ServoDetach(pin int) error {
+	ino.mu.Lock()
+	if s, ok := ino.servos[pin]; ok && s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	ino.mu.Unlock()
+	ino.logger.Printf("ServoDetach(%d)\r\n", pin)
+	return ino.board.SetPinMode(pin, Output)
+}
+
+// servoAttach puts pin back in SERVO mode, restoring the pulse range set
+// with ServoConfig since the firmware forgets it on detach.
+func (ino *Goduino) servoAttach(pin int) error {
+	s := ino.servo(pin)
+	ino.mu.Lock()
+	configured, min, max := s.configured, s.min, s.max
+	ino.mu.Unlock()
+	if configured {
+		if err := ino.board.ServoConfig(pin, max, min); err != nil {
+			return err
+		}
+	}
+	return ino.board.SetPinMode(pin, Servo)
+}
+
+// servoTouch restarts the idle timer of pin after a position command.
+func (ino *Goduino) servoTouch(pin int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	s, ok := ino.servos[pin]
+	if !ok || s.idle <= 0 {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Reset(s.idle)
+		return
+	}
+	s.timer = time.AfterFunc(s.idle, func() {
+		if err := ino.ServoDetach(pin); err != nil {
+			ino.logger.Printf("ServoDetach(%d) failed: %v\r\n", pin, err)
+		}
+	})
+}
+
+// stopServoTimers cancels every pending auto-detach.
+func (ino *Goduino) stopServoTimers() {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	for _, s := range ino.servos {
+		if s.timer != nil {
+			s.timer.Stop()
+			s.timer = nil
+		}
+	}
+}
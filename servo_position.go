@@ -0,0 +1,102 @@
+package goduino
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrServoPosition is returned by ServoPosition for servos that were
+// never written and have no feedback.
+var ErrServoPosition = errors.New("servo position is unknown")
+
+// servoFeedback is the ADC pin of an analog feedback servo with the
+// readings at 0 and 180 degrees.
+type servoFeedback struct {
+	pin       int
+	low, high int
+}
+
+// ServoSlewRate sets how fast, in degrees per second, the servo on pin is
+// assumed to move when estimating its position. Hobby servos are commonly
+// rated around 60 degrees per 0.1-0.2 s. A rate of 0, the default, assumes
+// the servo reaches every angle instantly.
+func (ino *Goduino) ServoSlewRate(pin int, degreesPerSecond float64) {
+	s := ino.servo(pin)
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if s.written {
+		s.from, s.moved = s.estimate(time.Now()), time.Now()
+	}
+	s.slew = math.Max(degreesPerSecond, 0)
+}
+
+// ServoFeedback reads the position of the servo on pin from analog pin
+// instead of estimating it, for servos exposing their potentiometer. Low
+// and high are the readings at 0 and 180 degrees.
+func (ino *Goduino) ServoFeedback(pin, analogPin, low, high int) error {
+	if low == high {
+		return fmt.Errorf("feedback readings must differ, got %v for both", low)
+	}
+	if err := ino.ensureAnalog(analogPin); err != nil {
+		return err
+	}
+	s := ino.servo(pin)
+	ino.mu.Lock()
+	s.feedback = &servoFeedback{pin: analogPin, low: low, high: high}
+	ino.mu.Unlock()
+	return nil
+}
+
+// ServoPosition returns the logical angle of the servo on pin: measured
+// for servos set up with ServoFeedback, otherwise estimated from the
+// angles written and the slew rate.
+func (ino *Goduino) ServoPosition(pin int) (float64, error) {
+	ino.mu.Lock()
+	s, ok := ino.servos[pin]
+	var fb *servoFeedback
+	if ok {
+		fb = s.feedback
+	}
+	if fb == nil {
+		defer ino.mu.Unlock()
+		if !ok || !s.written {
+			return 0, ErrServoPosition
+		}
+		return s.estimate(time.Now()), nil
+	}
+	ino.mu.Unlock()
+	value := ino.analogValue(fb.pin)
+	angle := (value - float64(fb.low)) * 180 / float64(fb.high-fb.low)
+	return math.Max(0, math.Min(180, angle)), nil
+}
+
+// servoMoved starts the motion estimate of pin towards angle.
+func (ino *Goduino) servoMoved(pin int, angle byte) {
+	s := ino.servo(pin)
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	now := time.Now()
+	if s.written {
+		s.from = s.estimate(now)
+	} else {
+		s.from = float64(angle)
+	}
+	s.target, s.moved, s.written = float64(angle), now, true
+}
+
+// estimate returns the position at t, moving from the last position
+// towards the target at the slew rate. ino.mu must be held.
+func (s *servoState) estimate(t time.Time) float64 {
+	if s.slew <= 0 {
+		return s.target
+	}
+	travel := s.slew * t.Sub(s.moved).Seconds()
+	if d := s.target - s.from; math.Abs(d) <= travel {
+		return s.target
+	} else if d > 0 {
+		return s.from + travel
+	}
+	return s.from - travel
+}
@@ -0,0 +1,112 @@
+package goduino
+
+import "sync"
+
+// ShiftRegister is a chain of 74HC595 shift registers driven through three
+// outputs, which may themselves be expander pins. Outputs are numbered from
+// Q0 of the first register in the chain.
+type ShiftRegister struct {
+	data, clock, latch DigitalWriter
+
+	mu    sync.Mutex
+	state []byte // output byte of each register, first register first
+}
+
+// NewShiftRegister creates a chain of n 74HC595 registers on the data
+// (DS), clock (SHCP) and latch (STCP) outputs and clears every output.
+func NewShiftRegister(data, clock, latch DigitalWriter, n int) (*ShiftRegister, error) {
+	s := &ShiftRegister{data: data, clock: clock, latch: latch, state: make([]byte, n)}
+	if err := s.latch.WriteDigital(0); err != nil {
+		return nil, err
+	}
+	if err := s.clock.WriteDigital(0); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s, s.shift(s.state)
+}
+
+// Len returns the number of outputs.
+func (s *ShiftRegister) Len() int { return 8 * len(s.state) }
+
+// shift clocks out state, last register first and Q7 first, then latches
+// it. Callers hold mu and commit state only once it succeeded, so a failed
+// write is retried by the next one.
+func (s *ShiftRegister) shift(state []byte) error {
+	for i := len(state) - 1; i >= 0; i-- {
+		for bit := 7; bit >= 0; bit-- {
+			if err := s.data.WriteDigital(int(state[i]>>uint(bit)) & 1); err != nil {
+				return err
+			}
+			if err := s.clock.WriteDigital(1); err != nil {
+				return err
+			}
+			if err := s.clock.WriteDigital(0); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.latch.WriteDigital(1); err != nil {
+		return err
+	}
+	return s.latch.WriteDigital(0)
+}
+
+// WriteRegister sets the eight outputs of register reg at once.
+func (s *ShiftRegister) WriteRegister(reg int, value byte) error {
+	if err := checkExpanderPin(8*reg, s.Len()); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state[reg] == value {
+		return nil
+	}
+	return s.update(reg, value)
+}
+
+// Write drives output low for 0 and high otherwise.
+func (s *ShiftRegister) Write(output, value int) error {
+	if err := checkExpanderPin(output, s.Len()); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.state[output/8] &^ (1 << uint(output%8))
+	if value != 0 {
+		b |= 1 << uint(output%8)
+	}
+	if b == s.state[output/8] {
+		return nil
+	}
+	return s.update(output/8, b)
+}
+
+// update shifts out the state with register reg set to value and keeps it
+// if that succeeded. Callers hold mu.
+func (s *ShiftRegister) update(reg int, value byte) error {
+	state := append([]byte(nil), s.state...)
+	state[reg] = value
+	if err := s.shift(state); err != nil {
+		return err
+	}
+	s.state = state
+	return nil
+}
+
+// Output returns output as a DigitalWriter.
+func (s *ShiftRegister) Output(output int) *ShiftRegisterOutput {
+	return &ShiftRegisterOutput{s: s, output: output}
+}
+
+// ShiftRegisterOutput is one output of a ShiftRegister.
+type ShiftRegisterOutput struct {
+	s      *ShiftRegister
+	output int
+}
+
+// WriteDigital writes value to the output.
+func (o *ShiftRegisterOutput) WriteDigital(value int) error { return o.s.Write(o.output, value) }
+
+var _ DigitalWriter = (*ShiftRegisterOutput)(nil)
@@ -0,0 +1,47 @@
+package goduino
+
+import (
+	"errors"
+	"testing"
+)
+
+// testWriter is a DigitalWriter recording its writes, failing with err.
+type testWriter struct {
+	writes int
+	err    error
+}
+
+func (w *testWriter) WriteDigital(value int) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.writes++
+	return nil
+}
+
+func TestShiftRegisterFailedWrite(t *testing.T) {
+	data, clock, latch := &testWriter{}, &testWriter{}, &testWriter{}
+	s, err := NewShiftRegister(data, clock, latch, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.err = errors.New("link down")
+	if err := s.Write(3, 1); err == nil {
+		t.Fatal("Write succeeded with a failing data pin")
+	}
+	// The failed write is not taken as done
+	data.err = nil
+	latches := latch.writes
+	if err := s.Write(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if latch.writes == latches {
+		t.Errorf("Write after a failure was skipped")
+	}
+	if err := s.WriteRegister(0, 0x08); err != nil {
+		t.Fatal(err)
+	}
+	if latch.writes != latches+2 {
+		t.Errorf("WriteRegister of the current state shifted again")
+	}
+}
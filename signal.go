@@ -0,0 +1,40 @@
+package goduino
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals makes SIGINT and SIGTERM shut the program down cleanly: the
+// safe states are applied, the board is disconnected and the process exits
+// with the conventional 128+signal status. The returned function restores
+// the default signal behavior and may be called more than once.
+func (ino *Goduino) HandleSignals() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			ino.logger.Printf("received %v, shutting down\r\n", sig)
+			if err := ino.Disconnect(); err != nil {
+				ino.logger.Printf("disconnect failed: %v\r\n", err)
+			}
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			os.Exit(code)
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(done)
+		})
+	}
+}
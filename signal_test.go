@@ -0,0 +1,10 @@
+package goduino
+
+import "testing"
+
+func TestHandleSignalsStopTwice(t *testing.T) {
+	ino, _ := newTestGoduino(t)
+	stop := ino.HandleSignals()
+	stop()
+	stop()
+}
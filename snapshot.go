@@ -0,0 +1,130 @@
+package goduino
+
+import "time"
+
+// PinSnapshot is the state of a pin at the time of a snapshot.
+type PinSnapshot struct {
+	Mode       PinMode
+	Value      int
+	Overridden bool // Value is forced by Override
+}
+
+// Snapshot returns the current mode and value of every pin of the board,
+// keyed by pin number. Analog inputs hold their last reported reading.
+func (ino *Goduino) Snapshot() map[int]PinSnapshot {
+	pins := ino.board.Pins()
+	snapshot := make(map[int]PinSnapshot, len(pins))
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	for i, pin := range pins {
+		s := PinSnapshot{Mode: PinMode(pin.Mode), Value: pin.Value}
+		if value, ok := ino.overrides[overrideKey{DigitalEvent, i}]; ok {
+			s.Value, s.Overridden = value, true
+		}
+		if pin.AnalogChannel != noAnalogChannel {
+			if value, ok := ino.overrides[overrideKey{AnalogEvent, pin.AnalogChannel}]; ok {
+				s.Value, s.Overridden = value, true
+			}
+		}
+		snapshot[i] = s
+	}
+	return snapshot
+}
+
+// SnapshotStream delivers a board snapshot periodically.
+type SnapshotStream struct {
+	// C receives a snapshot every interval. Snapshots are dropped while
+	// the receiver is not ready.
+	C <-chan map[int]PinSnapshot
+
+	ino  *Goduino
+	task int
+}
+
+// SnapshotStream takes a Snapshot every interval, starting now, and sends
+// it on the returned stream until the stream is closed. An interval <= 0
+// defaults to one second.
+func (ino *Goduino) SnapshotStream(interval time.Duration) *SnapshotStream {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	c := make(chan map[int]PinSnapshot, 1)
+	s := &SnapshotStream{C: c, ino: ino}
+	s.task = ino.every(interval, priorityLow, func() {
+		select {
+		case c <- ino.Snapshot():
+		default:
+		}
+	})
+	return s
+}
+
+// Close stops the stream.
+func (s *SnapshotStream) Close() {
+	s.ino.cancelTask(s.task)
+}
+
+// SnapshotDelta is a frame of a DeltaStream. A keyframe holds every pin,
+// other frames only the pins that changed since the previous frame.
+type SnapshotDelta struct {
+	Keyframe bool
+	Pins     map[int]PinSnapshot
+}
+
+// DeltaStream delivers board state as deltas, for dashboards following
+// many reporting pins over a slow link.
+type DeltaStream struct {
+	// C receives a frame every interval in which a pin changed. Frames
+	// are not lost while the receiver is not ready: the next frame holds
+	// the changes of the missed ones.
+	C <-chan SnapshotDelta
+
+	ino  *Goduino
+	task int
+}
+
+// DeltaStream compares a Snapshot every interval with the last frame sent
+// and sends the pins that changed. The first frame and then every
+// keyframe intervals a keyframe is sent instead, so receivers that joined
+// late or lost frames resynchronize; keyframe 0 sends only the first. An
+// interval <= 0 defaults to one second.
+func (ino *Goduino) DeltaStream(interval time.Duration, keyframe int) *DeltaStream {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	c := make(chan SnapshotDelta, 1)
+	s := &DeltaStream{C: c, ino: ino}
+	var last map[int]PinSnapshot // state the receiver has
+	ticks := 0
+	s.task = ino.every(interval, priorityLow, func() {
+		current := ino.Snapshot()
+		ticks++
+		frame := SnapshotDelta{Pins: map[int]PinSnapshot{}}
+		if last == nil || (keyframe > 0 && ticks >= keyframe) {
+			frame = SnapshotDelta{Keyframe: true, Pins: current}
+		} else {
+			for pin, p := range current {
+				if prev, ok := last[pin]; !ok || prev != p {
+					frame.Pins[pin] = p
+				}
+			}
+			if len(frame.Pins) == 0 {
+				return
+			}
+		}
+		select {
+		case c <- frame:
+			last = current
+			if frame.Keyframe {
+				ticks = 0
+			}
+		default:
+		}
+	})
+	return s
+}
+
+// Close stops the stream.
+func (s *DeltaStream) Close() {
+	s.ino.cancelTask(s.task)
+}
@@ -0,0 +1,95 @@
+package goduino
+
+import (
+	"errors"
+	"github.com/argandas/goduino/firmata"
+	"time"
+)
+
+// StepperInterface selects how a stepper motor is wired.
+type StepperInterface int
+
+const (
+	StepperDriver    StepperInterface = firmata.StepperDriver // step and direction pins
+	StepperTwoWire   StepperInterface = firmata.StepperTwoWire
+	StepperThreeWire StepperInterface = firmata.StepperThreeWire
+	StepperFourWire  StepperInterface = firmata.StepperFourWire
+)
+
+// StepperDirection is the turning direction of a stepper move.
+type StepperDirection int
+
+const (
+	Clockwise StepperDirection = iota
+	CounterClockwise
+)
+
+// StepperConfig configures the AccelStepper device (0-9) for a motor with
+// stepsPerRev steps per revolution wired to pins through iface. The board
+// must run a firmware with AccelStepperFirmata, e.g. ConfigurableFirmata.
+func (ino *Goduino) StepperConfig(device int, iface StepperInterface, stepsPerRev int, pins ...int) error {
+	if stepsPerRev <= 0 {
+		return errors.New("steps per revolution must be positive")
+	}
+	if err := ino.board.StepperConfig(device, int(iface), pins); err != nil {
+		return err
+	}
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.steppers == nil {
+		ino.steppers = map[int]int{}
+	}
+	ino.steppers[device] = stepsPerRev
+	return nil
+}
+
+// StepperStep turns device by steps in direction at speed revolutions
+// per minute. The returned channel is closed when the move completes, so
+// callers can block on it or select on it with other events.
+func (ino *Goduino) StepperStep(device int, direction StepperDirection, steps int, speed float64) (<-chan struct{}, error) {
+	if err := ino.checkFence(); err != nil {
+		return nil, err
+	}
+	ino.mu.Lock()
+	stepsPerRev, ok := ino.steppers[device]
+	ino.mu.Unlock()
+	if !ok {
+		return nil, errors.New("stepper device is not configured")
+	}
+	if err := ino.board.StepperSpeed(device, speed*float64(stepsPerRev)/60); err != nil {
+		return nil, err
+	}
+	if direction == CounterClockwise {
+		steps = -steps
+	}
+	done := ino.board.StepperMoveComplete(device)
+	if err := ino.board.StepperStep(device, steps); err != nil {
+		return nil, err
+	}
+	return done, nil
+}
+
+// StepperStop stops device as fast as possible.
+func (ino *Goduino) StepperStop(device int) error {
+	return ino.board.StepperStop(device)
+}
+
+// StepperPosition returns the position of device in steps, as reported by
+// its last completed move.
+func (ino *Goduino) StepperPosition(device int) int {
+	return ino.board.StepperPosition(device)
+}
+
+// Tone plays frequency Hz on pin for duration, zero plays until NoTone.
+// The board must run a firmware handling TONE_DATA.
+func (ino *Goduino) Tone(pin int, frequency int, duration time.Duration) error {
+	if err := ino.checkFence(); err != nil {
+		return err
+	}
+	return ino.board.Tone(pin, frequency, int(duration/time.Millisecond))
+}
+
+// NoTone stops the tone on pin.
+func (ino *Goduino) NoTone(pin int) error {
+	return ino.board.NoTone(pin)
+}
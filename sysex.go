@@ -0,0 +1,87 @@
+package goduino
+
+// SendSysex sends a custom sysex message of cmd with data to the board.
+// cmd and every data byte must be 7 bit.
+func (ino *Goduino) SendSysex(cmd byte, data []byte) error {
+	if err := ino.checkFence(); err != nil {
+		return err
+	}
+	return ino.board.SendSysex(cmd, data)
+}
+
+// OnSysex calls handler with the data of every sysex message of cmd
+// received from the board, so sketches can extend the protocol with their
+// own commands. A later registration for cmd replaces the handler, a nil
+// handler removes it. Commands used by the extensions of this package,
+// such as the identity and parameter ones, reach both. Handlers run on the
+// read loop and must return quickly.
+func (ino *Goduino) OnSysex(cmd byte, handler func(data []byte)) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	ino.routeSysex(cmd, false, handler)
+}
+
+// sysexRoute holds the handlers of a sysex command: the one of the
+// extension implemented by this package, if any, and the one registered
+// with OnSysex. Both see every message.
+type sysexRoute struct {
+	internal, user func([]byte)
+}
+
+// routeSysex sets the internal or user handler of cmd, nil removes it.
+// The board only has one handler per command, so it is given a
+// dispatcher while either is set. ino.mu must be held.
+func (ino *Goduino) routeSysex(cmd byte, internal bool, handler func([]byte)) {
+	if ino.sysexRoutes == nil {
+		ino.sysexRoutes = map[byte]*sysexRoute{}
+	}
+	route := ino.sysexRoutes[cmd]
+	if route == nil {
+		route = &sysexRoute{}
+		ino.sysexRoutes[cmd] = route
+	}
+	if internal {
+		route.internal = handler
+	} else {
+		route.user = handler
+	}
+	if route.internal == nil && route.user == nil {
+		delete(ino.sysexRoutes, cmd)
+		ino.board.OnSysex(cmd, nil)
+		return
+	}
+	ino.board.OnSysex(cmd, func(data []byte) {
+		ino.mu.Lock()
+		route := ino.sysexRoutes[cmd]
+		var r sysexRoute
+		if route != nil {
+			r = *route
+		}
+		ino.mu.Unlock()
+		if r.internal != nil {
+			r.internal(data)
+		}
+		if r.user != nil {
+			r.user(append([]byte{}, data...))
+		}
+	})
+}
+
+// SendString sends s to the board as STRING_DATA.
+func (ino *Goduino) SendString(s string) error {
+	if err := ino.checkFence(); err != nil {
+		return err
+	}
+	return ino.board.SendString(s)
+}
+
+// OnString calls handler with every STRING_DATA message received from the
+// board that is not a virtual channel message, replacing any previous
+// handler; nil removes it. It runs on the read loop and must return
+// quickly.
+func (ino *Goduino) OnString(handler func(string)) {
+	ino.mu.Lock()
+	ino.stringHandler = handler
+	ino.mu.Unlock()
+	ino.routeStrings()
+}
@@ -0,0 +1,45 @@
+package goduino
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSysex(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	var got []byte
+	ino.OnSysex(0x01, func(data []byte) { got = data })
+	board.InjectSysex(0x01, []byte{0x10, 0x20})
+	if !bytes.Equal(got, []byte{0x10, 0x20}) {
+		t.Errorf("handler got % X, want 10 20", got)
+	}
+	ino.OnSysex(0x01, nil)
+	got = nil
+	board.InjectSysex(0x01, []byte{0x30})
+	if got != nil {
+		t.Errorf("removed handler got % X", got)
+	}
+
+	if err := ino.SendSysex(0x02, []byte{0x7F}); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := hasCall(board, "SendSysex", 0x02); !ok || !bytes.Equal(c.Data, []byte{0x7F}) {
+		t.Errorf("SendSysex sent %+v", c)
+	}
+}
+
+func TestString(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	var got string
+	ino.OnString(func(s string) { got = s })
+	board.InjectString("ready")
+	if got != "ready" {
+		t.Errorf("handler got %q, want ready", got)
+	}
+	if err := ino.SendString("go"); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := hasCall(board, "SendString", 0); !ok || string(c.Data) != "go" {
+		t.Errorf("SendString sent %+v", c)
+	}
+}
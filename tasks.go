@@ -0,0 +1,135 @@
+package goduino
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// taskPriority orders periodic tasks that are due at the same time.
+type taskPriority int
+
+const (
+	priorityLow    taskPriority = iota // pollers and monitors
+	priorityNormal                     // control loops
+	priorityHigh                       // multiplexed outputs, where jitter shows
+)
+
+// minTaskPeriod is the shortest period of a task, so a zero or negative
+// one cannot spin the loop.
+const minTaskPeriod = time.Millisecond
+
+type periodicTask struct {
+	id       int
+	period   time.Duration
+	priority taskPriority
+	next     time.Time
+	fn       func()
+}
+
+// taskLoop time-slices the periodic work of host-driven drivers over one
+// goroutine, so drivers do not each run a ticker goroutine competing for
+// the link. One due task runs at a time, highest priority first; runs
+// that fall behind are skipped rather than queued.
+type taskLoop struct {
+	mu      sync.Mutex
+	tasks   map[int]*periodicTask
+	nextID  int
+	running int // id of the running task, 0 if none
+	idle    *sync.Cond
+	wake    chan struct{}
+	started bool
+}
+
+func (l *taskLoop) init() {
+	if l.tasks == nil {
+		l.tasks = map[int]*periodicTask{}
+		l.idle = sync.NewCond(&l.mu)
+		l.wake = make(chan struct{}, 1)
+	}
+}
+
+// every runs fn now and then every period until cancelTask is called with
+// the returned id. Periods are at least minTaskPeriod.
+func (ino *Goduino) every(period time.Duration, priority taskPriority, fn func()) int {
+	if period < minTaskPeriod {
+		period = minTaskPeriod
+	}
+	l := &ino.tasks
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.init()
+	l.nextID++
+	l.tasks[l.nextID] = &periodicTask{id: l.nextID, period: period, priority: priority, next: time.Now(), fn: fn}
+	if !l.started {
+		l.started = true
+		go l.run()
+	} else {
+		select {
+		case l.wake <- struct{}{}:
+		default:
+		}
+	}
+	return l.nextID
+}
+
+// cancelTask removes the task with id, waiting for it to return if it is
+// running. It must not be called from the task itself.
+func (ino *Goduino) cancelTask(id int) {
+	l := &ino.tasks
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.init()
+	delete(l.tasks, id)
+	for l.running == id {
+		l.idle.Wait()
+	}
+}
+
+func (l *taskLoop) run() {
+	for {
+		l.mu.Lock()
+		if len(l.tasks) == 0 {
+			l.started = false
+			l.mu.Unlock()
+			return
+		}
+		now := time.Now()
+		due := []*periodicTask{}
+		var earliest time.Time
+		for _, t := range l.tasks {
+			if !t.next.After(now) {
+				due = append(due, t)
+			} else if earliest.IsZero() || t.next.Before(earliest) {
+				earliest = t.next
+			}
+		}
+		if len(due) == 0 {
+			l.mu.Unlock()
+			select {
+			case <-l.wake:
+			case <-time.After(earliest.Sub(now)):
+			}
+			continue
+		}
+		sort.Slice(due, func(i, j int) bool {
+			if due[i].priority != due[j].priority {
+				return due[i].priority > due[j].priority
+			}
+			return due[i].next.Before(due[j].next)
+		})
+		t := due[0]
+		if t.next = t.next.Add(t.period); t.next.Before(now) {
+			t.next = now.Add(t.period)
+		}
+		l.running = t.id
+		l.mu.Unlock()
+
+		t.fn()
+
+		l.mu.Lock()
+		l.running = 0
+		l.idle.Broadcast()
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,85 @@
+package goduino
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTaskLoop(t *testing.T) {
+	ino, _ := newTestGoduino(t)
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func() {
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+		}
+	}
+	// Tasks due together run highest priority first, so the loop is held
+	// back until all three are added
+	ino.tasks.mu.Lock()
+	ino.tasks.started = true
+	ino.tasks.mu.Unlock()
+	low := ino.every(time.Hour, priorityLow, record("low"))
+	high := ino.every(time.Hour, priorityHigh, record("high"))
+	fast := ino.every(5*time.Millisecond, priorityNormal, record("fast"))
+	go ino.tasks.run()
+
+	time.Sleep(50 * time.Millisecond)
+	ino.cancelTask(fast)
+	mu.Lock()
+	n := len(order)
+	if n < 5 || order[0] != "high" || order[1] != "fast" || order[2] != "low" {
+		t.Errorf("ran %v, want high, fast, low, then fast again", order)
+	}
+	mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	if len(order) != n {
+		t.Errorf("cancelled task ran %d more times", len(order)-n)
+	}
+	mu.Unlock()
+	ino.cancelTask(low)
+	ino.cancelTask(high)
+}
+
+func TestCancelTaskWaits(t *testing.T) {
+	ino, _ := newTestGoduino(t)
+	started := make(chan struct{})
+	var done bool
+	var once sync.Once
+	id := ino.every(time.Hour, priorityNormal, func() {
+		once.Do(func() { close(started) })
+		time.Sleep(20 * time.Millisecond)
+		done = true
+	})
+	<-started
+	ino.cancelTask(id)
+	if !done {
+		t.Errorf("cancelTask returned while the task was running")
+	}
+}
+
+func TestEveryNonPositivePeriod(t *testing.T) {
+	ino, _ := newTestGoduino(t)
+	var mu sync.Mutex
+	runs := 0
+	id := ino.every(0, priorityNormal, func() {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+	})
+	time.Sleep(20 * time.Millisecond)
+	ino.cancelTask(id)
+	mu.Lock()
+	defer mu.Unlock()
+	if runs == 0 || runs > 25 {
+		t.Errorf("task with period 0 ran %d times in 20ms", runs)
+	}
+	if _, err := ino.Plot(io.Discard, 0, PlotTSV); err == nil {
+		t.Errorf("Plot accepted an interval of 0")
+	}
+}
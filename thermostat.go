@@ -0,0 +1,176 @@
+package goduino
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// thermostatQueue is the number of telemetry events a Thermostat buffers.
+const thermostatQueue = 16
+
+// ThermostatEvent is the telemetry of one thermostat control step.
+type ThermostatEvent struct {
+	Time        time.Time
+	Temperature Celsius
+	Setpoint    Celsius
+	On          bool
+	Err         error // set when the temperature could not be read
+}
+
+// ThermostatConfig configures a Thermostat. Zero durations disable the
+// corresponding protection.
+type ThermostatConfig struct {
+	Setpoint   Celsius
+	Hysteresis Celsius       // total band around the setpoint
+	Cooling    bool          // drive the output above the setpoint instead of below
+	Interval   time.Duration // control period, one second if zero
+	MinOn      time.Duration // shortest time the output stays on
+	MinOff     time.Duration // shortest time the output stays off
+}
+
+// Thermostat is a bang-bang controller with hysteresis driving a relay or
+// heater pin from a Thermometer. On read errors and when stopped the output
+// is switched off.
+type Thermostat struct {
+	// Events receives the telemetry of every control step. Events are
+	// dropped while it is full.
+	Events <-chan ThermostatEvent
+
+	ino    *Goduino
+	sensor Thermometer
+	pin    int
+	events chan ThermostatEvent
+
+	mu      sync.Mutex
+	config  ThermostatConfig
+	on      bool
+	changed time.Time
+	task    int // scheduler task id, 0 when stopped
+}
+
+// NewThermostat creates a thermostat driving pin from sensor. Call Start to
+// run it.
+func (ino *Goduino) NewThermostat(sensor Thermometer, pin int, config ThermostatConfig) *Thermostat {
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+	events := make(chan ThermostatEvent, thermostatQueue)
+	return &Thermostat{Events: events, ino: ino, sensor: sensor, pin: pin, events: events, config: config}
+}
+
+// SetSetpoint changes the target temperature.
+func (t *Thermostat) SetSetpoint(c Celsius) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.config.Setpoint = c
+}
+
+// Setpoint returns the target temperature.
+func (t *Thermostat) Setpoint() Celsius {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.config.Setpoint
+}
+
+// On reports whether the output is driven.
+func (t *Thermostat) On() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.on
+}
+
+// Name returns the name of the thermostat as a Driver.
+func (t *Thermostat) Name() string { return fmt.Sprintf("thermostat(pin %d)", t.pin) }
+
+// Init sets the output pin to output mode.
+func (t *Thermostat) Init() error {
+	return t.ino.PinMode(t.pin, Output)
+}
+
+// Halt stops controlling and switches the output off.
+func (t *Thermostat) Halt() error {
+	return t.Stop()
+}
+
+// Start switches the output off and begins controlling.
+func (t *Thermostat) Start() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.task != 0 {
+		return nil
+	}
+	if err := t.ino.DigitalWrite(t.pin, 0); err != nil {
+		return err
+	}
+	t.on = false
+	t.changed = time.Now()
+	t.task = t.ino.every(t.config.Interval, priorityNormal, t.step)
+	return nil
+}
+
+// Stop ends controlling and switches the output off.
+func (t *Thermostat) Stop() error {
+	t.mu.Lock()
+	task := t.task
+	t.task = 0
+	t.mu.Unlock()
+	if task == 0 {
+		return nil
+	}
+	t.ino.cancelTask(task)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.on = false
+	return t.ino.DigitalWrite(t.pin, 0)
+}
+
+func (t *Thermostat) step() {
+	temp, err := t.sensor.Temperature()
+	failed := err
+	now := time.Now()
+	t.mu.Lock()
+	c := t.config
+	want := t.on
+	if err != nil {
+		want = false
+	} else {
+		low, high := c.Setpoint-c.Hysteresis/2, c.Setpoint+c.Hysteresis/2
+		switch {
+		case !c.Cooling && temp < low, c.Cooling && temp > high:
+			want = true
+		case !c.Cooling && temp > high, c.Cooling && temp < low:
+			want = false
+		}
+	}
+	// Respect minimum cycle times, except that errors always switch off
+	if want != t.on && err == nil {
+		minimum := c.MinOff
+		if t.on {
+			minimum = c.MinOn
+		}
+		if now.Sub(t.changed) < minimum {
+			want = t.on
+		}
+	}
+	if want != t.on {
+		value := 0
+		if want {
+			value = 1
+		}
+		if werr := t.ino.DigitalWrite(t.pin, value); werr != nil {
+			t.ino.logger.Printf("thermostat write to pin %d failed: %v\r\n", t.pin, werr)
+			failed = werr
+		} else {
+			t.on = want
+			t.changed = now
+		}
+	}
+	event := ThermostatEvent{Time: now, Temperature: temp, Setpoint: c.Setpoint, On: t.on, Err: err}
+	t.mu.Unlock()
+	t.ino.ReportDriver(t, failed)
+	select {
+	case t.events <- event:
+	default:
+	}
+}
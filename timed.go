@@ -0,0 +1,100 @@
+package goduino
+
+import (
+	"time"
+)
+
+// timedOutput is a pending timed restore of a pin.
+type timedOutput struct {
+	timer     *time.Timer
+	after     int
+	addedSafe bool // after was set as the safe state of the pin
+}
+
+// PulseHigh drives pin high now and low after d, for door strikes and
+// valves. See TimedWrite.
+func (ino *Goduino) PulseHigh(pin int, d time.Duration) error {
+	return ino.TimedWrite(pin, 1, 0, d)
+}
+
+// TimedWrite writes value to the digital pin now and after once d has
+// elapsed. The restore is run by the library, so it happens even if the
+// caller stops in between; until then after is also used as the safe state
+// of pin unless one was declared with SetSafeState. Calling it again for a
+// pending pin restarts the timer.
+func (ino *Goduino) TimedWrite(pin, value, after int, d time.Duration) error {
+	if err := ino.DigitalWrite(pin, value); err != nil {
+		return err
+	}
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.timedOutputs == nil {
+		ino.timedOutputs = make(map[int]*timedOutput)
+	}
+	t := &timedOutput{after: after}
+	if old, ok := ino.timedOutputs[pin]; ok {
+		old.timer.Stop()
+		t.addedSafe = old.addedSafe
+	} else if _, ok := ino.safeStates[pin]; !ok {
+		t.addedSafe = true
+	}
+	if t.addedSafe {
+		if ino.safeStates == nil {
+			ino.safeStates = make(map[int]int)
+		}
+		ino.safeStates[pin] = after
+	}
+	ino.timedOutputs[pin] = t
+	t.timer = time.AfterFunc(d, func() { ino.expireTimed(pin, t) })
+	return nil
+}
+
+// CancelTimed restores a pending timed pin immediately.
+func (ino *Goduino) CancelTimed(pin int) error {
+	ino.mu.Lock()
+	t, ok := ino.timedOutputs[pin]
+	ino.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	t.timer.Stop()
+	return ino.restoreTimed(pin, t)
+}
+
+func (ino *Goduino) expireTimed(pin int, t *timedOutput) {
+	if err := ino.restoreTimed(pin, t); err != nil {
+		ino.logger.Printf("timed restore of pin %d failed: %v\r\n", pin, err)
+	}
+}
+
+func (ino *Goduino) restoreTimed(pin int, t *timedOutput) error {
+	ino.mu.Lock()
+	if ino.timedOutputs[pin] != t {
+		// Replaced or already restored
+		ino.mu.Unlock()
+		return nil
+	}
+	delete(ino.timedOutputs, pin)
+	if safe, ok := ino.safeStates[pin]; t.addedSafe && ok && safe == t.after {
+		// not redeclared with SetSafeState since
+		delete(ino.safeStates, pin)
+	}
+	after := t.after
+	ino.mu.Unlock()
+	return ino.DigitalWrite(pin, after)
+}
+
+// stopTimedOutputs restores every pending timed pin right away, before the
+// board is disconnected.
+func (ino *Goduino) stopTimedOutputs() {
+	ino.mu.Lock()
+	pending := make(map[int]*timedOutput, len(ino.timedOutputs))
+	for pin, t := range ino.timedOutputs {
+		t.timer.Stop()
+		pending[pin] = t
+	}
+	ino.mu.Unlock()
+	for pin, t := range pending {
+		ino.expireTimed(pin, t)
+	}
+}
@@ -0,0 +1,40 @@
+package goduino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimedWrite(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	if err := ino.PulseHigh(13, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertPin(t, 13, 1)
+	// A safe state declared while pending outlives the restore
+	ino.SetSafeState(13, 1)
+	time.Sleep(50 * time.Millisecond)
+	board.AssertPin(t, 13, 0)
+	ino.mu.Lock()
+	safe, ok := ino.safeStates[13]
+	ino.mu.Unlock()
+	if !ok || safe != 1 {
+		t.Errorf("safe state of pin 13 = %v, %v after the restore, want 1", safe, ok)
+	}
+}
+
+func TestTimedWriteDisconnect(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	if err := ino.PulseHigh(12, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := ino.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	board.AssertPin(t, 12, 0)
+	board.ClearCalls()
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := hasCall(board, "DigitalWrite", 12); ok {
+		t.Errorf("timed restore fired after Disconnect")
+	}
+}
@@ -0,0 +1,218 @@
+package goduino
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Transform converts a value, typically a raw reading into engineering
+// units.
+type Transform func(float64) float64
+
+// Pipeline is a chain of transforms applied in order.
+type Pipeline []Transform
+
+// Apply runs v through every transform of the pipeline.
+func (p Pipeline) Apply(v float64) float64 {
+	for _, t := range p {
+		v = t(v)
+	}
+	return v
+}
+
+// Scale multiplies by k.
+func Scale(k float64) Transform {
+	return func(v float64) float64 { return v * k }
+}
+
+// Offset adds c.
+func Offset(c float64) Transform {
+	return func(v float64) float64 { return v + c }
+}
+
+// Clamp limits to the range min to max.
+func Clamp(min, max float64) Transform {
+	return func(v float64) float64 { return math.Max(min, math.Min(max, v)) }
+}
+
+// MapRange maps inLo-inHi linearly onto outLo-outHi, e.g.
+// MapRange(0, 1023, 0, 5) for volts from a 10 bit reading. An empty input
+// range, inLo equal to inHi, maps every value to outLo.
+func MapRange(inLo, inHi, outLo, outHi float64) Transform {
+	if inLo == inHi {
+		return func(float64) float64 { return outLo }
+	}
+	k := (outHi - outLo) / (inHi - inLo)
+	return func(v float64) float64 { return outLo + (v-inLo)*k }
+}
+
+// CalibrationPoint pairs a raw reading with the true value measured for
+// it.
+type CalibrationPoint struct {
+	Raw, Value float64
+}
+
+// Calibrate interpolates linearly between calibration points, and
+// extrapolates from the outer segments. At least two points are needed.
+func Calibrate(points ...CalibrationPoint) (Transform, error) {
+	if len(points) < 2 {
+		return nil, fmt.Errorf("calibration needs at least 2 points, got %d", len(points))
+	}
+	pts := append([]CalibrationPoint(nil), points...)
+	sort.Slice(pts, func(i, j int) bool { return pts[i].Raw < pts[j].Raw })
+	for i := 1; i < len(pts); i++ {
+		if pts[i].Raw == pts[i-1].Raw {
+			return nil, fmt.Errorf("calibration has two points at raw %v", pts[i].Raw)
+		}
+	}
+	return func(v float64) float64 {
+		i := sort.Search(len(pts)-1, func(i int) bool { return pts[i+1].Raw >= v })
+		if i == len(pts)-1 {
+			i--
+		}
+		a, b := pts[i], pts[i+1]
+		return a.Value + (v-a.Raw)*(b.Value-a.Value)/(b.Raw-a.Raw)
+	}, nil
+}
+
+// units maps a unit to its factor and offset from the base unit of its
+// quantity: base = v*factor + offset.
+var units = map[string]struct {
+	quantity       string
+	factor, offset float64
+}{
+	"c":   {"temperature", 1, 0},
+	"f":   {"temperature", 5.0 / 9, -32 * 5.0 / 9},
+	"k":   {"temperature", 1, -273.15},
+	"mm":  {"length", 1, 0},
+	"cm":  {"length", 10, 0},
+	"m":   {"length", 1000, 0},
+	"in":  {"length", 25.4, 0},
+	"pa":  {"pressure", 1, 0},
+	"hpa": {"pressure", 100, 0},
+	"kpa": {"pressure", 1000, 0},
+	"bar": {"pressure", 100000, 0},
+	"psi": {"pressure", 6894.757, 0},
+	"mv":  {"voltage", 1, 0},
+	"v":   {"voltage", 1000, 0},
+}
+
+// Convert converts between units of the same quantity: c, f and k for
+// temperature; mm, cm, m and in for length; pa, hpa, kpa, bar and psi for
+// pressure; mv and v for voltage.
+func Convert(from, to string) (Transform, error) {
+	f, ok := units[strings.ToLower(from)]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", from)
+	}
+	t, ok := units[strings.ToLower(to)]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", to)
+	}
+	if f.quantity != t.quantity {
+		return nil, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+	return func(v float64) float64 { return (v*f.factor + f.offset - t.offset) / t.factor }, nil
+}
+
+// ParsePipeline parses a pipeline from its text form, for pipelines kept
+// in configuration files. Transforms are separated by |:
+//
+//	map(0, 1023, 0, 5) | scale(100) | offset(-50) | clamp(-40, 125)
+//	calibrate(100:0, 900:100) | convert(c, f)
+//
+// calibrate takes raw:value pairs.
+func ParsePipeline(spec string) (Pipeline, error) {
+	var p Pipeline
+	for _, stage := range strings.Split(spec, "|") {
+		stage = strings.TrimSpace(stage)
+		open := strings.Index(stage, "(")
+		if open < 0 || !strings.HasSuffix(stage, ")") {
+			return nil, fmt.Errorf("invalid transform %q", stage)
+		}
+		name := strings.TrimSpace(stage[:open])
+		var args []string
+		for _, arg := range strings.Split(stage[open+1:len(stage)-1], ",") {
+			args = append(args, strings.TrimSpace(arg))
+		}
+		t, err := parseTransform(name, args)
+		if err != nil {
+			return nil, fmt.Errorf("transform %q: %v", stage, err)
+		}
+		p = append(p, t)
+	}
+	return p, nil
+}
+
+func parseTransform(name string, args []string) (Transform, error) {
+	if name == "convert" {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("convert takes 2 units")
+		}
+		return Convert(args[0], args[1])
+	}
+	if name == "calibrate" {
+		points := make([]CalibrationPoint, len(args))
+		for i, arg := range args {
+			pair := strings.SplitN(arg, ":", 2)
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("calibration point %q is not raw:value", arg)
+			}
+			raw, err := strconv.ParseFloat(strings.TrimSpace(pair[0]), 64)
+			if err != nil {
+				return nil, err
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(pair[1]), 64)
+			if err != nil {
+				return nil, err
+			}
+			points[i] = CalibrationPoint{raw, value}
+		}
+		return Calibrate(points...)
+	}
+	nums := make([]float64, len(args))
+	for i, arg := range args {
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = n
+	}
+	want := map[string]int{"scale": 1, "offset": 1, "clamp": 2, "map": 4}
+	n, ok := want[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown transform %q", name)
+	}
+	if len(nums) != n {
+		return nil, fmt.Errorf("%s takes %d arguments", name, n)
+	}
+	switch name {
+	case "scale":
+		return Scale(nums[0]), nil
+	case "offset":
+		return Offset(nums[0]), nil
+	case "clamp":
+		return Clamp(nums[0], nums[1]), nil
+	}
+	if nums[0] == nums[1] {
+		return nil, fmt.Errorf("map input range %v-%v is empty", nums[0], nums[1])
+	}
+	return MapRange(nums[0], nums[1], nums[2], nums[3]), nil
+}
+
+// Transformed returns a channel receiving the values of c, such as a
+// change channel or the channel of an AnalogSubscription, run through p.
+// It is closed when c is closed.
+func Transformed(c <-chan int, p Pipeline) <-chan float64 {
+	out := make(chan float64, cap(c))
+	go func() {
+		defer close(out)
+		for v := range c {
+			out <- p.Apply(float64(v))
+		}
+	}()
+	return out
+}
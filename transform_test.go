@@ -0,0 +1,84 @@
+package goduino
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParsePipeline(t *testing.T) {
+	tests := []struct {
+		spec string
+		in   float64
+		want float64
+	}{
+		{"map(0, 1023, 0, 5)", 1023, 5},
+		{"map(0, 1023, 0, 5) | scale(100) | offset(-50) | clamp(-40, 125)", 0, -40},
+		{"scale(2)|offset(1)", 3, 7},
+		{"calibrate(100:0, 900:100)", 500, 50},
+		{"calibrate(900:100, 100:0)", 1000, 112.5},
+		{"calibrate(0:0, 10:100, 20:120)", 15, 110},
+		{"convert(c, f)", 100, 212},
+		{"convert(F, K)", 32, 273.15},
+		{"convert(psi, kpa)", 1, 6.894757},
+	}
+	for _, tt := range tests {
+		p, err := ParsePipeline(tt.spec)
+		if err != nil {
+			t.Errorf("ParsePipeline(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := p.Apply(tt.in); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q applied to %v = %v, want %v", tt.spec, tt.in, got, tt.want)
+		}
+	}
+	if got := MapRange(1, 1, 2, 5)(1); got != 2 {
+		t.Errorf("MapRange over an empty range = %v, want 2", got)
+	}
+	for _, spec := range []string{
+		"",
+		"scale",
+		"scale(x)",
+		"scale(1, 2)",
+		"round(1)",
+		"map(1, 1, 0, 5)",
+		"calibrate(1:1)",
+		"calibrate(1:1, 1:2)",
+		"calibrate(1-1, 2:2)",
+		"convert(c, m)",
+		"convert(c)",
+	} {
+		if _, err := ParsePipeline(spec); err == nil {
+			t.Errorf("ParsePipeline(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestTransformed(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	s, err := ino.SubscribeAnalog(0, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.InjectAnalog(0, 1023)
+	p, err := ParsePipeline("map(0, 1023, 0, 5)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	volts := Transformed(s.C, p)
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case v := <-volts:
+			if v != 5 {
+				continue
+			}
+		case <-deadline:
+			t.Fatal("no transformed value of 5 V")
+		}
+		break
+	}
+	s.Close()
+	for range volts {
+	}
+}
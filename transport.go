@@ -0,0 +1,54 @@
+package goduino
+
+import (
+	"github.com/tarm/serial"
+	"io"
+	"net"
+	"time"
+)
+
+// defaultBaud is the baud rate of StandardFirmata.
+const defaultBaud = 57600
+
+// TCPOptions configures the connection of a network transport.
+type TCPOptions struct {
+	// DialTimeout bounds how long Connect waits for the board, zero
+	// means the operating system default
+	DialTimeout time.Duration
+	// KeepAlive is the TCP keepalive period, zero uses the default and
+	// a negative value disables keepalives
+	KeepAlive time.Duration
+}
+
+// NewTCP creates a Goduino for a board running a network Firmata sketch,
+// e.g. StandardFirmataWiFi or StandardFirmataEthernet, at address
+// ("host:port"). A TCPOptions arg configures the connection. The board is
+// dialed by Connect and closed by Disconnect like a serial port.
+func NewTCP(name string, address string, args ...interface{}) *Goduino {
+	ino := New(name, address)
+	opts := TCPOptions{DialTimeout: 10 * time.Second}
+	for _, arg := range args {
+		switch arg.(type) {
+		case TCPOptions:
+			opts = arg.(TCPOptions)
+		case *TCPOptions:
+			opts = *arg.(*TCPOptions)
+		}
+	}
+	ino.openSP = func(address string) (io.ReadWriteCloser, error) {
+		dialer := net.Dialer{Timeout: opts.DialTimeout, KeepAlive: opts.KeepAlive}
+		return dialer.Dial("tcp", address)
+	}
+	return ino
+}
+
+// openSerial opens port with the serial parameters given to New, 57600
+// baud 8N1 unless configured otherwise.
+func (ino *Goduino) openSerial(port string) (io.ReadWriteCloser, error) {
+	config := ino.serialConfig
+	config.Name = port
+	if config.Baud == 0 {
+		config.Baud = defaultBaud
+	}
+	return serial.OpenPort(&config)
+}
@@ -0,0 +1,39 @@
+package goduino
+
+import (
+	"strconv"
+	"time"
+)
+
+// ultrasoundTimeout bounds how long Distance waits for a reading.
+const ultrasoundTimeout = time.Second
+
+// Ultrasound is an ultrasonic distance sensor read through the
+// UltrasoundReport sysex. It implements Distancer.
+type Ultrasound struct {
+	ino *Goduino
+	pin int
+}
+
+// Ultrasound returns the ultrasonic sensor wired to pin.
+func (ino *Goduino) Ultrasound(pin int) *Ultrasound {
+	return &Ultrasound{ino: ino, pin: pin}
+}
+
+// Distance triggers a measurement and waits for its result.
+func (u *Ultrasound) Distance() (Millimeter, error) {
+	updated := u.ino.board.UltrasoundUpdated()
+	if err := u.ino.UltrasoundReport(u.pin); err != nil {
+		return 0, err
+	}
+	select {
+	case <-updated:
+	case <-time.After(ultrasoundTimeout):
+		return 0, ErrTimeout
+	}
+	cm, err := strconv.ParseFloat(u.ino.UltrasoundDistance(), 64)
+	if err != nil {
+		return 0, err
+	}
+	return Millimeter(cm * 10), nil
+}
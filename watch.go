@@ -0,0 +1,182 @@
+package goduino
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// watchInterval is how often watch expressions are evaluated.
+const watchInterval = 50 * time.Millisecond
+
+// Variable supplies the current value of a name used in watch expressions.
+type Variable func() (float64, error)
+
+// AlertKind tells whether an alert was raised or cleared.
+type AlertKind int
+
+const (
+	AlertRaised AlertKind = iota
+	AlertCleared
+)
+
+func (k AlertKind) String() string {
+	switch k {
+	case AlertRaised:
+		return "RAISED"
+	case AlertCleared:
+		return "CLEARED"
+	}
+	return "UNKNOWN"
+}
+
+// Alert is fired when a watch expression has changed state and held it for
+// the hold time of the watch.
+type Alert struct {
+	Kind AlertKind
+	Expr string
+	Time time.Time
+}
+
+// Watch evaluates an expression periodically and fires alerts.
+type Watch struct {
+	expr string
+	stop chan struct{}
+	once sync.Once
+}
+
+// watchState is the state of a Watch saved in a StateStore. The hold
+// timer is not saved: after a restart a pending change has to hold again.
+type watchState struct {
+	Active bool `json:"active"`
+}
+
+// pinVariable matches the built-in names D<n> and A<n>, the last reported
+// value of digital pin n and analog pin n.
+var pinVariable = regexp.MustCompile(`^([DA])([0-9]+)$`)
+
+// Bind makes v available to watch expressions as name.
+func (ino *Goduino) Bind(name string, v Variable) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.variables == nil {
+		ino.variables = make(map[string]Variable)
+	}
+	ino.variables[name] = v
+}
+
+// BindSensor makes a sensor reading available to watch expressions as name.
+// Thermometers are bound in degrees Celsius, hygrometers in percent,
+// barometers in pascals, distancers in millimeters and luxmeters in lux.
+func (ino *Goduino) BindSensor(name string, sensor interface{}) error {
+	var v Variable
+	switch s := sensor.(type) {
+	case Thermometer:
+		v = func() (float64, error) { t, err := s.Temperature(); return float64(t), err }
+	case Hygrometer:
+		v = func() (float64, error) { h, err := s.Humidity(); return float64(h), err }
+	case Barometer:
+		v = func() (float64, error) { p, err := s.Pressure(); return float64(p), err }
+	case Distancer:
+		v = func() (float64, error) { d, err := s.Distance(); return float64(d), err }
+	case Luxmeter:
+		v = func() (float64, error) { l, err := s.Illuminance(); return float64(l), err }
+	default:
+		return fmt.Errorf("%T is not a supported sensor", sensor)
+	}
+	ino.Bind(name, v)
+	return nil
+}
+
+func (ino *Goduino) resolveVariable(name string) (Variable, error) {
+	ino.mu.Lock()
+	v, ok := ino.variables[name]
+	ino.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+	if m := pinVariable.FindStringSubmatch(name); m != nil {
+		pin, _ := strconv.Atoi(m[2])
+		if m[1] == "A" {
+			p, err := ino.analogPin(pin)
+			if err != nil {
+				return nil, err
+			}
+			pin = p
+		}
+		return func() (float64, error) {
+			pins := ino.board.Pins()
+			if pin >= len(pins) {
+				return 0, fmt.Errorf("Invalid pin number %v\n", pin)
+			}
+			return float64(pins[pin].Value), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown name %q", name)
+}
+
+// Watch evaluates expr, such as "temp > 60 && fanRPM < 100", and calls fn
+// with an AlertRaised once it has been true for hold, then with an
+// AlertCleared once it has been false for hold. Names are bound with Bind
+// or BindSensor; D13 and A0 refer to the reported pin values. With a
+// StateStore set, a raised alert stays raised across restarts instead of
+// being raised again.
+func (ino *Goduino) Watch(expr string, hold time.Duration, fn func(Alert)) (*Watch, error) {
+	e, err := compileExpr(expr, ino.resolveVariable)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watch{expr: expr, stop: make(chan struct{})}
+	go w.run(ino, e, hold, fn)
+	return w, nil
+}
+
+// Stop ends the watch.
+func (w *Watch) Stop() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+func (w *Watch) run(ino *Goduino, e expression, hold time.Duration, fn func(Alert)) {
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+	store := ino.states()
+	key := "watch:" + w.expr
+	var state watchState
+	store.load(key, &state)
+	active := state.Active
+	var since time.Time // when the condition started to differ from active
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-t.C:
+			v, err := e()
+			if err != nil {
+				ino.logger.Printf("watch %q failed: %v\r\n", w.expr, err)
+				continue
+			}
+			if (v != 0) == active {
+				since = time.Time{}
+				continue
+			}
+			if since.IsZero() {
+				since = now
+			}
+			if now.Sub(since) < hold {
+				continue
+			}
+			active = !active
+			since = time.Time{}
+			if err := store.save(key, watchState{Active: active}); err != nil {
+				ino.logger.Printf("watch %q state not saved: %v\r\n", w.expr, err)
+			}
+			kind := AlertCleared
+			if active {
+				kind = AlertRaised
+			}
+			fn(Alert{Kind: kind, Expr: w.expr, Time: now})
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package goduino
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// webhookQueue is the number of events a Webhook buffers while posting.
+const webhookQueue = 64
+
+// WebhookEvent is the JSON document posted by a Webhook.
+type WebhookEvent struct {
+	Type  string    `json:"type"` // "alert", "pin" or "sensor"
+	Board string    `json:"board"`
+	Name  string    `json:"name,omitempty"`
+	Pin   *int      `json:"pin,omitempty"`
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// Webhook posts selected events as JSON to a URL from a background
+// goroutine, retrying failed posts with exponential backoff.
+type Webhook struct {
+	URL     string
+	Retries int           // extra attempts after a failed post
+	Backoff time.Duration // delay before the first retry, doubled after each
+	Client  *http.Client
+
+	ino       *Goduino
+	queue     chan WebhookEvent
+	done      chan struct{}
+	mu        sync.Mutex
+	listeners []int
+	closed    bool
+}
+
+// NewWebhook creates a webhook posting to url with 3 retries starting at
+// one second apart. Fields may be adjusted before the first event.
+func (ino *Goduino) NewWebhook(url string) *Webhook {
+	w := &Webhook{
+		URL:     url,
+		Retries: 3,
+		Backoff: time.Second,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		ino:     ino,
+		queue:   make(chan WebhookEvent, webhookQueue),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Alert posts a. It can be passed directly as the callback of Watch.
+func (w *Webhook) Alert(a Alert) {
+	value := 0.0
+	if a.Kind == AlertRaised {
+		value = 1
+	}
+	w.Post(WebhookEvent{Type: "alert", Name: a.Expr + " " + a.Kind.String(), Value: value, Time: a.Time})
+}
+
+// Sensor posts a sensor reading under name.
+func (w *Webhook) Sensor(name string, value float64) {
+	w.Post(WebhookEvent{Type: "sensor", Name: name, Value: value, Time: time.Now()})
+}
+
+// WatchPin posts every reported change of digital pin.
+func (w *Webhook) WatchPin(pin int) {
+	id := w.ino.board.AddDigitalListener(func(p int, value int) {
+		if p == pin {
+			w.Post(WebhookEvent{Type: "pin", Pin: &p, Value: float64(value), Time: time.Now()})
+		}
+	})
+	w.mu.Lock()
+	w.listeners = append(w.listeners, id)
+	w.mu.Unlock()
+}
+
+// Post queues event for delivery. Events are dropped while the queue is full
+// so a slow endpoint never blocks the board.
+func (w *Webhook) Post(event WebhookEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	event.Board = w.ino.Name()
+	select {
+	case w.queue <- event:
+	default:
+		w.ino.logger.Printf("webhook queue full, dropping %s event\r\n", event.Type)
+	}
+}
+
+// Close stops watching pins and waits for queued events to be posted.
+func (w *Webhook) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	for _, id := range w.listeners {
+		w.ino.board.RemoveDigitalListener(id)
+	}
+	close(w.queue)
+	w.mu.Unlock()
+	<-w.done
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+	for event := range w.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			w.ino.logger.Printf("webhook encode failed: %v\r\n", err)
+			continue
+		}
+		backoff := w.Backoff
+		for attempt := 0; ; attempt++ {
+			if err = w.send(body); err == nil {
+				break
+			}
+			if attempt >= w.Retries {
+				w.ino.logger.Printf("webhook %s failed: %v\r\n", w.URL, err)
+				break
+			}
+			<-time.After(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+func (w *Webhook) send(body []byte) error {
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package goduino
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// windowQueue is the number of events an AnalogWindow buffers.
+const windowQueue = 16
+
+// WindowEventKind tells whether a value entered or left a window.
+type WindowEventKind int
+
+const (
+	WindowEnter WindowEventKind = iota
+	WindowLeave
+)
+
+func (k WindowEventKind) String() string {
+	switch k {
+	case WindowEnter:
+		return "ENTER"
+	case WindowLeave:
+		return "LEAVE"
+	}
+	return "UNKNOWN"
+}
+
+// WindowEvent is sent when an analog pin has entered or left its window
+// and stayed there for the dwell time.
+type WindowEvent struct {
+	Pin   int
+	Kind  WindowEventKind
+	Value int
+	Time  time.Time
+}
+
+// AnalogWindow monitors an analog pin against a [Low, High] band.
+type AnalogWindow struct {
+	// C receives the window events. Events are dropped while it is full.
+	C <-chan WindowEvent
+
+	ino      *Goduino
+	pin      int
+	low      int
+	high     int
+	dwell    time.Duration
+	c        chan WindowEvent
+	listener int
+
+	mu      sync.Mutex
+	state   int // -1 unknown, 0 outside, 1 inside
+	pending int
+	since   time.Time
+}
+
+// WatchAnalogWindow monitors analog pin and sends an event on the returned
+// window when its value has been inside [low, high] for dwell after being
+// outside, or the reverse. The first event tells which side the value
+// settled on. Useful for battery monitoring and safety envelopes.
+func (ino *Goduino) WatchAnalogWindow(pin, low, high int, dwell time.Duration) (*AnalogWindow, error) {
+	if low > high {
+		return nil, fmt.Errorf("window low %d above high %d", low, high)
+	}
+	if err := ino.ensureAnalog(pin); err != nil {
+		return nil, err
+	}
+	c := make(chan WindowEvent, windowQueue)
+	w := &AnalogWindow{C: c, ino: ino, pin: pin, low: low, high: high, dwell: dwell, c: c, state: -1, pending: -1}
+	w.listener = ino.board.AddAnalogListener(w.update)
+	return w, nil
+}
+
+// Close stops monitoring.
+func (w *AnalogWindow) Close() {
+	w.ino.board.RemoveAnalogListener(w.listener)
+}
+
+func (w *AnalogWindow) update(channel int, value int) {
+	if channel != w.pin {
+		return
+	}
+	now := time.Now()
+	inside := 0
+	if value >= w.low && value <= w.high {
+		inside = 1
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if inside == w.state {
+		w.pending = -1
+		return
+	}
+	if inside != w.pending {
+		w.pending = inside
+		w.since = now
+	}
+	if now.Sub(w.since) < w.dwell {
+		return
+	}
+	w.state = inside
+	w.pending = -1
+	kind := WindowLeave
+	if inside == 1 {
+		kind = WindowEnter
+	}
+	select {
+	case w.c <- WindowEvent{Pin: w.pin, Kind: kind, Value: value, Time: now}:
+	default:
+		w.ino.logger.Printf("window of pin %d full, dropping %s event\r\n", w.pin, kind)
+	}
+}
@@ -0,0 +1,58 @@
+package goduino
+
+// writtenState is the last output written to a pin through DigitalWrite or
+// PwmWrite.
+type writtenState struct {
+	mode  int
+	value int
+}
+
+// SetWriteCache enables or disables output-state caching. While enabled, a
+// DigitalWrite or PwmWrite of the value a pin already outputs is not sent
+// to the board, so control loops can write every iteration without
+// flooding the link. Writes that bypass the cache (port writes, safe
+// states, mode changes, reconnects) invalidate the pins they touch.
+func (ino *Goduino) SetWriteCache(enabled bool) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	switch {
+	case !enabled:
+		ino.written = nil
+	case ino.written == nil:
+		ino.written = map[int]writtenState{}
+	}
+}
+
+// cachedWrite reports whether pin already outputs value in mode.
+func (ino *Goduino) cachedWrite(pin, mode, value int) bool {
+	ino.mu.Lock()
+	s, ok := ino.written[pin]
+	ino.mu.Unlock()
+	return ok && s == writtenState{mode, value} && ino.board.Pins()[pin].Mode == mode
+}
+
+// recordWrite remembers that pin outputs value in mode.
+func (ino *Goduino) recordWrite(pin, mode, value int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.written != nil {
+		ino.written[pin] = writtenState{mode, value}
+	}
+}
+
+// forgetWrites invalidates the cached output of pins, or of every pin if
+// none are given.
+func (ino *Goduino) forgetWrites(pins ...int) {
+	ino.mu.Lock()
+	defer ino.mu.Unlock()
+	if ino.written == nil {
+		return
+	}
+	if len(pins) == 0 {
+		ino.written = map[int]writtenState{}
+		return
+	}
+	for _, pin := range pins {
+		delete(ino.written, pin)
+	}
+}
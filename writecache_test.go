@@ -0,0 +1,59 @@
+package goduino
+
+import (
+	"testing"
+
+	"github.com/argandas/goduino/goduinotest"
+)
+
+// countCalls returns how many times board received method for pin.
+func countCalls(board *goduinotest.Board, method string, pin int) int {
+	n := 0
+	for _, c := range board.Calls() {
+		if c.Method == method && c.Pin == pin {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWriteCache(t *testing.T) {
+	ino, board := newTestGoduino(t)
+	ino.SetWriteCache(true)
+	for i := 0; i < 3; i++ {
+		if err := ino.DigitalWrite(13, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := ino.PwmWrite(9, 128); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countCalls(board, "DigitalWrite", 13); n != 1 {
+		t.Errorf("sent %d DigitalWrite, want 1", n)
+	}
+	if n := countCalls(board, "AnalogWrite", 9); n != 1 {
+		t.Errorf("sent %d AnalogWrite, want 1", n)
+	}
+
+	// A port write bypasses the cache, so the next write is sent
+	if err := ino.ClearBits(1, 0x20); err != nil {
+		t.Fatal(err)
+	}
+	board.ClearCalls()
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := countCalls(board, "DigitalWrite", 13); n != 1 {
+		t.Errorf("sent %d DigitalWrite after a port write, want 1", n)
+	}
+	board.AssertPin(t, 13, 1)
+
+	ino.SetWriteCache(false)
+	board.ClearCalls()
+	if err := ino.DigitalWrite(13, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := countCalls(board, "DigitalWrite", 13); n != 1 {
+		t.Errorf("sent %d DigitalWrite with the cache disabled, want 1", n)
+	}
+}